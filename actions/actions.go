@@ -16,30 +16,149 @@ package actions
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/metadata"
 	cid "github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
 )
 
-func Parse(objects map[string]cid.Cid, a string) (p2plab.Action, error) {
-	return &dummyAction{objects[a].String()}, nil
+// Parse parses an action of the form "[<verb>] <object>[/<path>][?<params>]"
+// into tasks. The object name is substituted with the CID it was transformed
+// into. When the verb is omitted, the action is a "get".
+//
+// The "read" verb verifies the content it reads against the digest recorded
+// in the plan for its target, and fails if there is none.
+//
+// The "add" verb takes either the name of an object, which is added on the
+// node from its definition, or a random source of the form "<size>[?seed=n]".
+// The "gc" verb takes no object.
+func Parse(plan metadata.ScenarioPlan, odefs map[string]metadata.ObjectDefinition, a string) (p2plab.Action, error) {
+	fields := strings.Fields(a)
+	var verb, arg string
+	switch len(fields) {
+	case 1:
+		verb, arg = string(metadata.TaskGet), fields[0]
+		if fields[0] == string(metadata.TaskGC) {
+			verb, arg = fields[0], ""
+		}
+	case 2:
+		verb, arg = fields[0], fields[1]
+	default:
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid action %q", a)
+	}
+
+	typ := metadata.TaskType(verb)
+	switch typ {
+	case metadata.TaskGC:
+		if arg != "" {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "gc takes no object: %q", a)
+		}
+		return &taskAction{typ: typ}, nil
+	case metadata.TaskAdd:
+		odef, ok := odefs[arg]
+		if !ok {
+			odef = metadata.ObjectDefinition{
+				Type:   string(metadata.ObjectRandom),
+				Source: arg,
+			}
+		}
+
+		switch metadata.ObjectType(odef.Type) {
+		case metadata.ObjectRandom, metadata.ObjectDirectory:
+		default:
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "cannot add object %q of type %q on a node", arg, odef.Type)
+		}
+
+		subject, err := json.Marshal(&odef)
+		if err != nil {
+			return nil, err
+		}
+		return &taskAction{typ: typ, subject: string(subject)}, nil
+	case metadata.TaskRead:
+		subject, err := substitute(plan.Objects, arg)
+		if err != nil {
+			return nil, err
+		}
+
+		target := readTarget(arg)
+		dgst, ok := plan.Digests[target]
+		if !ok {
+			return nil, errors.Wrapf(errdefs.ErrNotFound, "no digest to verify read of %q", target)
+		}
+
+		if strings.Contains(subject, "?") {
+			subject = strings.Replace(subject, "?", fmt.Sprintf("?digest=%s&", dgst), 1)
+		} else {
+			subject = fmt.Sprintf("%s?digest=%s", subject, dgst)
+		}
+		return &taskAction{typ: typ, subject: subject}, nil
+	case metadata.TaskGet, metadata.TaskGetPath, metadata.TaskGetRange, metadata.TaskGetDepth,
+		metadata.TaskPin, metadata.TaskUnpin, metadata.TaskProvide, metadata.TaskFindProvs:
+		subject, err := substitute(plan.Objects, arg)
+		if err != nil {
+			return nil, err
+		}
+		return &taskAction{typ: typ, subject: subject}, nil
+	default:
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "unknown action verb %q", verb)
+	}
+}
+
+// ReadTarget returns the target of a read action, of the form
+// "<object>[/<path>]", whose content digest must be in the plan.
+func ReadTarget(a string) (string, bool) {
+	fields := strings.Fields(a)
+	if len(fields) != 2 || metadata.TaskType(fields[0]) != metadata.TaskRead {
+		return "", false
+	}
+	return readTarget(fields[1]), true
+}
+
+func readTarget(arg string) string {
+	if i := strings.Index(arg, "?"); i >= 0 {
+		return arg[:i]
+	}
+	return arg
 }
 
-type dummyAction struct {
+// substitute replaces the object name at the start of arg with its CID,
+// preserving any trailing path and parameters.
+func substitute(objects map[string]cid.Cid, arg string) (string, error) {
+	name, rest := arg, ""
+	if i := strings.IndexAny(arg, "/?"); i >= 0 {
+		name, rest = arg[:i], arg[i:]
+	}
+
+	c, ok := objects[name]
+	if !ok {
+		return "", errors.Wrapf(errdefs.ErrNotFound, "object %q", name)
+	}
+
+	return c.String() + rest, nil
+}
+
+type taskAction struct {
+	typ     metadata.TaskType
 	subject string
 }
 
-func (a *dummyAction) String() string {
-	return fmt.Sprintf("get %q", a.subject)
+func (a *taskAction) String() string {
+	if a.subject == "" {
+		return string(a.typ)
+	}
+	return fmt.Sprintf("%s %q", a.typ, a.subject)
 }
 
-func (a *dummyAction) Tasks(ctx context.Context, ns []p2plab.Node) (map[string]metadata.Task, error) {
+func (a *taskAction) Tasks(ctx context.Context, ns []p2plab.Node) (map[string]metadata.Task, error) {
 	taskMap := make(map[string]metadata.Task)
 	for _, n := range ns {
 		taskMap[n.Metadata().ID] = metadata.Task{
-			Type:    metadata.TaskGet,
+			Type:    a.typ,
 			Subject: a.subject,
 		}
 	}
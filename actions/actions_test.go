@@ -0,0 +1,80 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	cid "github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFetch(t *testing.T) {
+	c, err := cid.Parse("bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi")
+	require.NoError(t, err)
+
+	plan := metadata.ScenarioPlan{
+		Objects: map[string]cid.Cid{"dataset": c},
+	}
+
+	for _, tc := range []struct {
+		action  string
+		typ     metadata.TaskType
+		subject string
+	}{
+		{"dataset", metadata.TaskGet, c.String()},
+		{"get dataset", metadata.TaskGet, c.String()},
+		{"get-path dataset/docs/readme.md", metadata.TaskGetPath, c.String() + "/docs/readme.md"},
+		{"get-range dataset/video.mp4?offset=1024&length=4096", metadata.TaskGetRange, c.String() + "/video.mp4?offset=1024&length=4096"},
+		{"get-range dataset?length=4096", metadata.TaskGetRange, c.String() + "?length=4096"},
+		{"get-depth dataset?depth=2", metadata.TaskGetDepth, c.String() + "?depth=2"},
+		{"get-depth dataset/docs?depth=0", metadata.TaskGetDepth, c.String() + "/docs?depth=0"},
+	} {
+		t.Run(tc.action, func(t *testing.T) {
+			action, err := Parse(plan, nil, tc.action)
+			require.NoError(t, err)
+
+			task, ok := action.(*taskAction)
+			require.True(t, ok)
+			require.Equal(t, tc.typ, task.typ)
+			require.Equal(t, tc.subject, task.subject)
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	plan := metadata.ScenarioPlan{
+		Objects: map[string]cid.Cid{},
+	}
+
+	for _, tc := range []struct {
+		action string
+		check  func(error) bool
+	}{
+		{"get-path missing/readme.md", errdefs.IsNotFound},
+		{"get-range missing?length=1", errdefs.IsNotFound},
+		{"get-depth missing?depth=1", errdefs.IsNotFound},
+		{"fetch dataset", errdefs.IsInvalidArgument},
+		{"get-path dataset extra", errdefs.IsInvalidArgument},
+	} {
+		t.Run(tc.action, func(t *testing.T) {
+			_, err := Parse(plan, nil, tc.action)
+			require.Error(t, err)
+			require.True(t, tc.check(err), "unexpected error: %s", err)
+		})
+	}
+}
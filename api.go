@@ -16,6 +16,7 @@ package p2plab
 
 import (
 	"context"
+	"io"
 
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/libp2p/go-libp2p-core/peer"
@@ -43,14 +44,40 @@ type ControlAPI interface {
 }
 
 type AgentAPI interface {
-	Healthcheck(ctx context.Context) bool
+	// Healthcheck waits for the node's agent to become reachable and returns
+	// its status.
+	Healthcheck(ctx context.Context) (metadata.AgentStatus, error)
 
-	Update(ctx context.Context, id, link string, pdef metadata.PeerDefinition) error
+	// Status returns the status of the node's agent without waiting for it to
+	// become reachable.
+	Status(ctx context.Context) (metadata.AgentStatus, error)
 
-	// SSH creates a SSH connection to the node.
+	// Update restarts the node's labapp with the peer definition. If the build
+	// has a link, its binary is downloaded and verified against its digest
+	// first.
+	Update(ctx context.Context, id string, build metadata.Build, pdef metadata.PeerDefinition) error
+
+	// Logs writes the logs of the node's labapp to w.
+	Logs(ctx context.Context, w io.Writer, opts ...LogsOption) error
+
+	// SSH executes a command or an interactive shell on the node through its
+	// agent. It returns an *ExitError if the command exits with a non-zero
+	// status.
 	SSH(ctx context.Context, opts ...SSHOption) error
 }
 
+const (
+	ProfileCPU       = "cpu"
+	ProfileHeap      = "heap"
+	ProfileGoroutine = "goroutine"
+	ProfileBlock     = "block"
+	ProfileMutex     = "mutex"
+)
+
+// ProfileKinds are the pprof profiles captured by a node between
+// StartProfiling and StopProfiling.
+var ProfileKinds = []string{ProfileCPU, ProfileHeap, ProfileGoroutine, ProfileBlock, ProfileMutex}
+
 type AppAPI interface {
 	PeerInfo(ctx context.Context) (peer.AddrInfo, error)
 
@@ -58,4 +85,50 @@ type AppAPI interface {
 
 	// Run executes an task on the node.
 	Run(ctx context.Context, task metadata.Task) error
+
+	// StartTask executes a task on the node in the background. Retrying the
+	// request does not start the task twice.
+	StartTask(ctx context.Context, task metadata.Task) (metadata.TaskExecution, error)
+
+	// GetTask returns the status, progress and timing of a task started in the
+	// background. Finished tasks are kept until they are deleted or expire.
+	GetTask(ctx context.Context, id string) (metadata.TaskExecution, error)
+
+	// ListTasks returns the tasks running in the background, and those that
+	// have finished but are still kept.
+	ListTasks(ctx context.Context) ([]metadata.TaskExecution, error)
+
+	// CancelTask cancels a task running in the background.
+	CancelTask(ctx context.Context, id string) error
+
+	// DeleteTask forgets a task, canceling it if it is still running.
+	DeleteTask(ctx context.Context, id string) error
+
+	// PeerState returns a snapshot of the internal state of the peer.
+	PeerState(ctx context.Context) (metadata.PeerState, error)
+
+	// Reset cancels the node's tasks, and clears the peer's blocks, wantlist,
+	// provider queue, bitswap ledgers, bandwidth counters and connections
+	// without restarting it.
+	Reset(ctx context.Context) error
+
+	// StartProfiling starts capturing a CPU profile, and enables block and
+	// mutex profiling. A profile that was started but never stopped is
+	// discarded.
+	StartProfiling(ctx context.Context) error
+
+	// StopProfiling stops capturing profiles and snapshots every profile kind.
+	StopProfiling(ctx context.Context) error
+
+	// Profile writes a profile snapshotted by StopProfiling to w in the
+	// gzipped pprof protobuf format.
+	Profile(ctx context.Context, kind string, w io.Writer) error
+
+	// StartSampling starts sampling the number of open connections of the
+	// peer for its report, discarding the samples of the previous benchmark.
+	StartSampling(ctx context.Context) error
+
+	// StopSampling stops sampling the number of open connections. The samples
+	// are reported until sampling starts again or the node is reset.
+	StopSampling(ctx context.Context) error
 }
@@ -16,6 +16,7 @@ package p2plab
 
 import (
 	"context"
+	"io"
 
 	"github.com/Netflix/p2plab/metadata"
 )
@@ -43,12 +44,21 @@ type Benchmark interface {
 	Metadata() metadata.Benchmark
 
 	Report(ctx context.Context) (metadata.Report, error)
+
+	// Artifacts returns the names of the files gathered during the benchmark,
+	// such as node logs.
+	Artifacts(ctx context.Context) ([]string, error)
+
+	// Artifact writes the contents of an artifact to w.
+	Artifact(ctx context.Context, name string, w io.Writer) error
 }
 
 type StartBenchmarkOption func(*StartBenchmarkSettings) error
 
 type StartBenchmarkSettings struct {
-	NoReset bool
+	NoReset  bool
+	NoUpdate bool
+	Profile  bool
 }
 
 func WithBenchmarkNoReset() StartBenchmarkOption {
@@ -57,3 +67,23 @@ func WithBenchmarkNoReset() StartBenchmarkOption {
 		return nil
 	}
 }
+
+// WithBenchmarkProfile captures CPU, heap, goroutine, block and mutex profiles
+// on every node during the benchmark stage. They are stored as the benchmark's
+// artifacts.
+func WithBenchmarkProfile() StartBenchmarkOption {
+	return func(s *StartBenchmarkSettings) error {
+		s.Profile = true
+		return nil
+	}
+}
+
+// WithBenchmarkNoUpdate resets every peer of the cluster in-place, even those
+// whose labapp does not run its peer definition and would otherwise be
+// updated.
+func WithBenchmarkNoUpdate() StartBenchmarkOption {
+	return func(s *StartBenchmarkSettings) error {
+		s.NoUpdate = true
+		return nil
+	}
+}
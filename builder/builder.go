@@ -111,14 +111,14 @@ func (b *builder) Resolve(ctx context.Context, ref string) (commit string, err e
 	return resolved, nil
 }
 
-func (b *builder) Build(ctx context.Context, commit string) (link string, err error) {
+func (b *builder) Build(ctx context.Context, commit string) (metadata.Build, error) {
 	build, err := b.db.GetBuild(ctx, commit)
 	if err == nil {
-		return build.Link, nil
+		return build, nil
 	}
 
 	if !errdefs.IsNotFound(err) {
-		return "", errors.Wrap(err, "failed to get build from db")
+		return metadata.Build{}, errors.Wrap(err, "failed to get build from db")
 	}
 
 	f, dir, err := b.buildCommit(ctx, commit)
@@ -127,27 +127,29 @@ func (b *builder) Build(ctx context.Context, commit string) (link string, err er
 		if rmErr != nil {
 			zerolog.Ctx(ctx).Debug().Str("dir", dir).Msg("failed to cleanup build dir")
 		}
-		return "", errors.Wrapf(err, "failed to build commit %q", commit)
+		return metadata.Build{}, errors.Wrapf(err, "failed to build commit %q", commit)
 	}
 	defer os.RemoveAll(dir)
 	defer f.Close()
 
-	link, err = b.uploader.Upload(ctx, f)
+	build, err = Upload(ctx, b.uploader, f)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to upload build")
+		return metadata.Build{}, errors.Wrap(err, "failed to upload build")
 	}
+	build.ID = commit
 
 	// Create build, ignoring if it already exists because of a parallel build
 	// request.
-	build, err = b.db.CreateBuild(ctx, metadata.Build{
-		ID:   commit,
-		Link: link,
-	})
-	if err != nil && !errdefs.IsAlreadyExists(err) {
-		return "", err
+	created, err := b.db.CreateBuild(ctx, build)
+	if err != nil {
+		if !errdefs.IsAlreadyExists(err) {
+			return metadata.Build{}, err
+		}
+
+		return b.db.GetBuild(ctx, commit)
 	}
 
-	return link, nil
+	return created, nil
 }
 
 func (b *builder) buildCommit(ctx context.Context, commit string) (f *os.File, dir string, err error) {
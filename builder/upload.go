@@ -0,0 +1,53 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package builder
+
+import (
+	"context"
+	"io"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/metadata"
+	digest "github.com/opencontainers/go-digest"
+)
+
+// Upload uploads a binary and returns a build with its link, digest and size
+// filled in.
+func Upload(ctx context.Context, uploader p2plab.Uploader, r io.Reader) (metadata.Build, error) {
+	var (
+		digester = digest.Canonical.Digester()
+		counter  = &countingWriter{}
+	)
+
+	link, err := uploader.Upload(ctx, io.TeeReader(r, io.MultiWriter(digester.Hash(), counter)))
+	if err != nil {
+		return metadata.Build{}, err
+	}
+
+	return metadata.Build{
+		Link:   link,
+		Digest: digester.Digest(),
+		Size:   counter.n,
+	}, nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
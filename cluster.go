@@ -36,6 +36,14 @@ type ClusterAPI interface {
 
 	// Remove destroys clusters permanently.
 	Remove(ctx context.Context, names ...string) error
+
+	// Scale adds or removes nodes from a cluster. New nodes are updated and
+	// connected if the cluster's nodes are already running.
+	Scale(ctx context.Context, name string, opts ...ScaleClusterOption) (Cluster, error)
+
+	// Repair brings clusters back to their definitions, or every cluster that
+	// was not created successfully if no names are given.
+	Repair(ctx context.Context, names []string, opts ...RepairClusterOption) error
 }
 
 // Cluster is a group of instances connected in a p2p network. They can be
@@ -60,6 +68,7 @@ type CreateClusterSettings struct {
 	Size              int
 	InstanceType      string
 	Region            string
+	PeersPerInstance  int
 	ClusterDefinition metadata.ClusterDefinition
 }
 
@@ -91,10 +100,81 @@ func WithClusterRegion(region string) CreateClusterOption {
 	}
 }
 
+// WithClusterPeersPerInstance packs the given number of nodes onto each
+// instance of the cluster.
+func WithClusterPeersPerInstance(peersPerInstance int) CreateClusterOption {
+	return func(s *CreateClusterSettings) error {
+		s.PeersPerInstance = peersPerInstance
+		return nil
+	}
+}
+
+// ScaleClusterOption is an option to modify scale cluster settings.
+type ScaleClusterOption func(*ScaleClusterSettings) error
+
+// ScaleClusterSettings specify the size a cluster is scaled to.
+type ScaleClusterSettings struct {
+	// Definition is the path to a cluster definition replacing the cluster's
+	// groups. Groups may be added or removed at the end of the definition, but
+	// existing groups may only change in size.
+	Definition string
+
+	// Group is the index of the group resized to Size when no definition is
+	// given.
+	Group int
+
+	Size int
+}
+
+// WithScaleDefinition scales a cluster to the given cluster definition.
+func WithScaleDefinition(definition string) ScaleClusterOption {
+	return func(s *ScaleClusterSettings) error {
+		s.Definition = definition
+		return nil
+	}
+}
+
+// WithScaleGroup selects the group of the cluster to resize. Defaults to the
+// first group.
+func WithScaleGroup(group int) ScaleClusterOption {
+	return func(s *ScaleClusterSettings) error {
+		s.Group = group
+		return nil
+	}
+}
+
+// WithScaleSize resizes a group of the cluster to the given number of nodes.
+func WithScaleSize(size int) ScaleClusterOption {
+	return func(s *ScaleClusterSettings) error {
+		s.Size = size
+		return nil
+	}
+}
+
+// RepairClusterOption is an option to modify repair cluster settings.
+type RepairClusterOption func(*RepairClusterSettings) error
+
+// RepairClusterSettings specify what is repaired besides clusters.
+type RepairClusterSettings struct {
+	// Orphans destroys the provider's node groups that have no cluster.
+	Orphans bool
+}
+
+// WithRepairOrphans destroys the provider's node groups that have no cluster.
+func WithRepairOrphans() RepairClusterOption {
+	return func(s *RepairClusterSettings) error {
+		s.Orphans = true
+		return nil
+	}
+}
+
 type ListOption func(*ListSettings) error
 
 type ListSettings struct {
 	Query string
+
+	// Healthcheck refreshes the state of listed nodes from their agents.
+	Healthcheck bool
 }
 
 func WithQuery(q string) ListOption {
@@ -104,6 +184,15 @@ func WithQuery(q string) ListOption {
 	}
 }
 
+// WithHealthcheck refreshes the state of listed nodes from their agents
+// before they are returned.
+func WithHealthcheck() ListOption {
+	return func(s *ListSettings) error {
+		s.Healthcheck = true
+		return nil
+	}
+}
+
 type QueryOption func(*QuerySettings) error
 
 type QuerySettings struct {
@@ -84,6 +84,21 @@ func App(ctx context.Context) *cli.App {
 			Usage:  "routing for libp2p [nil, kaddht]",
 			EnvVar: "LABAPP_LIBP2P_ROUTING",
 		},
+		cli.IntFlag{
+			Name:   "libp2p-conn-manager-low",
+			Usage:  "number of connections the connection manager trims down to",
+			EnvVar: "LABAPP_LIBP2P_CONN_MANAGER_LOW",
+		},
+		cli.IntFlag{
+			Name:   "libp2p-conn-manager-high",
+			Usage:  "number of connections above which the connection manager trims, disabled if zero",
+			EnvVar: "LABAPP_LIBP2P_CONN_MANAGER_HIGH",
+		},
+		cli.StringFlag{
+			Name:   "libp2p-conn-manager-grace-period",
+			Usage:  "duration new connections are protected from being trimmed",
+			EnvVar: "LABAPP_LIBP2P_CONN_MANAGER_GRACE_PERIOD",
+		},
 		cli.StringFlag{
 			Name:   "log-level,l",
 			Usage:  "set the logging level [debug, info, warn, error, fatal, panic, none]",
@@ -131,6 +146,10 @@ func appAction(c *cli.Context) error {
 		Muxers:             c.GlobalStringSlice("libp2p-muxers"),
 		SecurityTransports: c.GlobalStringSlice("libp2p-security-transports"),
 		Routing:            c.GlobalString("libp2p-routing"),
+
+		ConnManagerLow:         c.GlobalInt("libp2p-conn-manager-low"),
+		ConnManagerHigh:        c.GlobalInt("libp2p-conn-manager-high"),
+		ConnManagerGracePeriod: c.GlobalString("libp2p-conn-manager-grace-period"),
 	})
 	if err != nil {
 		return err
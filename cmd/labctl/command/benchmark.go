@@ -15,11 +15,18 @@
 package command
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/pkg/cliutil"
+	"github.com/Netflix/p2plab/pkg/logutil"
 	"github.com/Netflix/p2plab/printer"
+	"github.com/Netflix/p2plab/progress"
 	"github.com/Netflix/p2plab/query"
 	"github.com/rs/zerolog"
 	"github.com/urfave/cli"
@@ -41,6 +48,14 @@ var benchmarkCommand = cli.Command{
 					Name:  "no-reset",
 					Usage: "Skips resetting the cluster to maintain a stale state",
 				},
+				&cli.BoolFlag{
+					Name:  "no-update",
+					Usage: "Resets every peer in-place instead of updating those that are outdated",
+				},
+				&cli.BoolFlag{
+					Name:  "profile",
+					Usage: "Captures pprof profiles on every node during the benchmark stage",
+				},
 			},
 		},
 		{
@@ -87,6 +102,24 @@ var benchmarkCommand = cli.Command{
 			ArgsUsage: "<id>",
 			Action:    benchmarkReportAction,
 		},
+		{
+			Name:      "artifacts",
+			Usage:     "List a benchmark's artifacts, or print one of them.",
+			ArgsUsage: "<id> [<name>]",
+			Action:    benchmarkArtifactsAction,
+		},
+		{
+			Name:      "profiles",
+			Usage:     "Downloads the profiles captured during a benchmark.",
+			ArgsUsage: "<id>",
+			Action:    benchmarkProfilesAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "output,o",
+					Usage: "Directory to download the profiles into, defaults to the benchmark id",
+				},
+			},
+		},
 		{
 			Name:      "remove",
 			Aliases:   []string{"rm"},
@@ -115,10 +148,22 @@ func createBenchmarkAction(c *cli.Context) error {
 	ctx := cliutil.CommandContext(c)
 	cluster, scenario := c.Args().Get(0), c.Args().Get(1)
 
+	logWriter := logutil.LogWriter(ctx)
+	if logWriter != nil {
+		// Render the progress of every node in place while the benchmark runs.
+		ctx = logutil.WithLogWriter(ctx, progress.NewView(logWriter, os.Stderr))
+	}
+
 	var opts []p2plab.StartBenchmarkOption
 	if c.Bool("no-reset") {
 		opts = append(opts, p2plab.WithBenchmarkNoReset())
 	}
+	if c.Bool("no-update") {
+		opts = append(opts, p2plab.WithBenchmarkNoUpdate())
+	}
+	if c.Bool("profile") {
+		opts = append(opts, p2plab.WithBenchmarkProfile())
+	}
 
 	id, err := control.Benchmark().Create(ctx, cluster, scenario, opts...)
 	if err != nil {
@@ -259,6 +304,101 @@ func benchmarkReportAction(c *cli.Context) error {
 	return p.Print(report)
 }
 
+func benchmarkArtifactsAction(c *cli.Context) error {
+	if c.NArg() < 1 || c.NArg() > 2 {
+		return errors.New("benchmark id must be provided")
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	id := c.Args().First()
+	benchmark, err := control.Benchmark().Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() == 2 {
+		return benchmark.Artifact(ctx, c.Args().Get(1), os.Stdout)
+	}
+
+	names, err := benchmark.Artifacts(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+func benchmarkProfilesAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("benchmark id must be provided")
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	id := c.Args().First()
+	benchmark, err := control.Benchmark().Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	names, err := benchmark.Artifacts(ctx)
+	if err != nil {
+		return err
+	}
+
+	dir := c.String("output")
+	if dir == "" {
+		dir = id
+	}
+
+	count := 0
+	for _, name := range names {
+		if !strings.HasPrefix(name, "profiles/") {
+			continue
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(name, "profiles/")))
+		err = downloadArtifact(ctx, benchmark, name, path)
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+		count++
+	}
+
+	if count == 0 {
+		return errors.New("benchmark has no profiles, it must be created with --profile")
+	}
+	return nil
+}
+
+func downloadArtifact(ctx context.Context, benchmark p2plab.Benchmark, name, path string) error {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return benchmark.Artifact(ctx, name, f)
+}
+
 func removeBenchmarksAction(c *cli.Context) error {
 	var ids []string
 	for i := 0; i < c.NArg(); i++ {
@@ -56,6 +56,11 @@ var clusterCommand = cli.Command{
 					Usage: "AWS Region to deploy to.",
 					Value: "us-west-2",
 				},
+				&cli.IntFlag{
+					Name:  "peers-per-instance",
+					Usage: "Number of nodes packed onto each instance.",
+					Value: 1,
+				},
 			},
 		},
 		{
@@ -95,6 +100,38 @@ var clusterCommand = cli.Command{
 				},
 			},
 		},
+		{
+			Name:      "repair",
+			Usage:     "Repairs clusters, or every cluster that was not created successfully.",
+			ArgsUsage: "[<name> ...]",
+			Action:    repairClustersAction,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "orphans",
+					Usage: "Also destroy node groups that have no cluster.",
+				},
+			},
+		},
+		{
+			Name:      "scale",
+			Usage:     "Adds or removes nodes from a cluster.",
+			ArgsUsage: "<name>",
+			Action:    scaleClusterAction,
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "definition,d",
+					Usage: "Scale cluster to a cluster definition.",
+				},
+				&cli.IntFlag{
+					Name:  "size,s",
+					Usage: "Number of nodes in the group.",
+				},
+				&cli.IntFlag{
+					Name:  "group,g",
+					Usage: "Index of the group to resize.",
+				},
+			},
+		},
 		{
 			Name:      "remove",
 			ArgsUsage: "[<name> ...]",
@@ -131,6 +168,7 @@ func createClusterAction(c *cli.Context) error {
 			p2plab.WithClusterSize(c.Int("size")),
 			p2plab.WithClusterInstanceType(c.String("instance-type")),
 			p2plab.WithClusterRegion(c.String("region")),
+			p2plab.WithClusterPeersPerInstance(c.Int("peers-per-instance")),
 		)
 	}
 
@@ -239,6 +277,68 @@ func listClusterAction(c *cli.Context) error {
 	return p.Print(l)
 }
 
+func repairClustersAction(c *cli.Context) error {
+	var names []string
+	for i := 0; i < c.NArg(); i++ {
+		names = append(names, c.Args().Get(i))
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	var opts []p2plab.RepairClusterOption
+	if c.Bool("orphans") {
+		opts = append(opts, p2plab.WithRepairOrphans())
+	}
+
+	ctx := cliutil.CommandContext(c)
+	return control.Cluster().Repair(ctx, names, opts...)
+}
+
+func scaleClusterAction(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return errors.New("cluster name must be provided")
+	}
+
+	if !c.IsSet("definition") && !c.IsSet("size") {
+		return errors.New("definition or size must be provided")
+	}
+
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+	ctx := cliutil.CommandContext(c)
+
+	var options []p2plab.ScaleClusterOption
+	if c.IsSet("definition") {
+		options = append(options,
+			p2plab.WithScaleDefinition(c.String("definition")),
+		)
+	} else {
+		options = append(options,
+			p2plab.WithScaleGroup(c.Int("group")),
+			p2plab.WithScaleSize(c.Int("size")),
+		)
+	}
+
+	name := c.Args().First()
+	cluster, err := control.Cluster().Scale(ctx, name, options...)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Msgf("Scaled cluster %q to %d nodes", name, cluster.Metadata().Definition.Size())
+	return p.Print(cluster.Metadata())
+}
+
 func removeClustersAction(c *cli.Context) error {
 	var names []string
 	for i := 0; i < c.NArg(); i++ {
@@ -150,7 +150,7 @@ func connectAction(c *cli.Context) error {
 		return fmt.Errorf("No nodes found for %q", cluster)
 	}
 
-	err = nodes.WaitHealthy(ctx, ns)
+	_, err = nodes.WaitHealthy(ctx, ns)
 	if err != nil {
 		return err
 	}
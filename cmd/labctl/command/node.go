@@ -15,8 +15,13 @@
 package command
 
 import (
+	"context"
 	"encoding/json"
+	"io"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/metadata"
@@ -25,6 +30,7 @@ import (
 	"github.com/Netflix/p2plab/query"
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 var nodeCommand = cli.Command{
@@ -67,6 +73,10 @@ var nodeCommand = cli.Command{
 					Name:  "query,q",
 					Usage: "Runs a query to filter the listed nodes.",
 				},
+				cli.BoolFlag{
+					Name:  "healthcheck",
+					Usage: "Refreshes the state of each node's p2p app before listing.",
+				},
 			},
 		},
 		{
@@ -104,14 +114,92 @@ var nodeCommand = cli.Command{
 					Name:  "routing,r",
 					Usage: "Routing for libp2p [nil, kaddht]",
 				},
+				cli.IntFlag{
+					Name:  "conn-manager-low",
+					Usage: "Number of connections the connection manager trims down to.",
+				},
+				cli.IntFlag{
+					Name:  "conn-manager-high",
+					Usage: "Number of connections above which the connection manager trims, or 0 to disable it.",
+				},
+				cli.StringFlag{
+					Name:  "conn-manager-grace-period",
+					Usage: "Duration new connections are protected from being trimmed.",
+				},
+				cli.StringFlag{
+					Name:  "restart-policy",
+					Usage: "Whether labapp is restarted when it crashes [never, on-failure]",
+				},
+				cli.Float64Flag{
+					Name:  "cpu-quota",
+					Usage: "Number of CPUs labapp may use, e.g. 0.5, or 0 to remove the limit.",
+				},
+				cli.StringFlag{
+					Name:  "memory-limit",
+					Usage: "Maximum memory labapp may use, e.g. 512MiB, or 0 to remove the limit.",
+				},
+				cli.IntFlag{
+					Name:  "io-weight",
+					Usage: "Relative share of block IO given to labapp [1-10000], or 0 to remove the limit.",
+				},
 			},
 		},
+		{
+			Name:      "logs",
+			Usage:     "Prints the logs of a node's p2p app.",
+			ArgsUsage: "<cluster> <id>",
+			Action:    logsNodeAction,
+			Flags: []cli.Flag{
+				&cli.BoolFlag{
+					Name:  "follow,f",
+					Usage: "Keeps streaming new logs",
+				},
+				&cli.StringFlag{
+					Name:  "since",
+					Usage: "Only prints logs since a timestamp (RFC3339) or a relative duration (e.g. 10m)",
+				},
+			},
+		},
+		{
+			Name:      "peer-state",
+			Usage:     "Inspects the internal state of a node's peer.",
+			ArgsUsage: "<cluster> <id>",
+			Action:    peerStateNodeAction,
+		},
+		{
+			Name:      "tasks",
+			Usage:     "Lists the tasks a node runs in the background.",
+			ArgsUsage: "<cluster> <id>",
+			Action:    tasksNodeAction,
+		},
+		{
+			Name:      "cancel-task",
+			Usage:     "Cancels a task running in the background of a node.",
+			ArgsUsage: "<cluster> <id> <task>",
+			Action:    cancelTaskNodeAction,
+		},
 		{
 			Name:      "ssh",
-			Usage:     "SSH into a node.",
+			Usage:     "Opens an interactive shell on a node.",
 			ArgsUsage: "<cluster> <id>",
 			Action:    sshNodeAction,
 		},
+		{
+			Name:      "exec",
+			Usage:     "Executes a command on a node.",
+			ArgsUsage: "<cluster> <id> -- <command> [args...]",
+			Action:    execNodeAction,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "interactive,i",
+					Usage: "Forwards stdin to the command.",
+				},
+				cli.BoolFlag{
+					Name:  "tty,t",
+					Usage: "Allocates a pseudo-terminal for the command.",
+				},
+			},
+		},
 	},
 }
 
@@ -141,6 +229,127 @@ func inspectNodeAction(c *cli.Context) error {
 	return p.Print(node.Metadata())
 }
 
+func logsNodeAction(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return errors.New("cluster and node id must be provided")
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	var opts []p2plab.LogsOption
+	if c.Bool("follow") {
+		opts = append(opts, p2plab.WithLogsFollow())
+	}
+	if c.IsSet("since") {
+		since, err := parseSince(c.String("since"))
+		if err != nil {
+			return err
+		}
+		opts = append(opts, p2plab.WithLogsSince(since))
+	}
+
+	ctx := cliutil.CommandContext(c)
+	cluster := c.Args().First()
+	id := c.Args().Get(1)
+	node, err := control.Node().Get(ctx, cluster, id)
+	if err != nil {
+		return err
+	}
+
+	return node.Logs(ctx, os.Stdout, opts...)
+}
+
+// parseSince parses either a RFC3339 timestamp or a duration relative to now.
+func parseSince(since string) (time.Time, error) {
+	d, err := time.ParseDuration(since)
+	if err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, since)
+	if err != nil {
+		return t, errors.Errorf("invalid since %q: must be a RFC3339 timestamp or a duration", since)
+	}
+	return t, nil
+}
+
+func peerStateNodeAction(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return errors.New("cluster and node id must be provided")
+	}
+
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	cluster := c.Args().First()
+	id := c.Args().Get(1)
+	node, err := control.Node().Get(ctx, cluster, id)
+	if err != nil {
+		return err
+	}
+
+	state, err := node.PeerState(ctx)
+	if err != nil {
+		return err
+	}
+
+	return p.Print(state)
+}
+
+func tasksNodeAction(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return errors.New("cluster and node id must be provided")
+	}
+
+	p, err := CommandPrinter(c, printer.OutputTable)
+	if err != nil {
+		return err
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	execs, err := control.Node().Tasks(ctx, c.Args().First(), c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	l := make([]interface{}, len(execs))
+	for i, exec := range execs {
+		l[i] = exec
+	}
+
+	return p.Print(l)
+}
+
+func cancelTaskNodeAction(c *cli.Context) error {
+	if c.NArg() != 3 {
+		return errors.New("cluster, node id and task id must be provided")
+	}
+
+	control, err := ResolveControl(c)
+	if err != nil {
+		return err
+	}
+
+	ctx := cliutil.CommandContext(c)
+	return control.Node().CancelTask(ctx, c.Args().First(), c.Args().Get(1), c.Args().Get(2))
+}
+
 func labelNodesAction(c *cli.Context) error {
 	if c.NArg() < 1 {
 		return errors.New("cluster id must be provided")
@@ -225,6 +434,40 @@ func updateNodesAction(c *cli.Context) error {
 	if c.IsSet("routing") {
 		pdef.Routing = c.String("routing")
 	}
+	if c.IsSet("conn-manager-low") {
+		pdef.ConnManagerLow = c.Int("conn-manager-low")
+	}
+	if c.IsSet("conn-manager-high") {
+		pdef.ConnManagerHigh = c.Int("conn-manager-high")
+		if pdef.ConnManagerHigh == 0 {
+			pdef.ConnManagerHigh = metadata.ClearLimit
+		}
+	}
+	if c.IsSet("conn-manager-grace-period") {
+		pdef.ConnManagerGracePeriod = c.String("conn-manager-grace-period")
+	}
+	if c.IsSet("restart-policy") {
+		pdef.RestartPolicy = c.String("restart-policy")
+	}
+	// Limits set to zero are removed from the nodes.
+	if c.IsSet("cpu-quota") {
+		pdef.CPUQuota = c.Float64("cpu-quota")
+		if pdef.CPUQuota == 0 {
+			pdef.CPUQuota = metadata.ClearLimit
+		}
+	}
+	if c.IsSet("memory-limit") {
+		pdef.MemoryLimit = c.String("memory-limit")
+		if pdef.MemoryLimit == "" || pdef.MemoryLimit == "0" {
+			pdef.MemoryLimit = metadata.ClearMemoryLimit
+		}
+	}
+	if c.IsSet("io-weight") {
+		pdef.IOWeight = c.Int("io-weight")
+		if pdef.IOWeight == 0 {
+			pdef.IOWeight = metadata.ClearLimit
+		}
+	}
 
 	control, err := ResolveControl(c)
 	if err != nil {
@@ -275,6 +518,9 @@ func listNodeAction(c *cli.Context) error {
 
 		opts = append(opts, p2plab.WithQuery(q.String()))
 	}
+	if c.Bool("healthcheck") {
+		opts = append(opts, p2plab.WithHealthcheck())
+	}
 
 	cluster := c.Args().First()
 	nodes, err := control.Node().List(ctx, cluster, opts...)
@@ -306,10 +552,87 @@ func sshNodeAction(c *cli.Context) error {
 		return err
 	}
 
-	err = node.SSH(ctx)
+	return execNode(ctx, node, nil, true, terminal.IsTerminal(int(os.Stdin.Fd())))
+}
+
+func execNodeAction(c *cli.Context) error {
+	if c.NArg() < 3 {
+		return errors.New("cluster id, node id and command must be provided")
+	}
+
+	args := c.Args()[2:]
+	if args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return errors.New("command must be provided")
+	}
+
+	control, err := ResolveControl(c)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	ctx := cliutil.CommandContext(c)
+	node, err := control.Node().Get(ctx, c.Args().Get(0), c.Args().Get(1))
+	if err != nil {
+		return err
+	}
+
+	return execNode(ctx, node, args, c.Bool("interactive"), c.Bool("tty"))
+}
+
+// execNode executes a command, or a shell if args are empty, on a node with
+// its stdio attached to labctl's.
+func execNode(ctx context.Context, node p2plab.Node, args []string, interactive, tty bool) error {
+	var stdin io.Reader
+	if interactive {
+		stdin = os.Stdin
+	}
+
+	opts := []p2plab.SSHOption{
+		p2plab.WithSSHStdio(stdin, os.Stdout, os.Stderr),
+	}
+	if len(args) > 0 {
+		opts = append(opts, p2plab.WithSSHCommand(args...))
+	}
+
+	fd := int(os.Stdin.Fd())
+	if tty && terminal.IsTerminal(fd) {
+		cols, rows, err := terminal.GetSize(fd)
+		if err != nil {
+			return err
+		}
+
+		// Forward resizes of the local terminal to the remote one.
+		resize := make(chan p2plab.TerminalSize, 1)
+		winch := make(chan os.Signal, 1)
+		signal.Notify(winch, syscall.SIGWINCH)
+		defer signal.Stop(winch)
+		go func() {
+			for range winch {
+				cols, rows, err := terminal.GetSize(fd)
+				if err != nil {
+					continue
+				}
+				select {
+				case resize <- p2plab.TerminalSize{Rows: uint16(rows), Cols: uint16(cols)}:
+				default:
+				}
+			}
+		}()
+
+		size := p2plab.TerminalSize{Rows: uint16(rows), Cols: uint16(cols)}
+		opts = append(opts, p2plab.WithSSHTTY(size, resize))
+
+		state, err := terminal.MakeRaw(fd)
+		if err != nil {
+			return err
+		}
+		defer terminal.Restore(fd, state)
+	} else if tty {
+		opts = append(opts, p2plab.WithSSHTTY(p2plab.TerminalSize{Rows: 24, Cols: 80}, nil))
+	}
+
+	return node.SSH(ctx, opts...)
 }
@@ -20,6 +20,7 @@ import (
 	"os"
 	"syscall"
 
+	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/cmd/labctl/command"
 	"github.com/Netflix/p2plab/pkg/cliutil"
 	"github.com/rs/zerolog"
@@ -40,6 +41,11 @@ func main() {
 
 	app := command.App(ctx)
 	if err := app.Run(os.Args); err != nil {
+		// Commands executed on nodes exit with their remote exit status.
+		if exitErr, ok := err.(*p2plab.ExitError); ok {
+			os.Exit(exitErr.Code)
+		}
+
 		fmt.Fprintf(os.Stderr, "labctl: %s\n", err)
 		os.Exit(1)
 	}
@@ -17,15 +17,24 @@ package command
 import (
 	"context"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/Netflix/p2plab/downloaders"
 	"github.com/Netflix/p2plab/downloaders/s3downloader"
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/labd"
 	"github.com/Netflix/p2plab/pkg/cliutil"
+	"github.com/Netflix/p2plab/providers"
+	"github.com/Netflix/p2plab/providers/inventory"
+	"github.com/Netflix/p2plab/providers/plugin"
+	"github.com/Netflix/p2plab/providers/terraform"
 	"github.com/Netflix/p2plab/uploaders"
 	"github.com/Netflix/p2plab/uploaders/fileuploader"
 	"github.com/Netflix/p2plab/uploaders/s3uploader"
 	"github.com/Netflix/p2plab/version"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/urfave/cli"
 )
@@ -61,10 +70,51 @@ func App(ctx context.Context) *cli.App {
 		},
 		cli.StringFlag{
 			Name:   "provider,p",
-			Usage:  "set the provider to create node groups [inmemory, terraform]",
+			Usage:  "set the provider to create node groups [inmemory, inventory, netns, terraform, or a registered plugin]",
 			Value:  "inmemory",
 			EnvVar: "LABD_PROVIDER",
 		},
+		cli.StringFlag{
+			Name:   "provider.inventory.path",
+			Usage:  "path to the YAML or JSON inventory of hosts for inventory provider",
+			EnvVar: "LABD_PROVIDER_INVENTORY_PATH",
+		},
+		cli.StringFlag{
+			Name:   "provider.terraform.templates",
+			Usage:  "directory of terraform templates for terraform provider, defaults to the templates directory in the provider root",
+			EnvVar: "LABD_PROVIDER_TERRAFORM_TEMPLATES",
+		},
+		cli.StringFlag{
+			Name:   "provider.terraform.backend",
+			Usage:  "set the backend storing terraform state for terraform provider [s3, local]",
+			Value:  "s3",
+			EnvVar: "LABD_PROVIDER_TERRAFORM_BACKEND",
+		},
+		cli.StringFlag{
+			Name:   "provider.terraform.s3.bucket",
+			Usage:  "bucket name storing terraform state for terraform provider, required by the s3 backend",
+			EnvVar: "LABD_PROVIDER_TERRAFORM_S3_BUCKET",
+		},
+		cli.StringFlag{
+			Name:   "provider.terraform.s3.region",
+			Usage:  "region of the bucket storing terraform state for terraform provider, required by the s3 backend",
+			EnvVar: "LABD_PROVIDER_TERRAFORM_S3_REGION",
+		},
+		cli.StringFlag{
+			Name:   "provider.terraform.regions",
+			Usage:  "comma separated regions clusters can be deployed to for terraform provider, defaults to every region with subnets",
+			EnvVar: "LABD_PROVIDER_TERRAFORM_REGIONS",
+		},
+		cli.StringSliceFlag{
+			Name:   "provider.terraform.subnet",
+			Usage:  "add a subnet instances are launched in as region=subnet-id for terraform provider, may be repeated",
+			EnvVar: "LABD_PROVIDER_TERRAFORM_SUBNETS",
+		},
+		cli.StringSliceFlag{
+			Name:   "provider.plugin",
+			Usage:  "register an out-of-process provider plugin as name=path followed by any space separated arguments, which can then be set as the provider",
+			EnvVar: "LABD_PROVIDER_PLUGINS",
+		},
 		cli.StringFlag{
 			Name:   "uploader,u",
 			Usage:  "set the uploader to use to distribute p2p app binaries [file, s3]",
@@ -97,6 +147,11 @@ func App(ctx context.Context) *cli.App {
 			Usage:  "region for s3 downloader",
 			EnvVar: "LABD_DOWNLOADER_S3_REGION",
 		},
+		cli.DurationFlag{
+			Name:  "reconcile-interval",
+			Usage: "how often clusters are compared with the provider's node groups, 0 to only reconcile on startup",
+			Value: 5 * time.Minute,
+		},
 	}
 	app.Action = daemonAction
 
@@ -113,10 +168,40 @@ func daemonAction(c *cli.Context) error {
 		return err
 	}
 
+	plugins, err := parsePlugins(c.GlobalStringSlice("provider.plugin"))
+	if err != nil {
+		return err
+	}
+
+	subnets, err := parseSubnets(c.GlobalStringSlice("provider.terraform.subnet"))
+	if err != nil {
+		return err
+	}
+
+	var regions []string
+	if c.GlobalString("provider.terraform.regions") != "" {
+		regions = strings.Split(c.GlobalString("provider.terraform.regions"), ",")
+	}
+
 	ctx := cliutil.CommandContext(c)
 	daemon, err := labd.New(root, c.GlobalString("address"), zerolog.Ctx(ctx),
 		labd.WithLibp2pPort(c.GlobalInt("libp2p-port")),
+		labd.WithReconcileInterval(c.GlobalDuration("reconcile-interval")),
 		labd.WithProvider(c.GlobalString("provider")),
+		labd.WithProviderSettings(providers.ProviderSettings{
+			Inventory: inventory.InventoryProviderSettings{
+				Path: c.GlobalString("provider.inventory.path"),
+			},
+			Terraform: terraform.TerraformProviderSettings{
+				Templates:    c.GlobalString("provider.terraform.templates"),
+				Backend:      c.GlobalString("provider.terraform.backend"),
+				Bucket:       c.GlobalString("provider.terraform.s3.bucket"),
+				BucketRegion: c.GlobalString("provider.terraform.s3.region"),
+				Regions:      regions,
+				Subnets:      subnets,
+			},
+			Plugins: plugins,
+		}),
 		labd.WithUploader(c.GlobalString("uploader")),
 		labd.WithUploaderSettings(uploaders.UploaderSettings{
 			S3: s3uploader.S3UploaderSettings{
@@ -141,3 +226,49 @@ func daemonAction(c *cli.Context) error {
 
 	return daemon.Serve(ctx)
 }
+
+func parseSubnets(specs []string) (map[string][]string, error) {
+	subnets := make(map[string][]string)
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "terraform subnet %q must be region=subnet-id", spec)
+		}
+
+		subnets[parts[0]] = append(subnets[parts[0]], parts[1])
+	}
+	return subnets, nil
+}
+
+func parsePlugins(specs []string) (map[string]plugin.PluginSettings, error) {
+	plugins := make(map[string]plugin.PluginSettings)
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "provider plugin %q must be name=path", spec)
+		}
+
+		fields := strings.Fields(parts[1])
+		if len(fields) == 0 {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "provider plugin %q must be name=path", spec)
+		}
+
+		// Plugins run in a directory of their own, so relative paths are
+		// resolved against the working directory of labd. Bare names are
+		// still looked up in PATH.
+		path := fields[0]
+		if strings.ContainsRune(path, filepath.Separator) {
+			var err error
+			path, err = filepath.Abs(path)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to resolve provider plugin %q", spec)
+			}
+		}
+
+		plugins[parts[0]] = plugin.PluginSettings{
+			Path: path,
+			Args: fields[1:],
+		}
+	}
+	return plugins, nil
+}
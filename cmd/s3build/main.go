@@ -89,16 +89,11 @@ func run(ref string) error {
 	}
 	zerolog.Ctx(ctx).Info().Str("ref", ref).Str("commit", commit).Msg("Resolved ref")
 
-	link, err := builder.Build(ctx, commit)
-	if err != nil {
-		return err
-	}
-	zerolog.Ctx(ctx).Info().Str("link", link).Msg("Completed build")
-
-	build, err := db.GetBuild(ctx, commit)
+	build, err := builder.Build(ctx, commit)
 	if err != nil {
 		return err
 	}
+	zerolog.Ctx(ctx).Info().Str("link", build.Link).Str("digest", build.Digest.String()).Msg("Completed build")
 
 	content, err := json.MarshalIndent(&build, "", "    ")
 	if err != nil {
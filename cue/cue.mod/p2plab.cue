@@ -7,6 +7,8 @@ Group :: {
     size: >=1 | *1
     instanceType: string
     region: string
+    // number of nodes packed onto each instance, each running its own labapp
+    peersPerInstance: >=1 | *1
     // labels is an optional field
     labels?: [...string]
     // although not optional if left unspecified
@@ -20,6 +22,13 @@ Peer :: {
 	muxers: [...string] | *["mplex"]
 	securityTransports: [...string] | *["secio"]
 	routing: string | *"nil"
+	connManagerLow?: int
+	connManagerHigh?: int
+	connManagerGracePeriod?: string
+	restartPolicy?: "never" | "on-failure"
+	cpuQuota?: number
+	memoryLimit?: string
+	ioWeight?: int & >=1 & <=10000
 }
 
 // a cluster is a collection of 1 or more groups of nodes
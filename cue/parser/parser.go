@@ -17,6 +17,8 @@ var (
 		size: >=1 | *1
 		instanceType: string
 		region: string
+		// number of nodes packed onto each instance, each running its own labapp
+		peersPerInstance: >=1 | *1
 		// labels is an optional field
 		labels?: [...string]
 		// although not optional if left unspecified
@@ -30,6 +32,13 @@ var (
 		muxers: [...string] | *["mplex"]
 		securityTransports: [...string] | *["secio"]
 		routing: string | *"nil"
+		connManagerLow?: int
+		connManagerHigh?: int
+		connManagerGracePeriod?: string
+		restartPolicy?: "never" | "on-failure"
+		cpuQuota?: number
+		memoryLimit?: string
+		ioWeight?: int & >=1 & <=10000
 	}
 	
 	// a cluster is a collection of 1 or more groups of nodes
@@ -0,0 +1,182 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dag
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	cid "github.com/ipfs/go-cid"
+	chunker "github.com/ipfs/go-ipfs-chunker"
+	files "github.com/ipfs/go-ipfs-files"
+	ipld "github.com/ipfs/go-ipld-format"
+	merkledag "github.com/ipfs/go-merkledag"
+	unixfs "github.com/ipfs/go-unixfs"
+	"github.com/ipfs/go-unixfs/importer/balanced"
+	"github.com/ipfs/go-unixfs/importer/helpers"
+	"github.com/ipfs/go-unixfs/importer/trickle"
+	unixfsio "github.com/ipfs/go-unixfs/io"
+	"github.com/pkg/errors"
+)
+
+// Add imports files, directories and symlinks into dserv as a Unixfs DAG
+// shaped by the settings, and returns its root.
+func Add(ctx context.Context, dserv ipld.DAGService, n files.Node, settings p2plab.AddSettings) (ipld.Node, error) {
+	if settings.NoCopy {
+		return nil, errors.Wrap(errdefs.ErrInvalidArgument, "adding without copying file data is not supported")
+	}
+
+	switch strings.ToLower(settings.LeafCodec) {
+	case "":
+	case "raw":
+		settings.RawLeaves = true
+	case "dag-pb", "protobuf":
+		settings.RawLeaves = false
+	default:
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "unrecognized leaf codec %q", settings.LeafCodec)
+	}
+
+	builder, err := settings.CidBuilder()
+	if err != nil {
+		return nil, err
+	}
+
+	return addNode(ctx, dserv, n, builder, settings)
+}
+
+func addNode(ctx context.Context, dserv ipld.DAGService, n files.Node, prefix cid.Builder, settings p2plab.AddSettings) (ipld.Node, error) {
+	switch f := n.(type) {
+	case files.Directory:
+		return addDirectory(ctx, dserv, f, prefix, settings)
+	case *files.Symlink:
+		return addSymlink(ctx, dserv, f, prefix)
+	case files.File:
+		return addFile(ctx, dserv, f, prefix, settings)
+	default:
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "unrecognized file type %T", n)
+	}
+}
+
+func addDirectory(ctx context.Context, dserv ipld.DAGService, dir files.Directory, prefix cid.Builder, settings p2plab.AddSettings) (ipld.Node, error) {
+	type entry struct {
+		name string
+		nd   ipld.Node
+	}
+
+	var entries []entry
+	it := dir.Entries()
+	for it.Next() {
+		if !settings.Hidden && strings.HasPrefix(it.Name(), ".") {
+			continue
+		}
+
+		child := it.Node()
+		nd, err := addNode(ctx, dserv, child, prefix, settings)
+		child.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to add %q", it.Name())
+		}
+
+		entries = append(entries, entry{it.Name(), nd})
+	}
+	if it.Err() != nil {
+		return nil, it.Err()
+	}
+
+	udir := unixfsio.NewDirectory(dserv)
+	udir.SetCidBuilder(prefix)
+
+	// Directories with too many entries to fit in a single block are sharded
+	// into a HAMT, unless sharding is forced for every directory.
+	if settings.Shard || (settings.ShardThreshold > 0 && len(entries) > settings.ShardThreshold) {
+		bdir, ok := udir.(*unixfsio.BasicDirectory)
+		if ok {
+			var err error
+			udir, err = bdir.SwitchToSharding(ctx)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to shard directory")
+			}
+		}
+	}
+
+	for _, e := range entries {
+		err := udir.AddChild(ctx, e.name, e.nd)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to add directory entry %q", e.name)
+		}
+	}
+
+	nd, err := udir.GetNode()
+	if err != nil {
+		return nil, err
+	}
+
+	err = dserv.Add(ctx, nd)
+	if err != nil {
+		return nil, err
+	}
+
+	return nd, nil
+}
+
+func addSymlink(ctx context.Context, dserv ipld.DAGService, link *files.Symlink, prefix cid.Builder) (ipld.Node, error) {
+	data, err := unixfs.SymlinkData(link.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	nd := merkledag.NodeWithData(data)
+	nd.SetCidBuilder(prefix)
+
+	err = dserv.Add(ctx, nd)
+	if err != nil {
+		return nil, err
+	}
+
+	return nd, nil
+}
+
+func addFile(ctx context.Context, dserv ipld.DAGService, f files.File, prefix cid.Builder, settings p2plab.AddSettings) (ipld.Node, error) {
+	dbp := helpers.DagBuilderParams{
+		Dagserv:    dserv,
+		RawLeaves:  settings.RawLeaves,
+		Maxlinks:   settings.MaxLinks,
+		CidBuilder: prefix,
+	}
+
+	chnk, err := chunker.FromString(f, settings.Chunker)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create chunker")
+	}
+
+	dbh, err := dbp.New(chnk)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create dag builder")
+	}
+
+	var nd ipld.Node
+	switch settings.Layout {
+	case "trickle":
+		nd, err = trickle.Layout(dbh)
+	case "balanced":
+		nd, err = balanced.Layout(dbh)
+	default:
+		return nil, errors.Errorf("unrecognized layout %q", settings.Layout)
+	}
+
+	return nd, err
+}
@@ -0,0 +1,143 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	files "github.com/ipfs/go-ipfs-files"
+	ipld "github.com/ipfs/go-ipld-format"
+	merkledag "github.com/ipfs/go-merkledag"
+	mdtest "github.com/ipfs/go-merkledag/test"
+	unixfs "github.com/ipfs/go-unixfs"
+	"github.com/ipfs/go-unixfs/importer/helpers"
+	unixfsio "github.com/ipfs/go-unixfs/io"
+	pb "github.com/ipfs/go-unixfs/pb"
+	"github.com/stretchr/testify/require"
+)
+
+func testAddSettings() p2plab.AddSettings {
+	return p2plab.AddSettings{
+		Layout:     "balanced",
+		Chunker:    "size-262144",
+		HashFunc:   "sha2-256",
+		MaxLinks:   helpers.DefaultLinksPerBlock,
+		CidVersion: 1,
+	}
+}
+
+func testDirectory() files.Directory {
+	return files.NewMapDirectory(map[string]files.Node{
+		"a":       files.NewBytesFile([]byte("a")),
+		"b":       files.NewBytesFile([]byte("b")),
+		".hidden": files.NewBytesFile([]byte("hidden")),
+		"sub": files.NewMapDirectory(map[string]files.Node{
+			"c": files.NewBytesFile([]byte("c")),
+		}),
+	})
+}
+
+func fsNode(t *testing.T, nd ipld.Node) *unixfs.FSNode {
+	pn, ok := nd.(*merkledag.ProtoNode)
+	require.True(t, ok)
+
+	fsn, err := unixfs.FSNodeFromBytes(pn.Data())
+	require.NoError(t, err)
+	return fsn
+}
+
+func entryNames(t *testing.T, dserv ipld.DAGService, nd ipld.Node) []string {
+	dir, err := unixfsio.NewDirectoryFromNode(dserv, nd)
+	require.NoError(t, err)
+
+	links, err := dir.Links(context.Background())
+	require.NoError(t, err)
+
+	var names []string
+	for _, link := range links {
+		names = append(names, link.Name)
+	}
+	return names
+}
+
+func TestAddDirectory(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name     string
+		settings func(*p2plab.AddSettings)
+		expected []string
+	}{
+		{"default", func(*p2plab.AddSettings) {}, []string{"a", "b", "sub"}},
+		{"hidden", func(s *p2plab.AddSettings) { s.Hidden = true }, []string{".hidden", "a", "b", "sub"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dserv := mdtest.Mock()
+			settings := testAddSettings()
+			tc.settings(&settings)
+
+			nd, err := Add(ctx, dserv, testDirectory(), settings)
+			require.NoError(t, err)
+			require.ElementsMatch(t, tc.expected, entryNames(t, dserv, nd))
+
+			dir, err := unixfsio.NewDirectoryFromNode(dserv, nd)
+			require.NoError(t, err)
+
+			sub, err := dir.Find(ctx, "sub")
+			require.NoError(t, err)
+			require.Equal(t, []string{"c"}, entryNames(t, dserv, sub))
+		})
+	}
+}
+
+func TestAddDirectorySharding(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name      string
+		shard     bool
+		threshold int
+		expected  pb.Data_DataType
+	}{
+		{"unsharded", false, 0, unixfs.TDirectory},
+		{"forced", true, 0, unixfs.THAMTShard},
+		{"above threshold", false, 2, unixfs.THAMTShard},
+		{"at threshold", false, 3, unixfs.TDirectory},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dserv := mdtest.Mock()
+			settings := testAddSettings()
+			settings.Shard = tc.shard
+			settings.ShardThreshold = tc.threshold
+
+			nd, err := Add(ctx, dserv, testDirectory(), settings)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.expected, fsNode(t, nd).Type())
+			require.ElementsMatch(t, []string{"a", "b", "sub"}, entryNames(t, dserv, nd))
+		})
+	}
+}
+
+func TestAddNoCopy(t *testing.T) {
+	settings := testAddSettings()
+	settings.NoCopy = true
+
+	_, err := Add(context.Background(), mdtest.Mock(), files.NewBytesFile([]byte("a")), settings)
+	require.True(t, errdefs.IsInvalidArgument(err))
+}
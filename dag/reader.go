@@ -0,0 +1,69 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dag
+
+import (
+	"context"
+	"io"
+
+	"github.com/Netflix/p2plab/errdefs"
+	files "github.com/ipfs/go-ipfs-files"
+	"github.com/pkg/errors"
+)
+
+// Read streams the contents of a Unixfs file tree into w and returns the
+// number of bytes written. Directories are read depth-first in the order of
+// their links and symlinks contribute their target, so the same DAG always
+// produces the same stream.
+func Read(ctx context.Context, n files.Node, w io.Writer) (int64, error) {
+	defer n.Close()
+
+	switch f := n.(type) {
+	case *files.Symlink:
+		written, err := io.WriteString(w, f.Target)
+		return int64(written), err
+	case files.File:
+		return io.Copy(w, &contextReader{ctx, f})
+	case files.Directory:
+		var total int64
+		it := f.Entries()
+		for it.Next() {
+			written, err := Read(ctx, it.Node(), w)
+			total += written
+			if err != nil {
+				return total, errors.Wrapf(err, "failed to read %q", it.Name())
+			}
+		}
+		if it.Err() != nil {
+			return total, it.Err()
+		}
+		return total, nil
+	default:
+		return 0, errors.Wrapf(errdefs.ErrInvalidArgument, "unsupported node type %T", n)
+	}
+}
+
+// contextReader stops reading once the context is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
@@ -24,20 +24,32 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+// Walk fetches the full DAG rooted at c.
 func Walk(ctx context.Context, c cid.Cid, ng ipld.NodeGetter) error {
+	return WalkDepth(ctx, c, ng, -1)
+}
+
+// WalkDepth fetches the DAG rooted at c up to depth links deep. A negative
+// depth fetches the full DAG.
+func WalkDepth(ctx context.Context, c cid.Cid, ng ipld.NodeGetter, depth int) error {
 	span, ctx := traceutil.StartSpanFromContext(ctx, "dag.Walk")
 	defer span.Finish()
 	span.SetTag("cid", c.String())
+	span.SetTag("depth", depth)
 
 	nd, err := ng.Get(ctx, c)
 	if err != nil {
 		return err
 	}
 
-	return walk(ctx, nd, ng)
+	return walk(ctx, nd, ng, depth)
 }
 
-func walk(ctx context.Context, nd ipld.Node, ng ipld.NodeGetter) error {
+func walk(ctx context.Context, nd ipld.Node, ng ipld.NodeGetter, depth int) error {
+	if depth == 0 {
+		return nil
+	}
+
 	var cids []cid.Cid
 	for _, link := range nd.Links() {
 		cids = append(cids, link.Cid)
@@ -60,7 +72,7 @@ func walk(ctx context.Context, nd ipld.Node, ng ipld.NodeGetter) error {
 
 		nd := ndOpt.Node
 		eg.Go(func() error {
-			return walk(gctx, nd, ng)
+			return walk(gctx, nd, ng, depth-1)
 		})
 	}
 
@@ -31,7 +31,7 @@ type Builder interface {
 	Resolve(ctx context.Context, ref string) (commit string, err error)
 
 	// Build compiles the peer at the given commit.
-	Build(ctx context.Context, commit string) (link string, err error)
+	Build(ctx context.Context, commit string) (metadata.Build, error)
 }
 
 // BuildAPI defines the API for build operations.
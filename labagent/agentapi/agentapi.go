@@ -19,12 +19,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/labagent/agentexec"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/httputil"
 	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
@@ -44,22 +51,42 @@ func (a *api) url(endpoint string, v ...interface{}) string {
 	return fmt.Sprintf("%s%s", a.addr, fmt.Sprintf(endpoint, v...))
 }
 
-func (a *api) Healthcheck(ctx context.Context) bool {
-	req := a.client.NewRequest("GET", a.url("/healthcheck"),
+func (a *api) Healthcheck(ctx context.Context) (metadata.AgentStatus, error) {
+	status, err := a.status(ctx,
 		httputil.WithRetryWaitMax(5*time.Minute),
 		httputil.WithRetryMax(10),
 	)
-	resp, err := req.Send(ctx)
 	if err != nil {
 		zerolog.Ctx(ctx).Debug().Str("err", err.Error()).Str("addr", a.addr).Msg("unhealthy")
-		return false
+		return status, err
+	}
+
+	return status, nil
+}
+
+func (a *api) Status(ctx context.Context) (metadata.AgentStatus, error) {
+	return a.status(ctx, httputil.WithRetryMax(0))
+}
+
+func (a *api) status(ctx context.Context, opts ...httputil.RequestOption) (metadata.AgentStatus, error) {
+	var status metadata.AgentStatus
+
+	req := a.client.NewRequest("GET", a.url("/healthcheck"), opts...)
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return status, err
 	}
 	defer resp.Body.Close()
 
-	return true
+	err = json.NewDecoder(resp.Body).Decode(&status)
+	if err != nil {
+		return status, err
+	}
+
+	return status, nil
 }
 
-func (a *api) Update(ctx context.Context, id, link string, pdef metadata.PeerDefinition) error {
+func (a *api) Update(ctx context.Context, id string, build metadata.Build, pdef metadata.PeerDefinition) error {
 	content, err := json.MarshalIndent(&pdef, "", "    ")
 	if err != nil {
 		return err
@@ -68,7 +95,13 @@ func (a *api) Update(ctx context.Context, id, link string, pdef metadata.PeerDef
 	req := a.client.NewRequest("PUT", a.url("/update")).
 		Body(bytes.NewReader(content)).
 		Option("id", id).
-		Option("link", link)
+		Option("build", build.ID).
+		Option("link", build.Link)
+
+	if build.Digest != "" {
+		req.Option("digest", build.Digest.String()).
+			Option("size", build.Size)
+	}
 
 	resp, err := req.Send(ctx)
 	if err != nil {
@@ -87,6 +120,80 @@ func (a *api) Update(ctx context.Context, id, link string, pdef metadata.PeerDef
 	return nil
 }
 
+func (a *api) Logs(ctx context.Context, w io.Writer, opts ...p2plab.LogsOption) error {
+	var settings p2plab.LogsSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return err
+		}
+	}
+
+	req := a.client.NewRequest("GET", a.url("/logs"))
+	if settings.Follow {
+		req.Option("follow", "true")
+	}
+	if !settings.Since.IsZero() {
+		req.Option("since", settings.Since.Format(time.RFC3339Nano))
+	}
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+	if err != nil && ctx.Err() == nil {
+		return err
+	}
+
+	return nil
+}
+
 func (a *api) SSH(ctx context.Context, opts ...p2plab.SSHOption) error {
+	var settings p2plab.SSHSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return err
+		}
+	}
+
+	u, err := url.Parse(a.url("/exec"))
+	if err != nil {
+		return err
+	}
+	u.Scheme = "ws"
+
+	q := u.Query()
+	for _, arg := range settings.Command {
+		q.Add("cmd", arg)
+	}
+	if settings.TTY {
+		q.Set("tty", "true")
+		q.Set("rows", strconv.Itoa(int(settings.Size.Rows)))
+		q.Set("cols", strconv.Itoa(int(settings.Size.Cols)))
+	}
+	u.RawQuery = q.Encode()
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		if resp != nil {
+			defer resp.Body.Close()
+			msg, _ := ioutil.ReadAll(resp.Body)
+			return errors.Wrapf(err, "failed to exec on %s: %s", a.addr, bytes.TrimSpace(msg))
+		}
+		return errors.Wrapf(err, "failed to exec on %s", a.addr)
+	}
+
+	exit, err := agentexec.Attach(ctx, conn, settings.Stdin, settings.Stdout, settings.Stderr, settings.Resize)
+	if err != nil {
+		return err
+	}
+	if exit.Code != 0 {
+		return &p2plab.ExitError{Code: exit.Code}
+	}
+
 	return nil
 }
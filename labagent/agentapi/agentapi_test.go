@@ -0,0 +1,126 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agentapi
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/labagent/agentexec"
+	"github.com/Netflix/p2plab/pkg/httputil"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestAPI returns an agent API to a server that executes commands like the
+// labagent's exec route, but outside of any labapp.
+func newTestAPI(t *testing.T) (p2plab.AgentAPI, func()) {
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		command := agentexec.Command{
+			Args: r.URL.Query()["cmd"],
+			TTY:  r.FormValue("tty") == "true",
+		}
+		if command.TTY {
+			rows, _ := strconv.ParseUint(r.FormValue("rows"), 10, 16)
+			cols, _ := strconv.ParseUint(r.FormValue("cols"), 10, 16)
+			command.Size = p2plab.TerminalSize{Rows: uint16(rows), Cols: uint16(cols)}
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		agentexec.Serve(context.Background(), conn, command)
+	}))
+
+	client, err := httputil.NewClient(httputil.NewHTTPClient())
+	require.NoError(t, err)
+
+	return New(client, srv.URL), srv.Close
+}
+
+func TestSSH(t *testing.T) {
+	api, cleanup := newTestAPI(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// The input spans many messages, and cat only exits once stdin is closed.
+	input := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+
+	var stdout, stderr bytes.Buffer
+	err := api.SSH(ctx,
+		p2plab.WithSSHCommand("/bin/sh", "-c", "cat; echo done >&2"),
+		p2plab.WithSSHStdio(bytes.NewReader(input), &stdout, &stderr),
+	)
+	require.NoError(t, err)
+	require.Equal(t, input, stdout.Bytes())
+	require.Equal(t, "done\n", stderr.String())
+}
+
+func TestSSHExitError(t *testing.T) {
+	api, cleanup := newTestAPI(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := api.SSH(ctx, p2plab.WithSSHCommand("/bin/sh", "-c", "exit 3"))
+	require.Equal(t, &p2plab.ExitError{Code: 3}, err)
+
+	err = api.SSH(ctx, p2plab.WithSSHCommand("/nonexistent"))
+	require.Error(t, err)
+	require.False(t, isExitError(err), "commands that fail to start are not exit errors")
+}
+
+func TestSSHResize(t *testing.T) {
+	api, cleanup := newTestAPI(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	resize := make(chan p2plab.TerminalSize, 1)
+	resize <- p2plab.TerminalSize{Rows: 30, Cols: 100}
+
+	var stdout bytes.Buffer
+	err := api.SSH(ctx,
+		p2plab.WithSSHCommand("/bin/sh", "-c", `stty size; until [ "$(stty size)" = "30 100" ]; do sleep 0.1; done; stty size`),
+		p2plab.WithSSHTTY(p2plab.TerminalSize{Rows: 24, Cols: 80}, resize),
+		p2plab.WithSSHStdio(nil, &stdout, nil),
+	)
+	if err != nil && !isExitError(err) {
+		t.Skipf("failed to allocate a pseudo-terminal: %s", err)
+	}
+	require.NoError(t, err)
+
+	// The resize may arrive before the command first reads its size.
+	out := stdout.String()
+	require.Contains(t, out, "30 100\r\n")
+	require.Regexp(t, `^(24 80|30 100)\r\n`, out)
+}
+
+func isExitError(err error) bool {
+	_, ok := err.(*p2plab.ExitError)
+	return ok
+}
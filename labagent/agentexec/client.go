@@ -0,0 +1,104 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agentexec
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+
+	"github.com/Netflix/p2plab"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+// Attach streams stdio between a websocket connection and the given readers
+// and writers until the remote command exits, and returns its exit status.
+// A nil stdin sends no input, and nil writers discard output.
+func Attach(ctx context.Context, wsConn *websocket.Conn, stdin io.Reader, stdout, stderr io.Writer, resize <-chan p2plab.TerminalSize) (Exit, error) {
+	c := &conn{Conn: wsConn}
+	defer c.Close()
+
+	if stdout == nil {
+		stdout = ioutil.Discard
+	}
+	if stderr == nil {
+		stderr = ioutil.Discard
+	}
+
+	go func() {
+		<-ctx.Done()
+		c.Close()
+	}()
+
+	go func() {
+		if stdin != nil {
+			_, err := io.Copy(&streamWriter{c, MessageStdin}, stdin)
+			if err != nil {
+				return
+			}
+		}
+		c.send(MessageCloseStdin, nil)
+	}()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case size, ok := <-resize:
+				if !ok {
+					return
+				}
+				c.sendJSON(MessageResize, &size)
+			}
+		}
+	}()
+
+	for {
+		_, msg, err := c.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				return Exit{}, ctx.Err()
+			}
+			return Exit{}, errors.Wrap(err, "connection closed before command exited")
+		}
+		if len(msg) == 0 {
+			continue
+		}
+
+		typ, payload := msg[0], msg[1:]
+		switch typ {
+		case MessageStdout:
+			_, err = stdout.Write(payload)
+		case MessageStderr:
+			_, err = stderr.Write(payload)
+		case MessageExit:
+			var exit Exit
+			err = json.Unmarshal(payload, &exit)
+			if err != nil {
+				return exit, err
+			}
+			if exit.Error != "" {
+				return exit, errors.New(exit.Error)
+			}
+			return exit, nil
+		}
+		if err != nil {
+			return Exit{}, err
+		}
+	}
+}
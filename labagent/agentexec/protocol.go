@@ -0,0 +1,93 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package agentexec implements the protocol used to execute commands on a
+// labagent's host over a websocket.
+//
+// Every message is a binary websocket message whose first byte is its type,
+// followed by its payload. The client sends stdin, resize and close stdin
+// messages, and the server sends stdout, stderr and a final exit message.
+package agentexec
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// MessageStdin carries input for the command.
+	MessageStdin byte = iota
+
+	// MessageStdout carries output of the command. When a TTY is allocated,
+	// stdout and stderr are both sent as stdout.
+	MessageStdout
+
+	MessageStderr
+
+	// MessageResize carries a JSON TerminalSize to resize the TTY to.
+	MessageResize
+
+	// MessageCloseStdin signals that there is no more input.
+	MessageCloseStdin
+
+	// MessageExit carries the JSON Exit status of the command. It is the last
+	// message sent by the server.
+	MessageExit
+)
+
+// Exit is the exit status of a command.
+type Exit struct {
+	Code int
+
+	// Error is set when the command could not be run at all.
+	Error string `json:",omitempty"`
+}
+
+// conn serializes writes to a websocket connection, which supports only one
+// concurrent writer.
+type conn struct {
+	*websocket.Conn
+	mu sync.Mutex
+}
+
+func (c *conn) send(typ byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.WriteMessage(websocket.BinaryMessage, append([]byte{typ}, payload...))
+}
+
+func (c *conn) sendJSON(typ byte, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.send(typ, payload)
+}
+
+// streamWriter writes to the connection as messages of a type.
+type streamWriter struct {
+	conn *conn
+	typ  byte
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	err := w.conn.send(w.typ, p)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
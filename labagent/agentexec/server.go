@@ -0,0 +1,171 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agentexec
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/pkg/netnsutil"
+	"github.com/creack/pty"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+)
+
+// DefaultShell is the command run when no command is given.
+var DefaultShell = []string{"/bin/sh"}
+
+// Command is a command to execute for a client.
+type Command struct {
+	Args []string
+
+	// Dir is the working directory of the command.
+	Dir string
+
+	// NetworkNamespace is the path to a network namespace the command is
+	// started in. By default it shares the labagent's network namespace.
+	NetworkNamespace string
+
+	// TTY runs the command in a pseudo-terminal of the given size.
+	TTY  bool
+	Size p2plab.TerminalSize
+}
+
+// start starts a command in its network namespace.
+func start(command Command, fn func() error) error {
+	if command.NetworkNamespace != "" {
+		return netnsutil.Do(command.NetworkNamespace, fn)
+	}
+	return fn()
+}
+
+// Serve executes a command with its stdio attached to a websocket connection,
+// until it exits or the client disconnects.
+func Serve(ctx context.Context, wsConn *websocket.Conn, command Command) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	c := &conn{Conn: wsConn}
+	defer c.Close()
+
+	args := command.Args
+	if len(args) == 0 {
+		args = DefaultShell
+	}
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = command.Dir
+	cmd.Env = os.Environ()
+
+	var (
+		stdin  io.WriteCloser
+		tty    *os.File
+		copied sync.WaitGroup
+		err    error
+	)
+	if command.TTY {
+		cmd.Env = append(cmd.Env, "TERM=xterm-256color")
+		err = start(command, func() error {
+			var err error
+			tty, err = pty.StartWithSize(cmd, &pty.Winsize{
+				Rows: command.Size.Rows,
+				Cols: command.Size.Cols,
+			})
+			return err
+		})
+		if err == nil {
+			defer tty.Close()
+			stdin = tty
+
+			copied.Add(1)
+			go func() {
+				defer copied.Done()
+				// Reading the pty fails once the command exits and all its output
+				// has been read.
+				io.Copy(&streamWriter{c, MessageStdout}, tty)
+			}()
+		}
+	} else {
+		cmd.Stdout = &streamWriter{c, MessageStdout}
+		cmd.Stderr = &streamWriter{c, MessageStderr}
+		stdin, err = cmd.StdinPipe()
+		if err == nil {
+			err = start(command, cmd.Start)
+		}
+	}
+	if err != nil {
+		return c.sendJSON(MessageExit, &Exit{Code: -1, Error: err.Error()})
+	}
+
+	go func() {
+		// Kill the command if the client disconnects.
+		defer cancel()
+
+		for {
+			_, msg, err := c.ReadMessage()
+			if err != nil {
+				return
+			}
+			if len(msg) == 0 {
+				continue
+			}
+
+			typ, payload := msg[0], msg[1:]
+			switch typ {
+			case MessageStdin:
+				_, err = stdin.Write(payload)
+			case MessageCloseStdin:
+				// Closing a pty would also close the command's output.
+				if tty == nil {
+					err = stdin.Close()
+				}
+			case MessageResize:
+				var size p2plab.TerminalSize
+				err = json.Unmarshal(payload, &size)
+				if err == nil && tty != nil {
+					err = pty.Setsize(tty, &pty.Winsize{Rows: size.Rows, Cols: size.Cols})
+				}
+			}
+			if err != nil {
+				zerolog.Ctx(ctx).Debug().Err(err).Msg("failed to handle exec message")
+			}
+		}
+	}()
+
+	err = cmd.Wait()
+	copied.Wait()
+
+	exit := Exit{}
+	if cmd.ProcessState != nil {
+		exit.Code = cmd.ProcessState.ExitCode()
+	} else if err != nil {
+		exit.Code = -1
+		exit.Error = err.Error()
+	}
+
+	err = c.sendJSON(MessageExit, &exit)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+}
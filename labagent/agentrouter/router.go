@@ -18,46 +18,200 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/daemon"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/labagent/agentexec"
 	"github.com/Netflix/p2plab/labagent/supervisor"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/gorilla/websocket"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
 type router struct {
-	addr       string
-	supervisor supervisor.Supervisor
+	addr        string
+	supervisors *supervisor.Pool
+	host        metadata.AgentStatus
 }
 
-func New(addr string, s supervisor.Supervisor) daemon.Router {
-	return &router{addr, s}
+func New(addr string, supervisors *supervisor.Pool) daemon.Router {
+	return &router{addr, supervisors, hostStatus()}
 }
 
 func (s *router) Routes() []daemon.Route {
-	return []daemon.Route{
-		// PUT
-		daemon.NewPutRoute("/update", s.putUpdate),
+	var routes []daemon.Route
+
+	// Routes without an instance prefix are served by the first labapp
+	// instance.
+	for _, prefix := range []string{"", "/instances/{instance}"} {
+		routes = append(routes,
+			// GET
+			daemon.NewGetRoute(prefix+"/healthcheck", s.getHealthcheck),
+			daemon.NewGetRoute(prefix+"/logs", s.getLogs),
+			daemon.NewGetRoute(prefix+"/exec", s.getExec),
+			// PUT
+			daemon.NewPutRoute(prefix+"/update", s.putUpdate),
+		)
+	}
+
+	return routes
+}
+
+// instance returns the supervisor of the labapp instance in the route.
+func (s *router) instance(vars map[string]string) (supervisor.Supervisor, error) {
+	var instance int
+	if vars["instance"] != "" {
+		var err error
+		instance, err = strconv.Atoi(vars["instance"])
+		if err != nil {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid instance %q", vars["instance"])
+		}
+	}
+
+	return s.supervisors.Get(instance)
+}
+
+// getHealthcheck reports the status of the agent, its host and labapp, so that
+// stale agents and mismatched hosts can be found before benchmarking.
+func (s *router) getHealthcheck(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	sv, err := s.instance(vars)
+	if err != nil {
+		return err
+	}
+
+	status := s.host
+	status.BuildID = sv.BuildID()
+	status.Peer = sv.PeerDefinition()
+	status.App = sv.AppState()
+	return daemon.WriteJSON(w, &status)
+}
+
+func (s *router) getLogs(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	sv, err := s.instance(vars)
+	if err != nil {
+		return err
 	}
+
+	var settings p2plab.LogsSettings
+	if r.FormValue("follow") != "" {
+		var err error
+		settings.Follow, err = strconv.ParseBool(r.FormValue("follow"))
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid follow: %s", err)
+		}
+	}
+
+	if r.FormValue("since") != "" {
+		var err error
+		settings.Since, err = time.Parse(time.RFC3339Nano, r.FormValue("since"))
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid since: %s", err)
+		}
+	}
+
+	return sv.Logs(ctx, logutil.NewWriteFlusher(w), settings)
+}
+
+var upgrader = websocket.Upgrader{}
+
+// getExec upgrades to a websocket that executes a command, or an interactive
+// shell if none is given, in the labapp's root and network namespace.
+func (s *router) getExec(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	sv, err := s.instance(vars)
+	if err != nil {
+		return err
+	}
+
+	command := agentexec.Command{
+		Args:             r.URL.Query()["cmd"],
+		Dir:              sv.AppRoot(),
+		NetworkNamespace: sv.NetworkNamespace(),
+	}
+
+	if r.FormValue("tty") != "" {
+		var err error
+		command.TTY, err = strconv.ParseBool(r.FormValue("tty"))
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid tty: %s", err)
+		}
+	}
+
+	if command.TTY {
+		for _, dim := range []struct {
+			name  string
+			value *uint16
+		}{
+			{"rows", &command.Size.Rows},
+			{"cols", &command.Size.Cols},
+		} {
+			v, err := strconv.ParseUint(r.FormValue(dim.name), 10, 16)
+			if err != nil {
+				return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid %s: %s", dim.name, err)
+			}
+			*dim.value = uint16(v)
+		}
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied with an error.
+		zerolog.Ctx(ctx).Debug().Err(err).Msg("failed to upgrade exec connection")
+		return nil
+	}
+
+	zerolog.Ctx(ctx).Info().Strs("cmd", command.Args).Bool("tty", command.TTY).Msg("Executing command")
+	err = agentexec.Serve(ctx, conn, command)
+	if err != nil {
+		// The connection is hijacked, so the error can no longer be served.
+		zerolog.Ctx(ctx).Debug().Err(err).Msg("failed to serve exec connection")
+	}
+
+	return nil
 }
 
 func (s *router) putUpdate(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	sv, err := s.instance(vars)
+	if err != nil {
+		return err
+	}
+
 	id := r.FormValue("id")
-	link := r.FormValue("link")
+	build := metadata.Build{
+		ID:   r.FormValue("build"),
+		Link: r.FormValue("link"),
+	}
+
+	dgst := r.FormValue("digest")
+	if dgst != "" {
+		build.Digest, err = digest.Parse(dgst)
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid digest %q", dgst)
+		}
+
+		build.Size, err = strconv.ParseInt(r.FormValue("size"), 10, 64)
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid size %q", r.FormValue("size"))
+		}
+	}
+
 	ctx, logger := logutil.WithResponseLogger(ctx, w)
 	logger.UpdateContext(func(c zerolog.Context) zerolog.Context {
-		return c.Str("id", id).Str("link", link)
+		return c.Str("id", id).Str("link", build.Link).Str("digest", build.Digest.String())
 	})
 
 	var pdef metadata.PeerDefinition
-	err := json.NewDecoder(r.Body).Decode(&pdef)
+	err = json.NewDecoder(r.Body).Decode(&pdef)
 	if err != nil {
 		return err
 	}
 
-	err = s.supervisor.Supervise(ctx, id, link, pdef)
+	err = sv.Supervise(ctx, id, build, pdef)
 	if err != nil {
 		return err
 	}
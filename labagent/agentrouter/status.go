@@ -0,0 +1,83 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agentrouter
+
+import (
+	"net"
+	"runtime"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/procutil"
+	"github.com/Netflix/p2plab/version"
+)
+
+// hostStatus returns the parts of the agent's status that don't change while
+// it runs. Host details that cannot be read on this platform are left empty.
+func hostStatus() metadata.AgentStatus {
+	status := metadata.AgentStatus{
+		Version:    version.Version,
+		Revision:   version.Revision,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		CPUs:       runtime.NumCPU(),
+		Transports: detectTransports(),
+	}
+
+	status.Kernel, _ = procutil.KernelRelease()
+	status.Memory, _ = procutil.MemTotal()
+	return status
+}
+
+// transportNetworks are the networks each libp2p transport of labapp needs.
+var transportNetworks = map[string]string{
+	"tcp":  "tcp",
+	"ws":   "tcp",
+	"quic": "udp",
+}
+
+// detectTransports returns the transports of labapp whose network the host
+// can listen on.
+func detectTransports() []string {
+	var transports []string
+	for _, transport := range metadata.Transports {
+		network, ok := transportNetworks[transport]
+		if !ok || !canListen(network) {
+			continue
+		}
+		transports = append(transports, transport)
+	}
+	return transports
+}
+
+// canListen returns whether the host can listen on the network's loopback.
+func canListen(network string) bool {
+	switch network {
+	case "tcp":
+		l, err := net.Listen(network, "127.0.0.1:0")
+		if err != nil {
+			return false
+		}
+		l.Close()
+	case "udp":
+		conn, err := net.ListenPacket(network, "127.0.0.1:0")
+		if err != nil {
+			return false
+		}
+		conn.Close()
+	default:
+		return false
+	}
+	return true
+}
@@ -20,16 +20,17 @@ import (
 	"path/filepath"
 
 	"github.com/Netflix/p2plab/daemon"
-	"github.com/Netflix/p2plab/daemon/healthcheckrouter"
 	"github.com/Netflix/p2plab/downloaders"
 	"github.com/Netflix/p2plab/labagent/agentrouter"
 	"github.com/Netflix/p2plab/labagent/supervisor"
 	"github.com/Netflix/p2plab/pkg/httputil"
+	"github.com/Netflix/p2plab/pkg/netnsutil"
 	"github.com/rs/zerolog"
 )
 
 type LabAgent struct {
 	daemon  *daemon.Daemon
+	netns   string
 	closers []io.Closer
 }
 
@@ -50,15 +51,19 @@ func New(root, addr, appRoot, appAddr string, logger *zerolog.Logger, opts ...La
 	settings.DownloaderSettings.Client = client
 	fs := downloaders.New(filepath.Join(root, "downloaders"), settings.DownloaderSettings)
 
-	s, err := supervisor.New(filepath.Join(root, "supervisor"), appRoot, appAddr, client, fs)
+	var supervisorOpts []supervisor.SupervisorOption
+	if settings.NetworkNamespace != "" {
+		supervisorOpts = append(supervisorOpts, supervisor.WithNetworkNamespace(settings.NetworkNamespace))
+	}
+
+	supervisors, err := supervisor.NewPool(filepath.Join(root, "supervisor"), appRoot, appAddr, client, fs, logger, supervisorOpts...)
 	if err != nil {
 		return nil, err
 	}
 
 	var closers []io.Closer
 	daemon, err := daemon.New("labagent", addr, logger,
-		healthcheckrouter.New(),
-		agentrouter.New(appAddr, s),
+		agentrouter.New(appAddr, supervisors),
 	)
 	if err != nil {
 		return nil, err
@@ -67,6 +72,7 @@ func New(root, addr, appRoot, appAddr string, logger *zerolog.Logger, opts ...La
 
 	return &LabAgent{
 		daemon:  daemon,
+		netns:   settings.NetworkNamespace,
 		closers: closers,
 	}, nil
 }
@@ -82,5 +88,13 @@ func (a *LabAgent) Close() error {
 }
 
 func (a *LabAgent) Serve(ctx context.Context) error {
+	if a.netns != "" {
+		// The daemon's listener is created in the namespace, and stays there
+		// while requests are served from other threads.
+		return netnsutil.Do(a.netns, func() error {
+			return a.daemon.Serve(ctx)
+		})
+	}
+
 	return a.daemon.Serve(ctx)
 }
@@ -20,6 +20,10 @@ type LabagentOption func(*LabagentSettings) error
 
 type LabagentSettings struct {
 	DownloaderSettings downloaders.DownloaderSettings
+
+	// NetworkNamespace is the path to a network namespace the labagent serves
+	// from and starts labapp in.
+	NetworkNamespace string
 }
 
 func WithDownloaderSettings(settings downloaders.DownloaderSettings) LabagentOption {
@@ -28,3 +32,12 @@ func WithDownloaderSettings(settings downloaders.DownloaderSettings) LabagentOpt
 		return nil
 	}
 }
+
+// WithNetworkNamespace serves the labagent and starts labapp in the network
+// namespace at path.
+func WithNetworkNamespace(path string) LabagentOption {
+	return func(s *LabagentSettings) error {
+		s.NetworkNamespace = path
+		return nil
+	}
+}
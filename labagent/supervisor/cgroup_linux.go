@@ -0,0 +1,270 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package supervisor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+const (
+	cgroup2SuperMagic = 0x63677270
+
+	// agentCgroup is the leaf cgroup labagent moves itself into.
+	agentCgroup = "labagent"
+
+	// shellPath is the shell used to start labapp in its cgroup.
+	shellPath = "/bin/sh"
+)
+
+// cgroup is a cgroup v2 that enforces the resource limits of labapp.
+type cgroup struct {
+	path string
+}
+
+// newCgroup creates a cgroup with the given limits. If no resource is limited,
+// no cgroup is created and a nil cgroup is returned.
+func newCgroup(name string, l limits) (*cgroup, error) {
+	if l.IsZero() {
+		return nil, nil
+	}
+
+	var st syscall.Statfs_t
+	err := syscall.Statfs(CgroupMountpoint, &st)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to stat %q", CgroupMountpoint)
+	}
+	if st.Type != cgroup2SuperMagic {
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "resource limits require cgroup v2 mounted at %q", CgroupMountpoint)
+	}
+
+	delegated, err := delegatedCgroup()
+	if err != nil {
+		return nil, err
+	}
+
+	// Controllers must be enabled in every ancestor for the limits to apply to
+	// the labapp cgroup. Only the cgroup delegated to labagent and its
+	// descendants are modified.
+	var controllers []string
+	if l.cpuQuota > 0 {
+		controllers = append(controllers, "cpu")
+	}
+	if l.memoryMax > 0 {
+		controllers = append(controllers, "memory")
+	}
+	if l.ioWeight > 0 {
+		controllers = append(controllers, "io")
+	}
+
+	err = checkControllers(delegated, controllers)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := filepath.Join(delegated, CgroupParent)
+	err = os.MkdirAll(parent, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	var enable []string
+	for _, controller := range controllers {
+		enable = append(enable, "+"+controller)
+	}
+	for _, dir := range []string{delegated, parent} {
+		err = writeCgroupFile(dir, "cgroup.subtree_control", strings.Join(enable, " "))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c := &cgroup{path: filepath.Join(parent, name)}
+	err = os.Mkdir(c.path, 0755)
+	if err != nil && !os.IsExist(err) {
+		return nil, err
+	}
+
+	if l.cpuQuota > 0 {
+		err = writeCgroupFile(c.path, "cpu.max", fmt.Sprintf("%d %d", l.cpuQuota, cpuPeriod))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if l.memoryMax > 0 {
+		err = writeCgroupFile(c.path, "memory.max", strconv.FormatUint(l.memoryMax, 10))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if l.ioWeight > 0 {
+		err = writeCgroupFile(c.path, "io.weight", fmt.Sprintf("default %d", l.ioWeight))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Wrap changes cmd to join the cgroup before it executes, so that the process
+// is never running outside of it. The command is run by a shell that moves
+// itself into the cgroup and then replaces itself with the original command.
+func (c *cgroup) Wrap(cmd *exec.Cmd) {
+	if c == nil {
+		return
+	}
+
+	cmd.Args = append([]string{
+		"sh", "-c", `echo $$ > "$0" && exec "$@"`,
+		filepath.Join(c.path, "cgroup.procs"),
+		cmd.Path,
+	}, cmd.Args[1:]...)
+	cmd.Path = shellPath
+}
+
+// Remove deletes the cgroup. The cgroup must no longer have any processes,
+// which may take a moment after they are killed.
+func (c *cgroup) Remove() error {
+	if c == nil {
+		return nil
+	}
+
+	var err error
+	for i := 0; i < 10; i++ {
+		err = os.Remove(c.path)
+		if err == nil || os.IsNotExist(err) {
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	return errors.Wrapf(err, "failed to remove cgroup %q", c.path)
+}
+
+var (
+	delegateOnce sync.Once
+	delegatePath string
+	delegateErr  error
+)
+
+// delegatedCgroup returns the path of the cgroup labagent was started in,
+// which must be delegated to it. The first call moves labagent into a leaf
+// cgroup, because controllers cannot be enabled for the children of a cgroup
+// that has processes.
+func delegatedCgroup() (string, error) {
+	delegateOnce.Do(func() {
+		delegatePath, delegateErr = setupDelegatedCgroup()
+	})
+	return delegatePath, delegateErr
+}
+
+func setupDelegatedCgroup() (string, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	self, err := parseCgroupFile(f)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(CgroupMountpoint, self)
+	leaf := filepath.Join(dir, agentCgroup)
+	err = os.MkdirAll(leaf, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	err = writeCgroupFile(leaf, "cgroup.procs", strconv.Itoa(os.Getpid()))
+	if err != nil {
+		return "", err
+	}
+
+	return dir, nil
+}
+
+// parseCgroupFile returns the cgroup v2 path of a process from the contents
+// of its /proc/<pid>/cgroup file.
+func parseCgroupFile(r io.Reader) (string, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// The unified hierarchy has the entry "0::<path>". If labagent already
+		// moved itself into its leaf, the delegated cgroup is its parent.
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 || parts[0] != "0" || parts[1] != "" {
+			continue
+		}
+
+		path := parts[2]
+		if filepath.Base(path) == agentCgroup {
+			path = filepath.Dir(path)
+		}
+		return path, nil
+	}
+	if scanner.Err() != nil {
+		return "", scanner.Err()
+	}
+
+	return "", errors.Wrap(errdefs.ErrInvalidArgument, "process is not in a cgroup v2 hierarchy")
+}
+
+// checkControllers returns an error if a controller is not available in the
+// cgroup at dir.
+func checkControllers(dir string, controllers []string) error {
+	content, err := ioutil.ReadFile(filepath.Join(dir, "cgroup.controllers"))
+	if err != nil {
+		return err
+	}
+
+	available := make(map[string]struct{})
+	for _, controller := range strings.Fields(string(content)) {
+		available[controller] = struct{}{}
+	}
+
+	for _, controller := range controllers {
+		if _, ok := available[controller]; !ok {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "controller %q is not delegated to cgroup %q", controller, dir)
+		}
+	}
+
+	return nil
+}
+
+func writeCgroupFile(dir, name, value string) error {
+	err := ioutil.WriteFile(filepath.Join(dir, name), []byte(value), 0644)
+	if err != nil {
+		return errors.Wrapf(err, "failed to write %q to %s", value, filepath.Join(dir, name))
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package supervisor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCgroupFile(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{"unified", "0::/system.slice/labagent.service\n", "/system.slice/labagent.service"},
+		{"hybrid", "12:cpu,cpuacct:/system.slice\n0::/system.slice/labagent.service\n", "/system.slice/labagent.service"},
+		{"namespace root", "0::/\n", "/"},
+		{"agent leaf", "0::/system.slice/labagent.service/labagent\n", "/system.slice/labagent.service"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path, err := parseCgroupFile(strings.NewReader(tc.content))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, path)
+		})
+	}
+
+	_, err := parseCgroupFile(strings.NewReader("12:cpu,cpuacct:/system.slice\n"))
+	require.True(t, errdefs.IsInvalidArgument(err))
+}
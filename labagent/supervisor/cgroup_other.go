@@ -0,0 +1,40 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !linux
+// +build !linux
+
+package supervisor
+
+import (
+	"os/exec"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+type cgroup struct{}
+
+func newCgroup(name string, l limits) (*cgroup, error) {
+	if l.IsZero() {
+		return nil, nil
+	}
+	return nil, errors.Wrap(errdefs.ErrInvalidArgument, "resource limits require cgroup v2 on linux")
+}
+
+func (c *cgroup) Wrap(cmd *exec.Cmd) {}
+
+func (c *cgroup) Remove() error {
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package supervisor
+
+import (
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	humanize "github.com/dustin/go-humanize"
+	"github.com/pkg/errors"
+)
+
+var (
+	// CgroupMountpoint is where the cgroup v2 unified hierarchy is mounted.
+	CgroupMountpoint = "/sys/fs/cgroup"
+
+	// CgroupParent is the cgroup under the cgroup delegated to labagent in
+	// which a cgroup is created for every labapp with resource limits.
+	CgroupParent = "p2plab"
+)
+
+// cpuPeriod is the period in microseconds over which the CPU quota is
+// enforced.
+const cpuPeriod = 100000
+
+// limits are the resource limits of a labapp process.
+type limits struct {
+	// cpuQuota is the CPU time in microseconds labapp may use every cpuPeriod.
+	cpuQuota int64
+
+	memoryMax uint64
+
+	ioWeight int
+}
+
+func parseLimits(pdef metadata.PeerDefinition) (limits, error) {
+	var l limits
+	if pdef.CPUQuota < 0 {
+		return l, errors.Wrapf(errdefs.ErrInvalidArgument, "cpu quota %v must not be negative", pdef.CPUQuota)
+	}
+	l.cpuQuota = int64(pdef.CPUQuota * cpuPeriod)
+
+	if pdef.MemoryLimit != "" {
+		var err error
+		l.memoryMax, err = humanize.ParseBytes(pdef.MemoryLimit)
+		if err != nil {
+			return l, errors.Wrapf(errdefs.ErrInvalidArgument, "memory limit %q: %s", pdef.MemoryLimit, err)
+		}
+	}
+
+	if pdef.IOWeight < 0 || pdef.IOWeight > 10000 {
+		return l, errors.Wrapf(errdefs.ErrInvalidArgument, "io weight %d must be between 1 and 10000, or 0 for no limit", pdef.IOWeight)
+	}
+	l.ioWeight = pdef.IOWeight
+
+	return l, nil
+}
+
+// IsZero returns true if no resource is limited.
+func (l limits) IsZero() bool {
+	return l == limits{}
+}
@@ -0,0 +1,366 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package supervisor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+var (
+	// MaxLogFiles is the number of labapp log files retained.
+	MaxLogFiles = 10
+
+	// MaxLogFileSize is the size in bytes past which a log file is rotated.
+	// Log files are only rotated at the end of a line, so that lines are
+	// never split across files, and may exceed it by the line being written.
+	MaxLogFileSize int64 = 10 * 1024 * 1024
+
+	// LogPollInterval is the interval between checks for new log lines when
+	// following logs.
+	LogPollInterval = 250 * time.Millisecond
+)
+
+// createLogFile creates a new log file for labapp, and removes the oldest log
+// files beyond MaxLogFiles.
+func (s *supervisor) createLogFile(ctx context.Context) (*os.File, error) {
+	logRoot := filepath.Join(s.root, "logs")
+	err := os.MkdirAll(logRoot, 0711)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Create(filepath.Join(logRoot, fmt.Sprintf("labapp-%d.log", time.Now().UnixNano())))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create log file")
+	}
+
+	s.logMu.Lock()
+	s.logPath = f.Name()
+	s.logMu.Unlock()
+
+	paths, err := s.logFiles()
+	if err != nil {
+		return f, err
+	}
+
+	for len(paths) > MaxLogFiles {
+		zerolog.Ctx(ctx).Debug().Str("path", paths[0]).Msg("Removing old log file")
+		err = os.Remove(paths[0])
+		if err != nil {
+			return f, err
+		}
+		paths = paths[1:]
+	}
+
+	return f, nil
+}
+
+// logWriter writes the logs of a run of labapp, rotating its log file once it
+// grows past MaxLogFileSize.
+type logWriter struct {
+	ctx  context.Context
+	s    *supervisor
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// newLogWriter creates the first log file for a new run of labapp.
+func (s *supervisor) newLogWriter(ctx context.Context) (*logWriter, error) {
+	f, err := s.createLogFile(ctx)
+	if err != nil {
+		if f != nil {
+			f.Close()
+		}
+		return nil, err
+	}
+
+	s.logMu.Lock()
+	s.runLogPath = f.Name()
+	s.logMu.Unlock()
+
+	return &logWriter{ctx: ctx, s: s, f: f}, nil
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var written int
+	for len(p) > 0 {
+		chunk := p
+		if w.size+int64(len(p)) > MaxLogFileSize {
+			i := bytes.IndexByte(p, '\n')
+			if i >= 0 {
+				chunk = p[:i+1]
+			}
+		}
+
+		n, err := w.f.Write(chunk)
+		written += n
+		w.size += int64(n)
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+
+		if w.size >= MaxLogFileSize && chunk[len(chunk)-1] == '\n' {
+			w.rotate()
+		}
+	}
+	return written, nil
+}
+
+// rotate continues writing to a new log file. Failing to rotate is not fatal
+// to labapp, so logs continue to be written to the current log file.
+func (w *logWriter) rotate() {
+	f, err := w.s.createLogFile(w.ctx)
+	if err != nil {
+		zerolog.Ctx(w.ctx).Warn().Err(err).Msg("Failed to rotate log file")
+	}
+	if f == nil {
+		return
+	}
+
+	w.f.Close()
+	w.f = f
+	w.size = 0
+}
+
+func (w *logWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.f.Close()
+}
+
+// logFiles returns every retained log file, oldest first.
+func (s *supervisor) logFiles() ([]string, error) {
+	logRoot := filepath.Join(s.root, "logs")
+	infos, err := ioutil.ReadDir(logRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), "labapp-") && strings.HasSuffix(info.Name(), ".log") {
+			paths = append(paths, filepath.Join(logRoot, info.Name()))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// logFilesSince returns the log files before latest of the run that started
+// with runStart, and those of previous runs that were written to at or after
+// since, oldest first.
+func (s *supervisor) logFilesSince(since time.Time, runStart, latest string) ([]string, error) {
+	paths, err := s.logFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var prev []string
+	for _, path := range paths {
+		if path >= latest {
+			break
+		}
+
+		if path >= runStart {
+			prev = append(prev, path)
+			continue
+		}
+
+		if since.IsZero() {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		if !info.ModTime().Before(since) {
+			prev = append(prev, path)
+		}
+	}
+	return prev, nil
+}
+
+func (s *supervisor) latestLogPath() string {
+	s.logMu.Lock()
+	defer s.logMu.Unlock()
+
+	return s.logPath
+}
+
+// currentLogPaths returns the first and the latest log file of the current
+// run of labapp.
+func (s *supervisor) currentLogPaths() (runStart, latest string) {
+	s.logMu.Lock()
+	defer s.logMu.Unlock()
+
+	return s.runLogPath, s.logPath
+}
+
+// Logs copies the logs of labapp's current run, from every log file it was
+// rotated through. When a time is given, the logs of every run since then are
+// copied, so that the logs leading up to a crash are not lost when labapp is
+// restarted.
+func (s *supervisor) Logs(ctx context.Context, w io.Writer, settings p2plab.LogsSettings) error {
+	runStart, path := s.currentLogPaths()
+	if path == "" {
+		return errors.Wrap(errdefs.ErrNotFound, "no labapp logs")
+	}
+
+	prev, err := s.logFilesSince(settings.Since, runStart, path)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range prev {
+		_, err = s.copyLogs(ctx, p, w, p2plab.LogsSettings{Since: settings.Since})
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		next, err := s.copyLogs(ctx, path, w, settings)
+		if err != nil {
+			return err
+		}
+
+		// Continue following the logs of the next run of labapp.
+		if next == "" {
+			return nil
+		}
+		path = next
+	}
+}
+
+// copyLogs copies the log lines of a run since the given time. When following,
+// it waits for new log lines until the context is done or a new log file is
+// created, because it was rotated or labapp was started again, in which case
+// the path of the new log file is returned.
+func (s *supervisor) copyLogs(ctx context.Context, path string, w io.Writer, settings p2plab.LogsSettings) (next string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var (
+		r       = bufio.NewReader(f)
+		pending []byte
+		include = settings.Since.IsZero()
+	)
+	for {
+		line, err := r.ReadBytes('\n')
+		pending = append(pending, line...)
+		if err == nil {
+			include = includeLogLine(pending, settings.Since, include)
+			if include {
+				_, err = w.Write(pending)
+				if err != nil {
+					return "", err
+				}
+			}
+			pending = nil
+			continue
+		}
+		if err != io.EOF {
+			return "", err
+		}
+
+		if !settings.Follow {
+			if len(pending) > 0 && includeLogLine(pending, settings.Since, include) {
+				_, err = w.Write(pending)
+				if err != nil {
+					return "", err
+				}
+			}
+			return "", nil
+		}
+
+		latest := s.latestLogPath()
+		if latest != path && len(pending) == 0 {
+			return latest, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", nil
+		case <-time.After(LogPollInterval):
+		}
+	}
+}
+
+// includeLogLine returns whether a log line was logged at or after since.
+// Lines without a timestamp, such as panics, follow the previous line.
+func includeLogLine(line []byte, since time.Time, prev bool) bool {
+	if since.IsZero() {
+		return true
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+
+	var evt map[string]interface{}
+	err := decoder.Decode(&evt)
+	if err != nil {
+		return prev
+	}
+
+	var t time.Time
+	switch v := evt[zerolog.TimestampFieldName].(type) {
+	case json.Number:
+		ms, err := v.Int64()
+		if err != nil {
+			return prev
+		}
+		t = time.Unix(0, ms*int64(time.Millisecond))
+	case string:
+		t, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			return prev
+		}
+	default:
+		return prev
+	}
+
+	return !t.Before(since)
+}
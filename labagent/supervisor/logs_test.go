@@ -0,0 +1,86 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package supervisor
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Netflix/p2plab"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogWriterRotate(t *testing.T) {
+	root, err := ioutil.TempDir("", "supervisor-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	defer func(size int64, files int) {
+		MaxLogFileSize = size
+		MaxLogFiles = files
+	}(MaxLogFileSize, MaxLogFiles)
+	MaxLogFileSize = 16
+	MaxLogFiles = 3
+
+	ctx := context.Background()
+	s := &supervisor{root: root}
+
+	// The logs of a previous run are removed once enough files are rotated.
+	prev, err := s.newLogWriter(ctx)
+	require.NoError(t, err)
+	_, err = prev.Write([]byte("previous run\n"))
+	require.NoError(t, err)
+	require.NoError(t, prev.Close())
+
+	w, err := s.newLogWriter(ctx)
+	require.NoError(t, err)
+	defer w.Close()
+
+	// Lines are split across writes and longer than a log file, but never
+	// split across log files.
+	for _, p := range []string{
+		"line 1\nline 2\nli",
+		"ne 3\n",
+		"a line longer than a log file\nline 5\n",
+	} {
+		n, err := w.Write([]byte(p))
+		require.NoError(t, err)
+		require.Equal(t, len(p), n)
+	}
+
+	paths, err := s.logFiles()
+	require.NoError(t, err)
+
+	var contents []string
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		contents = append(contents, string(content))
+	}
+	require.Equal(t, []string{
+		"line 1\nline 2\nline 3\n",
+		"a line longer than a log file\n",
+		"line 5\n",
+	}, contents)
+
+	var buf bytes.Buffer
+	err = s.Logs(ctx, &buf, p2plab.LogsSettings{})
+	require.NoError(t, err)
+	require.Equal(t, strings.Join(contents, ""), buf.String())
+}
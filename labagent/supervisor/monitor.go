@@ -0,0 +1,333 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package supervisor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/netnsutil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+var (
+	// MaxExitLogLines is the number of log lines retained from labapp to
+	// explain why it exited.
+	MaxExitLogLines = 20
+
+	// RestartBackoffMin is the delay before the first restart of a labapp
+	// that exited unexpectedly. The delay doubles on every consecutive restart.
+	RestartBackoffMin = time.Second
+
+	// RestartBackoffMax is the maximum delay between restarts.
+	RestartBackoffMax = time.Minute
+
+	// RestartResetPeriod is how long labapp must run before its restart
+	// backoff is reset.
+	RestartResetPeriod = 5 * time.Minute
+)
+
+// buildIDFile is the name of the file in the supervisor's root that records
+// the ID of the build labapp was last updated to.
+const buildIDFile = "labapp.build"
+
+func parseRestartPolicy(policy string) (metadata.RestartPolicy, error) {
+	switch metadata.RestartPolicy(policy) {
+	case "", metadata.RestartNever:
+		return metadata.RestartNever, nil
+	case metadata.RestartOnFailure:
+		return metadata.RestartOnFailure, nil
+	default:
+		return "", errors.Wrapf(errdefs.ErrInvalidArgument, "unknown restart policy %q", policy)
+	}
+}
+
+// run is a single execution of labapp.
+type run struct {
+	app  *exec.Cmd
+	logs *logWriter
+	tail *tailWriter
+}
+
+// spawn starts labapp in its cgroup with its output written to new log
+// files.
+func (s *supervisor) spawn(ctx context.Context, flags []string) (*run, error) {
+	logs, err := s.newLogWriter(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tail := newTailWriter(MaxExitLogLines)
+	app := s.cmd(ctx, io.MultiWriter(logs, tail), flags...)
+	s.cgroup.Wrap(app)
+	if s.netns != "" {
+		err = netnsutil.Do(s.netns, app.Start)
+	} else {
+		err = app.Start()
+	}
+	if err != nil {
+		logs.Close()
+		return nil, err
+	}
+
+	return &run{app, logs, tail}, nil
+}
+
+// monitor waits for labapp to exit and restarts it according to the restart
+// policy until ctx is canceled.
+func (s *supervisor) monitor(ctx context.Context, r *run, flags []string, policy metadata.RestartPolicy, done chan struct{}) {
+	defer close(done)
+
+	logger := zerolog.Ctx(ctx)
+	backoff := RestartBackoffMin
+	for {
+		r.app.Wait()
+		r.logs.Close()
+
+		state := s.recordExit(r)
+
+		// labapp is killed by canceling ctx when it is stopped or updated.
+		if ctx.Err() != nil {
+			logger.Debug().Int("pid", state.PID).Msg("labapp stopped")
+			return
+		}
+
+		if state.ExitCode == 0 && state.Signal == "" {
+			logger.Info().Int("pid", state.PID).Msg("labapp exited")
+			return
+		}
+
+		logger.Warn().
+			Int("pid", state.PID).
+			Int("exitCode", state.ExitCode).
+			Str("signal", state.Signal).
+			Msg("labapp exited unexpectedly")
+
+		if policy != metadata.RestartOnFailure {
+			return
+		}
+
+		if state.ExitedAt.Sub(state.StartedAt) > RestartResetPeriod {
+			backoff = RestartBackoffMin
+		}
+
+		for {
+			s.setStatus(metadata.AppRestarting)
+			logger.Info().Dur("backoff", backoff).Msg("Restarting labapp")
+
+			select {
+			case <-ctx.Done():
+				s.setStatus(metadata.AppExited)
+				return
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+			if backoff > RestartBackoffMax {
+				backoff = RestartBackoffMax
+			}
+
+			var err error
+			r, err = s.spawn(ctx, flags)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to restart labapp")
+				continue
+			}
+			break
+		}
+
+		s.recordStart(r.app, true)
+	}
+}
+
+// AppState returns the state of the supervised labapp.
+func (s *supervisor) AppState() metadata.AppState {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	state := s.state
+	state.LastLines = append([]string(nil), s.state.LastLines...)
+	return state
+}
+
+// BuildID returns the ID of the build labapp was last updated to.
+func (s *supervisor) BuildID() string {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	return s.buildID
+}
+
+// setBuildID records the ID of the build labapp was updated to, and persists
+// it next to the binary so it survives restarts of the agent.
+func (s *supervisor) setBuildID(id string) error {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	path := filepath.Join(s.root, buildIDFile)
+	tmpPath := fmt.Sprintf("%s.tmp", path)
+	err := ioutil.WriteFile(tmpPath, []byte(id), 0644)
+	if err != nil {
+		return errors.Wrap(err, "failed to write build id")
+	}
+
+	err = os.Rename(tmpPath, path)
+	if err != nil {
+		return errors.Wrap(err, "failed to write build id")
+	}
+
+	s.buildID = id
+	return nil
+}
+
+// readBuildID returns the build ID persisted in root, or an empty string if
+// labapp was never updated.
+func readBuildID(root string) (string, error) {
+	content, err := ioutil.ReadFile(filepath.Join(root, buildIDFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
+
+// PeerDefinition returns the peer definition labapp was last updated with.
+func (s *supervisor) PeerDefinition() metadata.PeerDefinition {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	return s.pdef
+}
+
+func (s *supervisor) setPeerDefinition(pdef metadata.PeerDefinition) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	s.pdef = pdef
+}
+
+func (s *supervisor) recordStart(app *exec.Cmd, restart bool) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	state := metadata.AppState{
+		Status:    metadata.AppRunning,
+		PID:       app.Process.Pid,
+		StartedAt: time.Now(),
+	}
+
+	// Keep the last exit so the cause of a restart remains visible.
+	if restart {
+		state.Restarts = s.state.Restarts + 1
+		state.ExitedAt = s.state.ExitedAt
+		state.ExitCode = s.state.ExitCode
+		state.Signal = s.state.Signal
+		state.LastLines = s.state.LastLines
+	}
+
+	s.state = state
+}
+
+func (s *supervisor) recordExit(r *run) metadata.AppState {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	s.state.Status = metadata.AppExited
+	s.state.ExitedAt = time.Now()
+	s.state.LastLines = r.tail.Lines()
+
+	ps := r.app.ProcessState
+	if ps != nil {
+		s.state.ExitCode = ps.ExitCode()
+		status, ok := ps.Sys().(syscall.WaitStatus)
+		if ok && status.Signaled() {
+			s.state.Signal = status.Signal().String()
+		}
+	}
+
+	return s.state
+}
+
+func (s *supervisor) setStatus(status metadata.AppStatus) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	s.state.Status = status
+}
+
+// tailWriter retains the last lines written to it.
+type tailWriter struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial []byte
+}
+
+func newTailWriter(max int) *tailWriter {
+	return &tailWriter{max: max}
+}
+
+func (t *tailWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.partial = append(t.partial, p...)
+	for {
+		i := bytes.IndexByte(t.partial, '\n')
+		if i < 0 {
+			break
+		}
+
+		t.lines = append(t.lines, string(t.partial[:i]))
+		if len(t.lines) > t.max {
+			t.lines = t.lines[len(t.lines)-t.max:]
+		}
+		t.partial = append(t.partial[:0], t.partial[i+1:]...)
+	}
+
+	return len(p), nil
+}
+
+// Lines returns the retained lines, including a trailing line that was not
+// terminated by a newline.
+func (t *tailWriter) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	lines := append([]string(nil), t.lines...)
+	if len(t.partial) > 0 {
+		lines = append(lines, string(t.partial))
+	}
+	if len(lines) > t.max {
+		lines = lines[len(lines)-t.max:]
+	}
+	return lines
+}
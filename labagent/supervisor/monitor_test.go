@@ -0,0 +1,72 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package supervisor
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRestartPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		policy   string
+		expected metadata.RestartPolicy
+		invalid  bool
+	}{
+		{"", metadata.RestartNever, false},
+		{"never", metadata.RestartNever, false},
+		{"on-failure", metadata.RestartOnFailure, false},
+		{"always", "", true},
+		{"On-Failure", "", true},
+	} {
+		policy, err := parseRestartPolicy(tc.policy)
+		if tc.invalid {
+			require.True(t, errdefs.IsInvalidArgument(err), tc.policy)
+			continue
+		}
+		require.NoError(t, err, tc.policy)
+		require.Equal(t, tc.expected, policy, tc.policy)
+	}
+}
+
+func TestTailWriter(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		max      int
+		writes   []string
+		expected []string
+	}{
+		{"empty", 2, nil, nil},
+		{"lines", 3, []string{"a\nb\n"}, []string{"a", "b"}},
+		{"truncated", 2, []string{"a\nb\nc\n", "d\n"}, []string{"c", "d"}},
+		{"split", 3, []string{"a", "b\nc", "d\n"}, []string{"ab", "cd"}},
+		{"partial", 2, []string{"a\nb\nc"}, []string{"b", "c"}},
+		{"empty lines", 3, []string{"\n\na\n"}, []string{"", "", "a"}},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			w := newTailWriter(tc.max)
+			for _, p := range tc.writes {
+				n, err := w.Write([]byte(p))
+				require.NoError(t, err)
+				require.Equal(t, len(p), n)
+			}
+			require.Equal(t, tc.expected, w.Lines())
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package supervisor
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/Netflix/p2plab/downloaders"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/pkg/httputil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// MaxInstances is the maximum number of labapp instances a labagent
+// supervises.
+var MaxInstances = 256
+
+// Pool supervises multiple labapp instances on the same host. Instance 0 uses
+// the labagent's root, app root and app address as is, and every other
+// instance gets its own directories and the app port offset by its index.
+// Builds are cached once for all instances.
+type Pool struct {
+	root    string
+	appRoot string
+	appHost string
+	appPort int
+	client  *httputil.Client
+	fs      *downloaders.Downloaders
+	logger  *zerolog.Logger
+	opts    []SupervisorOption
+
+	mu          sync.Mutex
+	supervisors map[int]*supervisor
+}
+
+func NewPool(root, appRoot, appAddr string, client *httputil.Client, fs *downloaders.Downloaders, logger *zerolog.Logger, opts ...SupervisorOption) (*Pool, error) {
+	u, err := url.Parse(appAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	appHost, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	appPort, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid app port %q", port)
+	}
+
+	return &Pool{
+		root:        root,
+		appRoot:     appRoot,
+		appHost:     appHost,
+		appPort:     appPort,
+		client:      client,
+		fs:          fs,
+		logger:      logger,
+		opts:        opts,
+		supervisors: make(map[int]*supervisor),
+	}, nil
+}
+
+// Get returns the supervisor of a labapp instance, creating it if it doesn't
+// exist yet.
+func (p *Pool) Get(instance int) (Supervisor, error) {
+	if instance < 0 || instance >= MaxInstances {
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "instance %d must be between 0 and %d", instance, MaxInstances-1)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, ok := p.supervisors[instance]
+	if ok {
+		return s, nil
+	}
+
+	root, appRoot := p.root, p.appRoot
+	if instance > 0 {
+		root = filepath.Join(p.root, "instances", strconv.Itoa(instance))
+		appRoot = fmt.Sprintf("%s-%d", p.appRoot, instance)
+	}
+
+	appAddr := fmt.Sprintf("http://%s", net.JoinHostPort(p.appHost, strconv.Itoa(p.appPort+instance)))
+	sv, err := New(root, appRoot, appAddr, p.client, p.fs, p.logger, p.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s = sv.(*supervisor)
+	s.cacheRoot = filepath.Join(p.root, "cache")
+	p.supervisors[instance] = s
+	return s, nil
+}
@@ -29,31 +29,94 @@ import (
 	"sync"
 	"syscall"
 
+	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/downloaders"
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/httputil"
 	"github.com/Netflix/p2plab/pkg/traceutil"
+	digest "github.com/opencontainers/go-digest"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
 type Supervisor interface {
-	Supervise(ctx context.Context, id, link string, pdef metadata.PeerDefinition) error
+	// Supervise restarts labapp with the peer definition. If the build has a
+	// link, labapp is first replaced with the build's binary.
+	Supervise(ctx context.Context, id string, build metadata.Build, pdef metadata.PeerDefinition) error
+
+	// Logs writes the logs of labapp to w.
+	Logs(ctx context.Context, w io.Writer, settings p2plab.LogsSettings) error
+
+	// AppState returns the state of the supervised labapp.
+	AppState() metadata.AppState
+
+	// BuildID returns the ID of the build labapp was last updated to.
+	BuildID() string
+
+	// PeerDefinition returns the peer definition labapp was last updated
+	// with.
+	PeerDefinition() metadata.PeerDefinition
+
+	// AppRoot returns the path to labapp's state directory.
+	AppRoot() string
+
+	// NetworkNamespace returns the path to the network namespace labapp is
+	// started in, or an empty string if it shares the labagent's.
+	NetworkNamespace() string
+}
+
+// SupervisorOption configures a supervisor.
+type SupervisorOption func(*SupervisorSettings) error
+
+type SupervisorSettings struct {
+	// NetworkNamespace is the path to a network namespace labapp is started
+	// in. By default labapp shares the labagent's network namespace.
+	NetworkNamespace string
+}
+
+// WithNetworkNamespace starts labapp in the network namespace at path.
+func WithNetworkNamespace(path string) SupervisorOption {
+	return func(s *SupervisorSettings) error {
+		s.NetworkNamespace = path
+		return nil
+	}
 }
 
 type supervisor struct {
-	root    string
-	appRoot string
-	appPort string
-	app     *exec.Cmd
-	client  *httputil.Client
-	fs      *downloaders.Downloaders
-	mu      sync.Mutex
-	cancel  func()
+	root      string
+	appRoot   string
+	appPort   string
+	cacheRoot string
+	netns     string
+	client    *httputil.Client
+	fs        *downloaders.Downloaders
+	logger    *zerolog.Logger
+	mu        sync.Mutex
+	cancel    func()
+	done      chan struct{}
+	cgroup    *cgroup
+
+	logMu      sync.Mutex
+	logPath    string
+	runLogPath string
+
+	stateMu sync.Mutex
+	state   metadata.AppState
+	buildID string
+	pdef    metadata.PeerDefinition
 }
 
-func New(root, appRoot, appAddr string, client *httputil.Client, fs *downloaders.Downloaders) (Supervisor, error) {
+func New(root, appRoot, appAddr string, client *httputil.Client, fs *downloaders.Downloaders, logger *zerolog.Logger, opts ...SupervisorOption) (Supervisor, error) {
+	var settings SupervisorSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	err := os.MkdirAll(root, 0711)
 	if err != nil {
 		return nil, err
@@ -69,27 +132,63 @@ func New(root, appRoot, appAddr string, client *httputil.Client, fs *downloaders
 		return nil, err
 	}
 
+	buildID, err := readBuildID(root)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read build id")
+	}
+
 	return &supervisor{
-		root:    root,
-		appRoot: appRoot,
-		appPort: appPort,
-		client:  client,
-		fs:      fs,
+		root:      root,
+		appRoot:   appRoot,
+		appPort:   appPort,
+		cacheRoot: filepath.Join(root, "cache"),
+		netns:     settings.NetworkNamespace,
+		client:    client,
+		fs:        fs,
+		logger:    logger,
+		buildID:   buildID,
 	}, nil
 }
 
-func (s *supervisor) Supervise(ctx context.Context, id, link string, pdef metadata.PeerDefinition) error {
+func (s *supervisor) AppRoot() string {
+	return s.appRoot
+}
+
+func (s *supervisor) NetworkNamespace() string {
+	return s.netns
+}
+
+func (s *supervisor) Supervise(ctx context.Context, id string, build metadata.Build, pdef metadata.PeerDefinition) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	err := s.kill(ctx)
+	policy, err := parseRestartPolicy(pdef.RestartPolicy)
+	if err != nil {
+		return err
+	}
+
+	l, err := parseLimits(pdef)
+	if err != nil {
+		return err
+	}
+
+	err = s.kill(ctx)
 	if err != nil {
 		return err
 	}
 
+	s.cgroup, err = newCgroup(id, l)
+	if err != nil {
+		return errors.Wrap(err, "failed to apply resource limits")
+	}
+
 	flags := s.peerDefinitionToFlags(id, pdef)
-	if link != "" {
-		err = s.atomicReplaceBinary(ctx, link)
+	if build.Link != "" {
+		err = s.atomicReplaceBinary(ctx, build)
+		if err != nil {
+			return err
+		}
+		err = s.setBuildID(build.ID)
 		if err != nil {
 			return err
 		}
@@ -99,7 +198,12 @@ func (s *supervisor) Supervise(ctx context.Context, id, link string, pdef metada
 			return err
 		}
 
-		return s.start(ctx, flags)
+		err = s.start(ctx, flags, policy)
+		if err != nil {
+			return err
+		}
+		s.setPeerDefinition(pdef)
+		return nil
 	} else {
 		return s.wait(ctx, flags)
 	}
@@ -125,18 +229,34 @@ func (s *supervisor) peerDefinitionToFlags(id string, pdef metadata.PeerDefiniti
 	if pdef.Routing != "" {
 		flags = append(flags, fmt.Sprintf("--libp2p-routing=%s", pdef.Routing))
 	}
+	if pdef.ConnManagerHigh > 0 {
+		flags = append(flags,
+			fmt.Sprintf("--libp2p-conn-manager-low=%d", pdef.ConnManagerLow),
+			fmt.Sprintf("--libp2p-conn-manager-high=%d", pdef.ConnManagerHigh),
+		)
+	}
+	if pdef.ConnManagerGracePeriod != "" {
+		flags = append(flags, fmt.Sprintf("--libp2p-conn-manager-grace-period=%s", pdef.ConnManagerGracePeriod))
+	}
 
 	return flags
 }
 
-func (s *supervisor) start(ctx context.Context, flags []string) error {
-	var actx context.Context
-	actx, s.cancel = context.WithCancel(context.Background())
-	s.app = s.cmd(actx, flags...)
-	err := s.app.Start()
+func (s *supervisor) start(ctx context.Context, flags []string, policy metadata.RestartPolicy) error {
+	// The app outlives the request that started it, so it is monitored with the
+	// labagent's logger.
+	actx, cancel := context.WithCancel(s.logger.WithContext(context.Background()))
+
+	r, err := s.spawn(actx, flags)
 	if err != nil {
+		cancel()
 		return err
 	}
+	s.recordStart(r.app, false)
+
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.monitor(actx, r, flags, policy, s.done)
 
 	v := new(bytes.Buffer)
 	versionCmd := s.cmdWithStdio(actx, v, ioutil.Discard, "--version")
@@ -166,49 +286,69 @@ func (s *supervisor) wait(ctx context.Context, flags []string) error {
 		flags = append(flags, fmt.Sprintf("--trace=%s", trace))
 	}
 
-	s.app = s.cmd(context.Background(), flags...)
+	r, err := s.spawn(context.Background(), flags)
+	if err != nil {
+		return err
+	}
+	s.recordStart(r.app, false)
+
+	exited := make(chan struct{})
+	defer close(exited)
 
 	go func() {
-		<-ctx.Done()
-		if s.app.Process != nil {
-			zerolog.Ctx(ctx).Info().Msg("Forwarding kill signal to labapp")
-			err := s.app.Process.Signal(syscall.SIGTERM)
-			if err != nil {
-				zerolog.Ctx(ctx).Error().Err(err).Msg("failed to SIGTERM labapp")
-			}
+		select {
+		case <-ctx.Done():
+		case <-exited:
+			return
+		}
+
+		zerolog.Ctx(ctx).Info().Msg("Forwarding kill signal to labapp")
+		err := r.app.Process.Signal(syscall.SIGTERM)
+		if err != nil {
+			zerolog.Ctx(ctx).Error().Err(err).Msg("failed to SIGTERM labapp")
 		}
 	}()
 
-	return s.app.Run()
+	err = r.app.Wait()
+	r.logs.Close()
+	s.recordExit(r)
+
+	rerr := s.removeCgroup()
+	if rerr != nil {
+		zerolog.Ctx(ctx).Warn().Err(rerr).Msg("failed to remove cgroup")
+	}
+
+	return err
 }
 
 func (s *supervisor) kill(ctx context.Context) error {
-	defer func() {
-		s.cancel = nil
-		s.app = nil
-	}()
-
 	if s.cancel == nil {
 		return nil
 	}
 
 	s.cancel()
-	err := s.app.Wait()
-	if err != nil {
-		exitErr, ok := err.(*exec.ExitError)
-		if !ok {
-			return errors.Wrap(err, "failed to wait for app to exit")
-		}
+	<-s.done
+	s.cancel = nil
+	s.done = nil
 
-		if exitErr.ProcessState.String() != "signal: killed" {
-			return errors.Wrapf(err, "app exited with unexpected status: %q", exitErr.ProcessState)
-		}
+	err := s.removeCgroup()
+	if err != nil {
+		return err
 	}
 
 	zerolog.Ctx(ctx).Debug().Msg("Successfully killed app")
 	return nil
 }
 
+func (s *supervisor) removeCgroup() error {
+	err := s.cgroup.Remove()
+	if err != nil {
+		return err
+	}
+	s.cgroup = nil
+	return nil
+}
+
 func (s *supervisor) clear(ctx context.Context) error {
 	err := os.RemoveAll(s.appRoot)
 	if err != nil {
@@ -223,57 +363,158 @@ func (s *supervisor) clear(ctx context.Context) error {
 	return nil
 }
 
-func (s *supervisor) atomicReplaceBinary(ctx context.Context, link string) error {
+func (s *supervisor) atomicReplaceBinary(ctx context.Context, build metadata.Build) error {
 	span, ctx := traceutil.StartSpanFromContext(ctx, "supervisor.atomicReplaceBinary")
 	defer span.Finish()
-	span.SetTag("link", link)
+	span.SetTag("link", build.Link)
+	span.SetTag("digest", build.Digest.String())
 
 	zerolog.Ctx(ctx).Debug().Str("root", s.root).Msg("Atomically replacing binary")
-	u, err := url.Parse(link)
+	binaryPath := filepath.Join(s.root, "labapp")
+
+	// Builds created before digests were recorded cannot be verified or cached.
+	if build.Digest == "" {
+		zerolog.Ctx(ctx).Warn().Str("link", build.Link).Msg("Build has no digest, skipping verification")
+		path, err := s.download(ctx, s.root, build)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(path)
+
+		return os.Rename(path, binaryPath)
+	}
+
+	cachePath, err := s.cacheBinary(ctx, build)
 	if err != nil {
 		return err
 	}
 
-	downloader, err := s.fs.Get(u.Scheme)
-	if err != nil {
+	// Link the cached binary next to labapp so that the rename is atomic and
+	// the cache entry remains.
+	tmpPath := fmt.Sprintf("%s.%s", binaryPath, build.Digest.Encoded())
+	err = os.Remove(tmpPath)
+	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
 
-	rc, err := downloader.Download(ctx, link)
+	err = os.Link(cachePath, tmpPath)
 	if err != nil {
 		return err
 	}
+	defer os.Remove(tmpPath)
+
+	// Atomically replace the binary.
+	return os.Rename(tmpPath, binaryPath)
+}
+
+// cacheBinary returns the path to the build's binary in a content-addressed
+// cache, downloading it only if it isn't already cached.
+func (s *supervisor) cacheBinary(ctx context.Context, build metadata.Build) (string, error) {
+	err := build.Digest.Validate()
+	if err != nil {
+		return "", errors.Wrapf(errdefs.ErrInvalidArgument, "build digest %q: %s", build.Digest, err)
+	}
+
+	dir := filepath.Join(s.cacheRoot, string(build.Digest.Algorithm()))
+	cachePath := filepath.Join(dir, build.Digest.Encoded())
+	_, err = os.Stat(cachePath)
+	if err == nil {
+		zerolog.Ctx(ctx).Debug().Str("digest", build.Digest.String()).Msg("Using cached binary")
+		return cachePath, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	err = os.MkdirAll(dir, 0711)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := s.download(ctx, dir, build)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(path)
+
+	err = os.Rename(path, cachePath)
+	if err != nil {
+		return "", err
+	}
+
+	return cachePath, nil
+}
+
+// download writes the build's binary to a temporary file in dir and returns
+// its path. If the build has a digest, the binary is verified against its
+// digest and size.
+func (s *supervisor) download(ctx context.Context, dir string, build metadata.Build) (path string, err error) {
+	u, err := url.Parse(build.Link)
+	if err != nil {
+		return "", err
+	}
+
+	downloader, err := s.fs.Get(u.Scheme)
+	if err != nil {
+		return "", err
+	}
+
+	rc, err := downloader.Download(ctx, build.Link)
+	if err != nil {
+		return "", err
+	}
 	defer rc.Close()
 
-	f, err := ioutil.TempFile(s.root, "labapp")
+	f, err := ioutil.TempFile(dir, "labapp")
 	if err != nil {
-		return err
+		return "", err
+	}
+	defer func() {
+		f.Close()
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+
+	var w io.Writer = f
+	var verifier digest.Verifier
+	if build.Digest != "" {
+		verifier = build.Digest.Verifier()
+		w = io.MultiWriter(f, verifier)
 	}
-	defer os.Remove(f.Name())
-	defer f.Close()
 
-	_, err = io.Copy(f, rc)
+	n, err := io.Copy(w, rc)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	err = os.Chmod(f.Name(), 0775)
+	if verifier != nil {
+		if n != build.Size {
+			return "", errors.Errorf("downloaded %d bytes from %q but expected %d", n, build.Link, build.Size)
+		}
+
+		if !verifier.Verified() {
+			return "", errors.Errorf("binary downloaded from %q does not match digest %q", build.Link, build.Digest)
+		}
+	}
+
+	err = f.Sync()
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	// Atomically replace the binary.
-	binaryPath := filepath.Join(s.root, "labapp")
-	err = os.Rename(f.Name(), binaryPath)
+	err = os.Chmod(f.Name(), 0775)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	return f.Name(), nil
 }
 
-func (s *supervisor) cmd(ctx context.Context, args ...string) *exec.Cmd {
-	return s.cmdWithStdio(ctx, os.Stdout, os.Stderr, args...)
+// cmd returns a labapp command that writes its output to the labagent's
+// stdio and to a log file.
+func (s *supervisor) cmd(ctx context.Context, logFile io.Writer, args ...string) *exec.Cmd {
+	return s.cmdWithStdio(ctx, io.MultiWriter(os.Stdout, logFile), io.MultiWriter(os.Stderr, logFile), args...)
 }
 
 func (s *supervisor) cmdWithStdio(ctx context.Context, stdout, stderr io.Writer, args ...string) *exec.Cmd {
@@ -19,12 +19,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/httputil"
 	"github.com/Netflix/p2plab/pkg/logutil"
 	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/rs/xid"
 )
 
 type api struct {
@@ -79,6 +81,176 @@ func (a *api) Report(ctx context.Context) (metadata.ReportNode, error) {
 	return report, nil
 }
 
+func (a *api) StartTask(ctx context.Context, task metadata.Task) (metadata.TaskExecution, error) {
+	var exec metadata.TaskExecution
+	content, err := json.MarshalIndent(&task, "", "    ")
+	if err != nil {
+		return exec, err
+	}
+
+	// The ID is chosen before sending, so a retried request refers to the same
+	// task.
+	req := a.client.NewRequest("POST", a.url("/tasks")).
+		Option("id", xid.New().String()).
+		Body(bytes.NewReader(content))
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return exec, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&exec)
+	if err != nil {
+		return exec, err
+	}
+
+	return exec, nil
+}
+
+func (a *api) GetTask(ctx context.Context, id string) (metadata.TaskExecution, error) {
+	var exec metadata.TaskExecution
+
+	req := a.client.NewRequest("GET", a.url("/tasks/%s", id))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return exec, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&exec)
+	if err != nil {
+		return exec, err
+	}
+
+	return exec, nil
+}
+
+func (a *api) ListTasks(ctx context.Context) ([]metadata.TaskExecution, error) {
+	var execs []metadata.TaskExecution
+
+	req := a.client.NewRequest("GET", a.url("/tasks"))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&execs)
+	if err != nil {
+		return nil, err
+	}
+
+	return execs, nil
+}
+
+func (a *api) CancelTask(ctx context.Context, id string) error {
+	req := a.client.NewRequest("POST", a.url("/tasks/%s/cancel", id))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+func (a *api) DeleteTask(ctx context.Context, id string) error {
+	req := a.client.NewRequest("DELETE", a.url("/tasks/%s", id))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+func (a *api) PeerState(ctx context.Context) (metadata.PeerState, error) {
+	var state metadata.PeerState
+
+	req := a.client.NewRequest("GET", a.url("/debug/state"))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return state, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&state)
+	if err != nil {
+		return state, err
+	}
+
+	return state, nil
+}
+
+func (a *api) StartProfiling(ctx context.Context) error {
+	req := a.client.NewRequest("POST", a.url("/profiles/start"))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+func (a *api) StopProfiling(ctx context.Context) error {
+	req := a.client.NewRequest("POST", a.url("/profiles/stop"))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+func (a *api) Profile(ctx context.Context, kind string, w io.Writer) error {
+	req := a.client.NewRequest("GET", a.url("/profiles/%s", kind))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func (a *api) StartSampling(ctx context.Context) error {
+	req := a.client.NewRequest("POST", a.url("/sampling/start"))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+func (a *api) StopSampling(ctx context.Context) error {
+	req := a.client.NewRequest("POST", a.url("/sampling/stop"))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+func (a *api) Reset(ctx context.Context) error {
+	req := a.client.NewRequest("POST", a.url("/reset"))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
 func (a *api) Run(ctx context.Context, task metadata.Task) error {
 	content, err := json.MarshalIndent(&task, "", "    ")
 	if err != nil {
@@ -0,0 +1,61 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package approuter
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Netflix/p2plab/daemon"
+)
+
+func (s *router) getDebugState(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	state, err := s.peer.State(ctx)
+	if err != nil {
+		return err
+	}
+	return daemon.WriteJSON(w, &state)
+}
+
+func (s *router) getDebugWantlist(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	wantlist := s.peer.Wantlist()
+	return daemon.WriteJSON(w, &wantlist)
+}
+
+func (s *router) getDebugLedgers(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	ledgers := s.peer.Ledgers()
+	return daemon.WriteJSON(w, &ledgers)
+}
+
+func (s *router) getDebugPeers(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	peers := s.peer.ConnectedPeers()
+	return daemon.WriteJSON(w, &peers)
+}
+
+func (s *router) getDebugBlockstore(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	stat, err := s.peer.BlockstoreStat(ctx)
+	if err != nil {
+		return err
+	}
+	return daemon.WriteJSON(w, &stat)
+}
+
+func (s *router) getDebugProvider(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	stat, err := s.peer.ProviderStat()
+	if err != nil {
+		return err
+	}
+	return daemon.WriteJSON(w, &stat)
+}
@@ -0,0 +1,137 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package approuter
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+// profiler captures a CPU profile between start and stop, and snapshots the
+// other profiles when stopped so they can be retrieved afterwards. Starting
+// again before stopping discards the profile in progress, as it was abandoned
+// by a benchmark that failed.
+type profiler struct {
+	mu       sync.Mutex
+	cpu      *bytes.Buffer
+	profiles map[string][]byte
+}
+
+func (p *profiler) Start(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cpu != nil {
+		zerolog.Ctx(ctx).Warn().Msg("Discarding abandoned profile")
+		pprof.StopCPUProfile()
+		p.cpu = nil
+	}
+
+	cpu := new(bytes.Buffer)
+	err := pprof.StartCPUProfile(cpu)
+	if err != nil {
+		return errors.Wrap(err, "failed to start cpu profile")
+	}
+
+	runtime.SetBlockProfileRate(1)
+	runtime.SetMutexProfileFraction(1)
+	p.cpu = cpu
+	p.profiles = nil
+	return nil
+}
+
+func (p *profiler) Stop() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cpu == nil {
+		return errors.Wrap(errdefs.ErrInvalidArgument, "profiling not started")
+	}
+
+	pprof.StopCPUProfile()
+	profiles := map[string][]byte{
+		p2plab.ProfileCPU: p.cpu.Bytes(),
+	}
+	p.cpu = nil
+
+	for _, kind := range p2plab.ProfileKinds {
+		if kind == p2plab.ProfileCPU {
+			continue
+		}
+
+		buf := new(bytes.Buffer)
+		err := pprof.Lookup(kind).WriteTo(buf, 0)
+		if err != nil {
+			return errors.Wrapf(err, "failed to write %s profile", kind)
+		}
+		profiles[kind] = buf.Bytes()
+	}
+
+	runtime.SetBlockProfileRate(0)
+	runtime.SetMutexProfileFraction(0)
+	p.profiles = profiles
+	return nil
+}
+
+func (p *profiler) Get(kind string) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	profile, ok := p.profiles[kind]
+	if !ok {
+		return nil, errors.Wrapf(errdefs.ErrNotFound, "%s profile", kind)
+	}
+	return profile, nil
+}
+
+func (s *router) postProfilesStart(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	err := s.profiler.Start(ctx)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Msg("Started profiling")
+	return nil
+}
+
+func (s *router) postProfilesStop(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	err := s.profiler.Stop()
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Msg("Stopped profiling")
+	return nil
+}
+
+func (s *router) getProfile(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	profile, err := s.profiler.Get(vars["kind"])
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	_, err = w.Write(profile)
+	return err
+}
@@ -17,28 +17,53 @@ package approuter
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/daemon"
 	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/peer"
 	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/Netflix/p2plab/pkg/randutil"
 	"github.com/Netflix/p2plab/pkg/traceutil"
+	"github.com/Netflix/p2plab/progress"
+	humanize "github.com/dustin/go-humanize"
 	cid "github.com/ipfs/go-cid"
+	files "github.com/ipfs/go-ipfs-files"
 	libp2ppeer "github.com/libp2p/go-libp2p-core/peer"
 	multiaddr "github.com/multiformats/go-multiaddr"
+	digest "github.com/opencontainers/go-digest"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
+var (
+	// DefaultFindProvidersCount is the number of providers to find when the
+	// findprovs task does not specify a count.
+	DefaultFindProvidersCount int64 = 20
+)
+
 type router struct {
-	peer *peer.Peer
+	peer     *peer.Peer
+	tasks    *taskSet
+	profiler *profiler
 }
 
 func New(p *peer.Peer) daemon.Router {
-	return &router{p}
+	return &router{
+		peer:     p,
+		tasks:    newTaskSet(),
+		profiler: &profiler{},
+	}
 }
 
 func (s *router) Routes() []daemon.Route {
@@ -46,8 +71,26 @@ func (s *router) Routes() []daemon.Route {
 		// GET
 		daemon.NewGetRoute("/peerInfo", s.getPeerInfo),
 		daemon.NewGetRoute("/report", s.getReport),
+		daemon.NewGetRoute("/tasks", s.getTasks),
+		daemon.NewGetRoute("/tasks/{id}", s.getTask),
+		daemon.NewGetRoute("/debug/state", s.getDebugState),
+		daemon.NewGetRoute("/debug/wantlist", s.getDebugWantlist),
+		daemon.NewGetRoute("/debug/ledgers", s.getDebugLedgers),
+		daemon.NewGetRoute("/debug/peers", s.getDebugPeers),
+		daemon.NewGetRoute("/debug/blockstore", s.getDebugBlockstore),
+		daemon.NewGetRoute("/debug/provider", s.getDebugProvider),
+		daemon.NewGetRoute("/profiles/{kind}", s.getProfile),
 		// POST
 		daemon.NewPostRoute("/run", s.postRunTask),
+		daemon.NewPostRoute("/tasks", s.postTasks),
+		daemon.NewPostRoute("/tasks/{id}/cancel", s.postTaskCancel),
+		daemon.NewPostRoute("/reset", s.postReset),
+		daemon.NewPostRoute("/profiles/start", s.postProfilesStart),
+		daemon.NewPostRoute("/profiles/stop", s.postProfilesStop),
+		daemon.NewPostRoute("/sampling/start", s.postSamplingStart),
+		daemon.NewPostRoute("/sampling/stop", s.postSamplingStop),
+		// DELETE
+		daemon.NewDeleteRoute("/tasks/{id}", s.deleteTask),
 	}
 }
 
@@ -67,6 +110,69 @@ func (s *router) getReport(ctx context.Context, w http.ResponseWriter, r *http.R
 	return daemon.WriteJSON(w, &report)
 }
 
+func (s *router) postReset(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.postReset")
+	defer span.Finish()
+
+	// Running tasks would keep fetching blocks into the reset peer, and keep
+	// their wants in the bitswap wantlist.
+	err := s.tasks.CancelAll(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to cancel tasks")
+	}
+	s.tasks.Prune()
+
+	err = s.peer.Reset(ctx)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Debug().Msg("Reset peer")
+	return nil
+}
+
+func (s *router) postSamplingStart(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	s.peer.StartSampling()
+	zerolog.Ctx(ctx).Debug().Msg("Started sampling")
+	return nil
+}
+
+func (s *router) postSamplingStop(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	s.peer.StopSampling()
+	zerolog.Ctx(ctx).Debug().Msg("Stopped sampling")
+	return nil
+}
+
+// reportProgress streams the progress of a task relative to when it started,
+// until the returned function is called.
+func (s *router) reportProgress(ctx context.Context) func() {
+	start, err := s.peer.Progress()
+	if err != nil {
+		zerolog.Ctx(ctx).Debug().Err(err).Msg("Failed to get task progress")
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		progress.Report(ctx, progress.TaskInterval, func() (metadata.TaskProgress, error) {
+			cur, err := s.peer.Progress()
+			if err != nil {
+				return cur, err
+			}
+			return progressSince(start, cur), nil
+		})
+	}()
+
+	// Wait for the reporter to exit so that nothing is written to the response
+	// after the handler returns.
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
 func (s *router) postRunTask(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	var task metadata.Task
 	err := json.NewDecoder(r.Body).Decode(&task)
@@ -79,9 +185,37 @@ func (s *router) postRunTask(ctx context.Context, w http.ResponseWriter, r *http
 		return c.Str("task", string(task.Type)).Str("subject", task.Subject)
 	})
 
+	stop := s.reportProgress(ctx)
+	defer stop()
+
+	return s.runTask(ctx, task)
+}
+
+func (s *router) runTask(ctx context.Context, task metadata.Task) error {
+	var err error
 	switch task.Type {
 	case metadata.TaskGet:
 		err = s.getFile(ctx, task.Subject)
+	case metadata.TaskGetPath:
+		err = s.getPath(ctx, task.Subject)
+	case metadata.TaskGetRange:
+		err = s.getRange(ctx, task.Subject)
+	case metadata.TaskGetDepth:
+		err = s.getDepth(ctx, task.Subject)
+	case metadata.TaskRead:
+		err = s.read(ctx, task.Subject)
+	case metadata.TaskAdd:
+		err = s.add(ctx, task.Subject)
+	case metadata.TaskPin:
+		err = s.pin(ctx, task.Subject)
+	case metadata.TaskUnpin:
+		err = s.unpin(ctx, task.Subject)
+	case metadata.TaskGC:
+		err = s.gc(ctx)
+	case metadata.TaskProvide:
+		err = s.provide(ctx, task.Subject)
+	case metadata.TaskFindProvs:
+		err = s.findProviders(ctx, task.Subject)
 	case metadata.TaskConnect:
 		addrs := strings.Split(task.Subject, ",")
 		err = s.connect(ctx, addrs)
@@ -104,22 +238,354 @@ func (s *router) postRunTask(ctx context.Context, w http.ResponseWriter, r *http
 func (s *router) getFile(ctx context.Context, target string) error {
 	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.getFile")
 	defer span.Finish()
-	span.SetTag("cid", target)
+	span.SetTag("subject", target)
+
+	c, path, _, err := parseSubject(target)
+	if err != nil {
+		return err
+	}
+
+	err = s.peer.FetchGraph(ctx, c, p2plab.WithPath(path))
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Debug().Str("cid", c.String()).Str("path", path).Msg("Retrieved file")
+	return nil
+}
+
+func (s *router) getPath(ctx context.Context, subject string) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.getPath")
+	defer span.Finish()
+	span.SetTag("subject", subject)
+
+	c, path, _, err := parseSubject(subject)
+	if err != nil {
+		return err
+	}
+
+	err = s.peer.FetchGraph(ctx, c, p2plab.WithPath(path))
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Debug().Str("cid", c.String()).Str("path", path).Msg("Retrieved path")
+	return nil
+}
+
+func (s *router) getRange(ctx context.Context, subject string) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.getRange")
+	defer span.Finish()
+	span.SetTag("subject", subject)
+
+	c, path, params, err := parseSubject(subject)
+	if err != nil {
+		return err
+	}
+
+	offset, err := parseInt64Param(params, "offset", 0)
+	if err != nil {
+		return err
+	}
+
+	length, err := parseInt64Param(params, "length", -1)
+	if err != nil {
+		return err
+	}
+	if length < 0 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "length must be provided in %q", subject)
+	}
+
+	err = s.peer.FetchRange(ctx, c, offset, length, p2plab.WithPath(path))
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Debug().Str("cid", c.String()).Str("path", path).Int64("offset", offset).Int64("length", length).Msg("Retrieved range")
+	return nil
+}
+
+func (s *router) getDepth(ctx context.Context, subject string) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.getDepth")
+	defer span.Finish()
+	span.SetTag("subject", subject)
+
+	c, path, params, err := parseSubject(subject)
+	if err != nil {
+		return err
+	}
+
+	depth, err := parseInt64Param(params, "depth", -1)
+	if err != nil {
+		return err
+	}
+	if depth < 0 {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "depth must be provided in %q", subject)
+	}
+
+	err = s.peer.FetchGraph(ctx, c, p2plab.WithPath(path), p2plab.WithDepth(int(depth)))
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Debug().Str("cid", c.String()).Str("path", path).Int64("depth", depth).Msg("Retrieved graph to depth")
+	return nil
+}
+
+func (s *router) read(ctx context.Context, subject string) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.read")
+	defer span.Finish()
+	span.SetTag("subject", subject)
 
-	c, err := cid.Parse(target)
+	c, params, err := s.resolveSubject(ctx, subject)
 	if err != nil {
-		return errors.Wrapf(errdefs.ErrInvalidArgument, "%s", err)
+		return err
 	}
 
-	err = s.peer.FetchGraph(ctx, c)
+	var (
+		verifier digest.Verifier
+		w        io.Writer = ioutil.Discard
+	)
+	if params.Get("digest") != "" {
+		dgst, err := digest.Parse(params.Get("digest"))
+		if err != nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid digest: %s", err)
+		}
+		verifier = dgst.Verifier()
+		w = verifier
+	}
+
+	start := time.Now()
+	n, err := s.peer.Read(ctx, c, w)
 	if err != nil {
 		return err
 	}
+	elapsed := time.Since(start)
+	span.SetTag("bytes", n)
 
-	zerolog.Ctx(ctx).Debug().Str("cid", c.String()).Msg("Retrieved file")
+	if verifier != nil && !verifier.Verified() {
+		return errors.Errorf("content of %q does not match digest %q", c, params.Get("digest"))
+	}
+
+	logger := zerolog.Ctx(ctx).Info().Str("cid", c.String()).Int64("bytes", n).Dur("elapsed", elapsed)
+	if elapsed > 0 {
+		logger = logger.Str("throughput", fmt.Sprintf("%s/s", humanize.Bytes(uint64(float64(n)/elapsed.Seconds()))))
+	}
+	logger.Bool("verified", verifier != nil).Msg("Read content")
 	return nil
 }
 
+func (s *router) add(ctx context.Context, subject string) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.add")
+	defer span.Finish()
+
+	var odef metadata.ObjectDefinition
+	err := json.Unmarshal([]byte(subject), &odef)
+	if err != nil {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid object definition: %s", err)
+	}
+	span.SetTag("type", odef.Type)
+	span.SetTag("source", odef.Source)
+
+	var n files.Node
+	switch metadata.ObjectType(odef.Type) {
+	case metadata.ObjectRandom:
+		size, seed, err := randutil.ParseSource(odef.Source)
+		if err != nil {
+			return err
+		}
+		n = files.NewReaderFile(randutil.NewReader(size, seed))
+	case metadata.ObjectDirectory:
+		stat, err := os.Lstat(odef.Source)
+		if err != nil {
+			return errors.Wrapf(err, "failed to stat %q", odef.Source)
+		}
+
+		n, err = files.NewSerialFile(odef.Source, odef.Hidden, stat)
+		if err != nil {
+			return errors.Wrapf(err, "failed to open %q", odef.Source)
+		}
+	default:
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "unsupported object type %q", odef.Type)
+	}
+	defer n.Close()
+
+	start := time.Now()
+	nd, err := s.peer.Add(ctx, n, p2plab.AddOptionsFromDefinition(odef)...)
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+
+	// Content added on a node is what it publishes, so it is pinned to survive
+	// garbage collection until it is unpinned.
+	err = s.peer.Pin(ctx, nd.Cid())
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Str("cid", nd.Cid().String()).Dur("elapsed", elapsed).Msg("Added content")
+	return nil
+}
+
+func (s *router) pin(ctx context.Context, target string) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.pin")
+	defer span.Finish()
+	span.SetTag("subject", target)
+
+	c, _, err := s.resolveSubject(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = s.peer.Pin(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Debug().Str("cid", c.String()).Dur("elapsed", time.Since(start)).Msg("Pinned")
+	return nil
+}
+
+func (s *router) unpin(ctx context.Context, target string) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.unpin")
+	defer span.Finish()
+	span.SetTag("subject", target)
+
+	c, _, err := s.resolveSubject(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	err = s.peer.Unpin(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Debug().Str("cid", c.String()).Msg("Unpinned")
+	return nil
+}
+
+func (s *router) gc(ctx context.Context) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.gc")
+	defer span.Finish()
+
+	start := time.Now()
+	removed, err := s.peer.GC(ctx)
+	if err != nil {
+		return err
+	}
+	span.SetTag("removed", removed)
+
+	zerolog.Ctx(ctx).Debug().Int("removed", removed).Dur("elapsed", time.Since(start)).Msg("Garbage collected blocks")
+	return nil
+}
+
+func (s *router) provide(ctx context.Context, target string) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.provide")
+	defer span.Finish()
+	span.SetTag("subject", target)
+
+	c, _, err := s.resolveSubject(ctx, target)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = s.peer.Provide(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Str("cid", c.String()).Dur("elapsed", time.Since(start)).Msg("Provided")
+	return nil
+}
+
+func (s *router) findProviders(ctx context.Context, subject string) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.findProviders")
+	defer span.Finish()
+	span.SetTag("subject", subject)
+
+	c, params, err := s.resolveSubject(ctx, subject)
+	if err != nil {
+		return err
+	}
+
+	count, err := parseInt64Param(params, "count", DefaultFindProvidersCount)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	infos, err := s.peer.FindProviders(ctx, c, int(count))
+	if err != nil {
+		return err
+	}
+	span.SetTag("providers", len(infos))
+
+	zerolog.Ctx(ctx).Info().Str("cid", c.String()).Int("providers", len(infos)).Dur("elapsed", time.Since(start)).Msg("Found providers")
+	return nil
+}
+
+// resolveSubject parses a subject of the form "<cid>[/<path>][?<params>]" and
+// resolves its path.
+func (s *router) resolveSubject(ctx context.Context, subject string) (cid.Cid, url.Values, error) {
+	c, path, params, err := parseSubject(subject)
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+
+	if path != "" {
+		c, err = s.peer.Resolve(ctx, c, path)
+		if err != nil {
+			return cid.Undef, nil, err
+		}
+	}
+
+	return c, params, nil
+}
+
+// parseSubject splits a subject of the form "<cid>[/<path>][?<params>]" into
+// its parts.
+func parseSubject(subject string) (cid.Cid, string, url.Values, error) {
+	var params url.Values
+	if i := strings.LastIndex(subject, "?"); i >= 0 {
+		var err error
+		params, err = url.ParseQuery(subject[i+1:])
+		if err != nil {
+			return cid.Undef, "", nil, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid params in %q: %s", subject, err)
+		}
+		subject = subject[:i]
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(subject, "/ipfs/"), "/", 2)
+	c, err := cid.Parse(parts[0])
+	if err != nil {
+		return cid.Undef, "", nil, errors.Wrapf(errdefs.ErrInvalidArgument, "%s", err)
+	}
+
+	var path string
+	if len(parts) == 2 {
+		path = parts[1]
+	}
+
+	return c, path, params, nil
+}
+
+func parseInt64Param(params url.Values, key string, defaultValue int64) (int64, error) {
+	v := params.Get(key)
+	if v == "" {
+		return defaultValue, nil
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(errdefs.ErrInvalidArgument, "%s %q", key, v)
+	}
+	return n, nil
+}
+
 func (s *router) connect(ctx context.Context, addrs []string) error {
 	span, ctx := traceutil.StartSpanFromContext(ctx, "approuter.connect")
 	defer span.Finish()
@@ -0,0 +1,332 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package approuter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab/daemon"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+	"github.com/rs/zerolog"
+)
+
+// asyncTask is a task running in the background of labapp.
+type asyncTask struct {
+	mu     sync.Mutex
+	exec   metadata.TaskExecution
+	start  metadata.TaskProgress
+	cancel context.CancelFunc
+
+	// done is closed once the task has finished.
+	done chan struct{}
+}
+
+func (t *asyncTask) finish(err error, canceled bool, progress metadata.TaskProgress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	defer close(t.done)
+
+	t.exec.End = time.Now()
+	t.exec.Progress = progressSince(t.start, progress)
+	switch {
+	case canceled:
+		t.exec.Status = metadata.TaskCanceled
+	case err != nil:
+		t.exec.Status = metadata.TaskError
+		t.exec.Error = err.Error()
+	default:
+		t.exec.Status = metadata.TaskDone
+	}
+}
+
+func (t *asyncTask) snapshot() metadata.TaskExecution {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.exec
+}
+
+var (
+	// MaxFinishedTasks is the number of finished tasks kept until they are
+	// deleted. Beyond it, the oldest finished tasks are dropped.
+	MaxFinishedTasks = 256
+
+	// FinishedTaskTTL is how long a finished task is kept if it is never
+	// deleted.
+	FinishedTaskTTL = 15 * time.Minute
+)
+
+// taskSet holds the asynchronous tasks that are running, or have finished but
+// have neither been deleted nor expired.
+type taskSet struct {
+	mu    sync.Mutex
+	tasks map[string]*asyncTask
+}
+
+func newTaskSet() *taskSet {
+	return &taskSet{tasks: make(map[string]*asyncTask)}
+}
+
+// Add adds a task unless a task with the same ID exists, in which case the
+// existing task is returned instead.
+func (ts *taskSet) Add(t *asyncTask) (*asyncTask, bool) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.evict()
+	if existing, ok := ts.tasks[t.exec.ID]; ok {
+		return existing, false
+	}
+
+	ts.tasks[t.exec.ID] = t
+	return t, true
+}
+
+// evict drops finished tasks older than FinishedTaskTTL, and the oldest
+// finished tasks beyond MaxFinishedTasks. It must be called with the lock
+// held.
+func (ts *taskSet) evict() {
+	var finished []metadata.TaskExecution
+	for id, t := range ts.tasks {
+		exec := t.snapshot()
+		if exec.Status == metadata.TaskRunning {
+			continue
+		}
+
+		if time.Since(exec.End) > FinishedTaskTTL {
+			delete(ts.tasks, id)
+			continue
+		}
+		finished = append(finished, exec)
+	}
+
+	if len(finished) <= MaxFinishedTasks {
+		return
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].End.Before(finished[j].End)
+	})
+	for _, exec := range finished[:len(finished)-MaxFinishedTasks] {
+		delete(ts.tasks, exec.ID)
+	}
+}
+
+// Remove removes a task.
+func (ts *taskSet) Remove(id string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	delete(ts.tasks, id)
+}
+
+func (ts *taskSet) Get(id string) (*asyncTask, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	t, ok := ts.tasks[id]
+	if !ok {
+		return nil, errors.Wrapf(errdefs.ErrNotFound, "task %q", id)
+	}
+	return t, nil
+}
+
+// List returns every task, ordered by when they started.
+func (ts *taskSet) List() []*asyncTask {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.evict()
+	var tasks []*asyncTask
+	for _, t := range ts.tasks {
+		tasks = append(tasks, t)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].exec.Start.Before(tasks[j].exec.Start)
+	})
+	return tasks
+}
+
+// CancelAll cancels every running task and waits for them to finish.
+func (ts *taskSet) CancelAll(ctx context.Context) error {
+	ts.mu.Lock()
+	var running []*asyncTask
+	for _, t := range ts.tasks {
+		if t.snapshot().Status == metadata.TaskRunning {
+			t.cancel()
+			running = append(running, t)
+		}
+	}
+	ts.mu.Unlock()
+
+	for _, t := range running {
+		select {
+		case <-t.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Prune removes every task that is no longer running.
+func (ts *taskSet) Prune() {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	for id, t := range ts.tasks {
+		if t.snapshot().Status != metadata.TaskRunning {
+			delete(ts.tasks, id)
+		}
+	}
+}
+
+func (s *router) postTasks(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	var task metadata.Task
+	err := json.NewDecoder(r.Body).Decode(&task)
+	if err != nil {
+		return err
+	}
+
+	// Clients choose the task's ID so that retrying a request whose response
+	// was lost does not start the task twice.
+	id := r.FormValue("id")
+	if id == "" {
+		id = xid.New().String()
+	}
+
+	start, err := s.peer.Progress()
+	if err != nil {
+		return err
+	}
+
+	logger := zerolog.Ctx(ctx).With().Str("id", id).Str("task", string(task.Type)).Str("subject", task.Subject).Logger()
+
+	// The task outlives the request, so it runs under a new context that only
+	// carries over the logger and trace.
+	tctx := logger.WithContext(context.Background())
+	tctx = opentracing.ContextWithSpan(tctx, opentracing.SpanFromContext(ctx))
+	tctx, cancel := context.WithCancel(tctx)
+
+	t := &asyncTask{
+		exec: metadata.TaskExecution{
+			ID:     id,
+			Task:   task,
+			Status: metadata.TaskRunning,
+			Start:  time.Now(),
+		},
+		start:  start,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	t, added := s.tasks.Add(t)
+	if !added {
+		cancel()
+		zerolog.Ctx(ctx).Debug().Str("id", id).Msg("Task already started")
+		exec := s.taskExecution(t)
+		return daemon.WriteJSON(w, &exec)
+	}
+
+	go func() {
+		defer cancel()
+
+		err := s.runTask(tctx, task)
+		if err != nil {
+			logger.Error().Err(err).Msg("Task failed")
+		}
+
+		progress, perr := s.peer.Progress()
+		if perr != nil {
+			progress = start
+		}
+		t.finish(err, tctx.Err() != nil, progress)
+	}()
+
+	exec := t.snapshot()
+	return daemon.WriteJSON(w, &exec)
+}
+
+func (s *router) getTasks(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	execs := []metadata.TaskExecution{}
+	for _, t := range s.tasks.List() {
+		execs = append(execs, s.taskExecution(t))
+	}
+
+	return daemon.WriteJSON(w, &execs)
+}
+
+func (s *router) getTask(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	t, err := s.tasks.Get(vars["id"])
+	if err != nil {
+		return err
+	}
+
+	exec := s.taskExecution(t)
+	return daemon.WriteJSON(w, &exec)
+}
+
+func (s *router) postTaskCancel(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	t, err := s.tasks.Get(vars["id"])
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Str("id", vars["id"]).Msg("Canceling task")
+	t.cancel()
+	return nil
+}
+
+func (s *router) deleteTask(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	t, err := s.tasks.Get(vars["id"])
+	if err != nil {
+		return err
+	}
+
+	// Deleting a running task cancels it, since nothing could collect its
+	// result anymore.
+	t.cancel()
+	s.tasks.Remove(vars["id"])
+	return nil
+}
+
+// taskExecution returns a snapshot of a task, with the progress made so far if
+// it is still running.
+func (s *router) taskExecution(t *asyncTask) metadata.TaskExecution {
+	exec := t.snapshot()
+	if exec.Status == metadata.TaskRunning {
+		progress, err := s.peer.Progress()
+		if err == nil {
+			exec.Progress = progressSince(t.start, progress)
+		}
+	}
+	return exec
+}
+
+// progressSince returns the progress made between two snapshots.
+func progressSince(start, cur metadata.TaskProgress) metadata.TaskProgress {
+	cur.BlocksReceived -= start.BlocksReceived
+	cur.DataReceived -= start.DataReceived
+	return cur
+}
@@ -17,6 +17,7 @@ package controlapi
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"strings"
 
 	"github.com/Netflix/p2plab"
@@ -47,6 +48,12 @@ func (a *benchmarkAPI) Create(ctx context.Context, cluster, scenario string, opt
 	if settings.NoReset {
 		req.Option("no-reset", "true")
 	}
+	if settings.NoUpdate {
+		req.Option("no-update", "true")
+	}
+	if settings.Profile {
+		req.Option("profile", "true")
+	}
 
 	resp, err := req.Send(ctx)
 	if err != nil {
@@ -203,3 +210,34 @@ func (b *benchmark) Report(ctx context.Context) (metadata.Report, error) {
 
 	return report, nil
 }
+
+func (b *benchmark) Artifacts(ctx context.Context) ([]string, error) {
+	req := b.client.NewRequest("GET", b.url("/benchmarks/%s/artifacts/json", b.metadata.ID))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list artifacts")
+	}
+	defer resp.Body.Close()
+
+	var names []string
+	err = json.NewDecoder(resp.Body).Decode(&names)
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+func (b *benchmark) Artifact(ctx context.Context, name string, w io.Writer) error {
+	req := b.client.NewRequest("GET", b.url("/benchmarks/%s/artifact", b.metadata.ID)).
+		Option("name", name)
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get artifact %q", name)
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
@@ -22,6 +22,7 @@ import (
 	"strings"
 
 	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/httputil"
 	"github.com/Netflix/p2plab/pkg/logutil"
@@ -49,28 +50,17 @@ func (a *clusterAPI) Create(ctx context.Context, name string, opts ...p2plab.Cre
 
 	var cdef metadata.ClusterDefinition
 	if settings.Definition != "" {
-		f, err := os.Open(settings.Definition)
+		cdef, err = readClusterDefinition(settings.Definition)
 		if err != nil {
 			return id, err
 		}
-		defer f.Close()
-
-		err = json.NewDecoder(f).Decode(&cdef)
-		if err != nil {
-			return id, err
-		}
-
-		for i, group := range cdef.Groups {
-			if group.Peer == nil {
-				cdef.Groups[i].Peer = &metadata.DefaultPeerDefinition
-			}
-		}
 	} else {
 		cdef.Groups = append(cdef.Groups, metadata.ClusterGroup{
-			Size:         settings.Size,
-			InstanceType: settings.InstanceType,
-			Region:       settings.Region,
-			Peer:         &metadata.DefaultPeerDefinition,
+			Size:             settings.Size,
+			InstanceType:     settings.InstanceType,
+			Region:           settings.Region,
+			PeersPerInstance: settings.PeersPerInstance,
+			Peer:             &metadata.DefaultPeerDefinition,
 		})
 	}
 
@@ -192,6 +182,60 @@ func (a *clusterAPI) List(ctx context.Context, opts ...p2plab.ListOption) ([]p2p
 type Event struct {
 }
 
+func (a *clusterAPI) Scale(ctx context.Context, name string, opts ...p2plab.ScaleClusterOption) (p2plab.Cluster, error) {
+	var settings p2plab.ScaleClusterSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var cdef metadata.ClusterDefinition
+	if settings.Definition != "" {
+		var err error
+		cdef, err = readClusterDefinition(settings.Definition)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		c, err := a.Get(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		cdef = c.Metadata().Definition
+		if settings.Group < 0 || settings.Group >= len(cdef.Groups) {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "cluster %q has no group %d", name, settings.Group)
+		}
+		cdef.Groups[settings.Group].Size = settings.Size
+	}
+
+	content, err := json.MarshalIndent(&cdef, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	req := a.client.NewRequest("PUT", a.url("/clusters/%s/scale", name), httputil.WithRetryMax(0)).
+		Body(bytes.NewReader(content))
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to scale cluster")
+	}
+	defer resp.Body.Close()
+
+	logWriter := logutil.LogWriter(ctx)
+	if logWriter != nil {
+		err = logutil.WriteRemoteLogs(ctx, resp.Body, logWriter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return a.Get(ctx, name)
+}
+
 func (a *clusterAPI) Remove(ctx context.Context, names ...string) error {
 	req := a.client.NewRequest("DELETE", a.url("/clusters/delete")).
 		Option("names", strings.Join(names, ","))
@@ -213,6 +257,62 @@ func (a *clusterAPI) Remove(ctx context.Context, names ...string) error {
 	return nil
 }
 
+func (a *clusterAPI) Repair(ctx context.Context, names []string, opts ...p2plab.RepairClusterOption) error {
+	var settings p2plab.RepairClusterSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return err
+		}
+	}
+
+	req := a.client.NewRequest("POST", a.url("/clusters/repair"), httputil.WithRetryMax(0))
+	if len(names) > 0 {
+		req.Option("names", strings.Join(names, ","))
+	}
+	if settings.Orphans {
+		req.Option("orphans", settings.Orphans)
+	}
+
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to repair clusters")
+	}
+	defer resp.Body.Close()
+
+	logWriter := logutil.LogWriter(ctx)
+	if logWriter != nil {
+		err = logutil.WriteRemoteLogs(ctx, resp.Body, logWriter)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readClusterDefinition(path string) (metadata.ClusterDefinition, error) {
+	var cdef metadata.ClusterDefinition
+	f, err := os.Open(path)
+	if err != nil {
+		return cdef, err
+	}
+	defer f.Close()
+
+	err = json.NewDecoder(f).Decode(&cdef)
+	if err != nil {
+		return cdef, err
+	}
+
+	for i, group := range cdef.Groups {
+		if group.Peer == nil {
+			cdef.Groups[i].Peer = &metadata.DefaultPeerDefinition
+		}
+	}
+
+	return cdef, nil
+}
+
 type cluster struct {
 	client   *httputil.Client
 	metadata metadata.Cluster
@@ -93,6 +93,9 @@ func (a *nodeAPI) List(ctx context.Context, cluster string, opts ...p2plab.ListO
 	if settings.Query != "" {
 		req.Option("query", settings.Query)
 	}
+	if settings.Healthcheck {
+		req.Option("healthcheck", "true")
+	}
 
 	resp, err := req.Send(ctx)
 	if err != nil {
@@ -114,6 +117,34 @@ func (a *nodeAPI) List(ctx context.Context, cluster string, opts ...p2plab.ListO
 	return ns, nil
 }
 
+func (a *nodeAPI) Tasks(ctx context.Context, cluster, id string) ([]metadata.TaskExecution, error) {
+	req := a.client.NewRequest("GET", a.url("/clusters/%s/nodes/%s/tasks/json", cluster, id))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var execs []metadata.TaskExecution
+	err = json.NewDecoder(resp.Body).Decode(&execs)
+	if err != nil {
+		return nil, err
+	}
+
+	return execs, nil
+}
+
+func (a *nodeAPI) CancelTask(ctx context.Context, cluster, id, task string) error {
+	req := a.client.NewRequest("POST", a.url("/clusters/%s/nodes/%s/tasks/%s/cancel", cluster, id, task))
+	resp, err := req.Send(ctx)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
 type node struct {
 	p2plab.AgentAPI
 	p2plab.AppAPI
@@ -122,12 +153,22 @@ type node struct {
 
 func NewNode(client *httputil.Client, m metadata.Node) p2plab.Node {
 	return &node{
-		AgentAPI: agentapi.New(client, fmt.Sprintf("http://%s:%d", m.Address, m.AgentPort)),
+		AgentAPI: agentapi.New(client, agentAddr(m)),
 		AppAPI:   appapi.New(client, fmt.Sprintf("http://%s:%d", m.Address, m.AppPort)),
 		metadata: m,
 	}
 }
 
+// agentAddr returns the address of the node's labagent, scoped to the node's
+// labapp instance when its labagent supervises more than one.
+func agentAddr(m metadata.Node) string {
+	addr := fmt.Sprintf("http://%s:%d", m.Address, m.AgentPort)
+	if m.Instance > 0 {
+		addr = fmt.Sprintf("%s/instances/%d", addr, m.Instance)
+	}
+	return addr
+}
+
 func (n *node) ID() string {
 	return n.metadata.ID
 }
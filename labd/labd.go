@@ -18,6 +18,7 @@ import (
 	"context"
 	"io"
 	"path/filepath"
+	"time"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/builder"
@@ -28,6 +29,7 @@ import (
 	"github.com/Netflix/p2plab/labd/routers/buildrouter"
 	"github.com/Netflix/p2plab/labd/routers/clusterrouter"
 	"github.com/Netflix/p2plab/labd/routers/experimentrouter"
+	"github.com/Netflix/p2plab/labd/routers/helpers"
 	"github.com/Netflix/p2plab/labd/routers/noderouter"
 	"github.com/Netflix/p2plab/labd/routers/scenariorouter"
 	"github.com/Netflix/p2plab/metadata"
@@ -41,10 +43,12 @@ import (
 )
 
 type Labd struct {
-	daemon  *daemon.Daemon
-	seeder  *peer.Peer
-	builder p2plab.Builder
-	closers []io.Closer
+	daemon            *daemon.Daemon
+	seeder            *peer.Peer
+	builder           p2plab.Builder
+	rhelper           *helpers.Helper
+	reconcileInterval time.Duration
+	closers           []io.Closer
 }
 
 func New(root, addr string, logger *zerolog.Logger, opts ...LabdOption) (*Labd, error) {
@@ -109,10 +113,10 @@ func New(root, addr string, logger *zerolog.Logger, opts ...LabdOption) (*Labd,
 
 	daemon, err := daemon.New("labd", addr, logger,
 		healthcheckrouter.New(),
-		clusterrouter.New(db, provider, client),
+		clusterrouter.New(db, provider, client, builder),
 		noderouter.New(db, client),
 		scenariorouter.New(db),
-		benchmarkrouter.New(db, client, ts, seeder, builder),
+		benchmarkrouter.New(filepath.Join(root, "artifacts"), db, client, ts, seeder, builder),
 		experimentrouter.New(db, provider, client, ts, seeder, builder),
 		buildrouter.New(db, uploader, fs),
 	)
@@ -122,10 +126,12 @@ func New(root, addr string, logger *zerolog.Logger, opts ...LabdOption) (*Labd,
 	closers = append(closers, daemon)
 
 	d := &Labd{
-		daemon:  daemon,
-		seeder:  seeder,
-		builder: builder,
-		closers: closers,
+		daemon:            daemon,
+		seeder:            seeder,
+		builder:           builder,
+		rhelper:           helpers.New(db, provider, client, builder),
+		reconcileInterval: settings.ReconcileInterval,
+		closers:           closers,
 	}
 
 	return d, nil
@@ -154,5 +160,36 @@ func (d *Labd) Serve(ctx context.Context) error {
 	}
 	zerolog.Ctx(ctx).Info().Strs("addrs", addrs).Msg("IPFS listening")
 
+	go d.reconcile(ctx)
+
 	return d.daemon.Serve(ctx)
 }
+
+// reconcile marks clusters interrupted by a previous labd, then periodically
+// compares clusters with the provider's node groups until ctx is done.
+func (d *Labd) reconcile(ctx context.Context) {
+	logger := zerolog.Ctx(ctx)
+	err := d.rhelper.Reconcile(ctx, true)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to reconcile clusters")
+	}
+
+	if d.reconcileInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(d.reconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err = d.rhelper.Reconcile(ctx, false)
+			if err != nil {
+				logger.Error().Err(err).Msg("failed to reconcile clusters")
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package benchmarkrouter
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Netflix/p2plab/daemon"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+// artifactsDir returns the directory holding the files gathered during a
+// benchmark, such as node logs.
+func (s *router) artifactsDir(id string) string {
+	return filepath.Join(s.root, id)
+}
+
+func (s *router) getBenchmarkArtifacts(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	id := vars["id"]
+	_, err := s.db.GetBenchmark(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	dir := s.artifactsDir(id)
+	names := []string{}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(name))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return daemon.WriteJSON(w, &names)
+}
+
+func (s *router) getBenchmarkArtifact(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	id := vars["id"]
+	name := filepath.Clean(filepath.FromSlash(r.FormValue("name")))
+	if name == "." || filepath.IsAbs(name) || strings.HasPrefix(name, "..") {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "invalid artifact name %q", r.FormValue("name"))
+	}
+
+	f, err := os.Open(filepath.Join(s.artifactsDir(id), name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.Wrapf(errdefs.ErrNotFound, "artifact %q of benchmark %q", name, id)
+		}
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
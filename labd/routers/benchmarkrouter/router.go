@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -43,6 +44,7 @@ import (
 )
 
 type router struct {
+	root    string
 	db      metadata.DB
 	client  *httputil.Client
 	ts      *transformers.Transformers
@@ -50,8 +52,8 @@ type router struct {
 	builder p2plab.Builder
 }
 
-func New(db metadata.DB, client *httputil.Client, ts *transformers.Transformers, seeder *peer.Peer, builder p2plab.Builder) daemon.Router {
-	return &router{db, client, ts, seeder, builder}
+func New(root string, db metadata.DB, client *httputil.Client, ts *transformers.Transformers, seeder *peer.Peer, builder p2plab.Builder) daemon.Router {
+	return &router{root, db, client, ts, seeder, builder}
 }
 
 func (s *router) Routes() []daemon.Route {
@@ -60,6 +62,8 @@ func (s *router) Routes() []daemon.Route {
 		daemon.NewGetRoute("/benchmarks/json", s.getBenchmarks),
 		daemon.NewGetRoute("/benchmarks/{id}/json", s.getBenchmarkById),
 		daemon.NewGetRoute("/benchmarks/{id}/report/json", s.getBenchmarkReportById),
+		daemon.NewGetRoute("/benchmarks/{id}/artifacts/json", s.getBenchmarkArtifacts),
+		daemon.NewGetRoute("/benchmarks/{id}/artifact", s.getBenchmarkArtifact),
 		// POST
 		daemon.NewPostRoute("/benchmarks/create", s.postBenchmarksCreate),
 		// PUT
@@ -108,6 +112,24 @@ func (s *router) postBenchmarksCreate(ctx context.Context, w http.ResponseWriter
 		}
 	}
 
+	noUpdate := false
+	if r.FormValue("no-update") != "" {
+		var err error
+		noUpdate, err = strconv.ParseBool(r.FormValue("no-update"))
+		if err != nil {
+			return err
+		}
+	}
+
+	profile := false
+	if r.FormValue("profile") != "" {
+		var err error
+		profile, err = strconv.ParseBool(r.FormValue("profile"))
+		if err != nil {
+			return err
+		}
+	}
+
 	sid := r.FormValue("scenario")
 	scenario, err := s.db.GetScenario(ctx, sid)
 	if err != nil {
@@ -120,7 +142,8 @@ func (s *router) postBenchmarksCreate(ctx context.Context, w http.ResponseWriter
 		return err
 	}
 
-	bid := fmt.Sprintf("%s-%s-%d", cid, sid, time.Now().UnixNano())
+	start := time.Now()
+	bid := fmt.Sprintf("%s-%s-%d", cid, sid, start.UnixNano())
 	w.Header().Add(controlapi.ResourceID, bid)
 
 	ctx, logger := logutil.WithResponseLogger(ctx, w)
@@ -142,16 +165,23 @@ func (s *router) postBenchmarksCreate(ctx context.Context, w http.ResponseWriter
 		ns = append(ns, node)
 	}
 
-	if !noReset {
-		err = nodes.Update(ctx, s.builder, ns)
+	switch {
+	case noReset:
+	case noUpdate:
+		err = nodes.Reset(ctx, ns)
 		if err != nil {
-			return errors.Wrap(err, "failed to update cluster")
+			return errors.Wrap(err, "failed to reset cluster")
 		}
 
 		err = nodes.Connect(ctx, ns)
 		if err != nil {
 			return errors.Wrap(err, "failed to connect cluster")
 		}
+	default:
+		err = nodes.Prepare(ctx, s.builder, ns)
+		if err != nil {
+			return errors.Wrap(err, "failed to prepare cluster")
+		}
 	}
 
 	zerolog.Ctx(ctx).Info().Msg("Creating scenario plan")
@@ -184,12 +214,32 @@ func (s *router) postBenchmarksCreate(ctx context.Context, w http.ResponseWriter
 		seederAddrs = append(seederAddrs, fmt.Sprintf("%s/p2p/%s", addr, s.seeder.Host().ID()))
 	}
 
+	var opts []scenarios.RunOption
+	profileDir := filepath.Join(s.artifactsDir(bid), "profiles")
+	if profile {
+		opts = append(opts, scenarios.WithProfiles(profileDir))
+	}
+
 	zerolog.Ctx(ctx).Info().Msg("Executing scenario plan")
-	execution, err := scenarios.Run(ctx, lset, plan, seederAddrs)
+	execution, err := scenarios.Run(ctx, lset, plan, seederAddrs, opts...)
 	if err != nil {
 		return errors.Wrap(err, "failed to run scenario plan")
 	}
 
+	if profile {
+		err = reports.MergeProfiles(profileDir, queries)
+		if err != nil {
+			zerolog.Ctx(ctx).Warn().Err(err).Msg("Failed to merge profiles")
+		}
+	}
+
+	// Logs are gathered on a best-effort basis, as they are not needed for
+	// the report.
+	err = nodes.CollectLogs(ctx, ns, filepath.Join(s.artifactsDir(bid), "logs"), start)
+	if err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Msg("Failed to collect logs")
+	}
+
 	report := metadata.Report{
 		Summary: metadata.ReportSummary{
 			TotalTime: execution.End.Sub(execution.Start),
@@ -256,6 +306,13 @@ func (s *router) deleteBenchmarks(ctx context.Context, w http.ResponseWriter, r
 		return err
 	}
 
+	for _, id := range ids {
+		err = os.RemoveAll(s.artifactsDir(id))
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -21,6 +21,7 @@ import (
 	"net/url"
 
 	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/builder"
 	"github.com/Netflix/p2plab/daemon"
 	"github.com/Netflix/p2plab/downloaders"
 	"github.com/Netflix/p2plab/metadata"
@@ -95,7 +96,7 @@ func (b *router) getBuildDownload(ctx context.Context, w http.ResponseWriter, r
 }
 
 func (b *router) postBuildsUpload(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
-	link, err := b.uploader.Upload(ctx, r.Body)
+	build, err := builder.Upload(ctx, b.uploader, r.Body)
 	if err != nil {
 		return err
 	}
@@ -104,11 +105,9 @@ func (b *router) postBuildsUpload(ctx context.Context, w http.ResponseWriter, r
 	if err != nil {
 		return err
 	}
+	build.ID = id.String()
 
-	build, err := b.db.CreateBuild(ctx, metadata.Build{
-		ID:   id.String(),
-		Link: link,
-	})
+	build, err = b.db.CreateBuild(ctx, build)
 	if err != nil {
 		return err
 	}
@@ -38,12 +38,12 @@ type router struct {
 }
 
 // New returns a new clutser router initialized with the router helpers
-func New(db metadata.DB, provider p2plab.NodeProvider, client *httputil.Client) daemon.Router {
+func New(db metadata.DB, provider p2plab.NodeProvider, client *httputil.Client, builder p2plab.Builder) daemon.Router {
 	return &router{
 		db,
 		provider,
 		client,
-		helpers.New(db, provider, client),
+		helpers.New(db, provider, client, builder),
 	}
 }
 
@@ -54,8 +54,10 @@ func (s *router) Routes() []daemon.Route {
 		daemon.NewGetRoute("/clusters/{name}/json", s.getCluster),
 		// POST
 		daemon.NewPostRoute("/clusters/create", s.postClustersCreate),
+		daemon.NewPostRoute("/clusters/repair", s.postClustersRepair),
 		// PUT
 		daemon.NewPutRoute("/clusters/label", s.putClustersLabel),
+		daemon.NewPutRoute("/clusters/{name}/scale", s.putClusterScale),
 		// DELETE
 		daemon.NewDeleteRoute("/clusters/delete", s.deleteClusters),
 	}
@@ -91,6 +93,37 @@ func (s *router) postClustersCreate(ctx context.Context, w http.ResponseWriter,
 	return err
 }
 
+func (s *router) postClustersRepair(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	names := stringutil.Coalesce(strings.Split(r.FormValue("names"), ","))
+	if len(names) == 0 {
+		cs, err := s.db.ListClusters(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range cs {
+			if c.Status != metadata.ClusterCreated {
+				names = append(names, c.ID)
+			}
+		}
+	}
+
+	ctx, _ = logutil.WithResponseLogger(ctx, w)
+
+	for _, name := range names {
+		err := s.rhelper.RepairCluster(ctx, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.FormValue("orphans") == "true" {
+		return s.rhelper.RepairOrphans(ctx)
+	}
+
+	return nil
+}
+
 func (s *router) putClustersLabel(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	names := strings.Split(r.FormValue("names"), ",")
 	addLabels := stringutil.Coalesce(strings.Split(r.FormValue("adds"), ","))
@@ -108,6 +141,19 @@ func (s *router) putClustersLabel(ctx context.Context, w http.ResponseWriter, r
 	return daemon.WriteJSON(w, &clusters)
 }
 
+func (s *router) putClusterScale(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	var cdef metadata.ClusterDefinition
+	err := json.NewDecoder(r.Body).Decode(&cdef)
+	if err != nil {
+		return err
+	}
+
+	ctx, _ = logutil.WithResponseLogger(ctx, w)
+
+	_, err = s.rhelper.ScaleCluster(ctx, vars["name"], cdef)
+	return err
+}
+
 func (s *router) deleteClusters(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	names := strings.Split(r.FormValue("names"), ",")
 
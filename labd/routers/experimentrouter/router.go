@@ -65,7 +65,7 @@ func New(db metadata.DB, provider p2plab.NodeProvider, client *httputil.Client,
 		ts,
 		seeder,
 		builder,
-		helpers.New(db, provider, client),
+		helpers.New(db, provider, client, builder),
 	}
 }
 
@@ -170,14 +170,9 @@ func (s *router) postExperimentsCreate(ctx context.Context, w http.ResponseWrite
 			}
 			zerolog.Ctx(ctx).Info().Int("trial", i).Strs("ids", ids).Msg("Created cluster for experiment")
 
-			err = nodes.Update(ctx, s.builder, ns)
+			err = nodes.Prepare(ctx, s.builder, ns)
 			if err != nil {
-				return errors.Wrap(err, "failed to update cluster")
-			}
-
-			err = nodes.Connect(ctx, ns)
-			if err != nil {
-				return errors.Wrap(err, "failed to connect cluster")
+				return errors.Wrap(err, "failed to prepare cluster")
 			}
 
 			plan, queries, err := scenarios.Plan(ctx, trial.Scenario, s.ts, s.seeder, lset)
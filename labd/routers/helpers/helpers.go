@@ -2,8 +2,10 @@ package helpers
 
 import (
 	"context"
+	"sort"
 
 	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/labd/controlapi"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/nodes"
@@ -11,6 +13,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	bolt "go.etcd.io/bbolt"
+	"golang.org/x/sync/errgroup"
 )
 
 // Helper abstracts commonly used functions to be shared by any router
@@ -18,11 +21,12 @@ type Helper struct {
 	db       metadata.DB
 	provider p2plab.NodeProvider
 	client   *httputil.Client
+	builder  p2plab.Builder
 }
 
 // New instantiates our helper type
-func New(db metadata.DB, provider p2plab.NodeProvider, client *httputil.Client) *Helper {
-	return &Helper{db, provider, client}
+func New(db metadata.DB, provider p2plab.NodeProvider, client *httputil.Client, builder p2plab.Builder) *Helper {
+	return &Helper{db, provider, client, builder}
 }
 
 // CreateCluster enables creating the nodes in a cluster, waiting for them to be healthy before returning
@@ -47,7 +51,7 @@ func (h *Helper) CreateCluster(ctx context.Context, cdef metadata.ClusterDefinit
 	zerolog.Ctx(ctx).Info().Str("cid", name).Msg("Creating node group")
 	ng, err := h.provider.CreateNodeGroup(ctx, name, cdef)
 	if err != nil {
-		return cluster, err
+		return cluster, h.failCluster(ctx, cluster, err)
 	}
 
 	var mns []metadata.Node
@@ -67,7 +71,7 @@ func (h *Helper) CreateCluster(ctx context.Context, cdef metadata.ClusterDefinit
 
 		return nil
 	}); err != nil {
-		return cluster, err
+		return cluster, h.failCluster(ctx, cluster, err)
 	}
 
 	var ns = make([]p2plab.Node, len(mns))
@@ -75,14 +79,327 @@ func (h *Helper) CreateCluster(ctx context.Context, cdef metadata.ClusterDefinit
 		ns[i] = controlapi.NewNode(h.client, n)
 	}
 
-	if err := nodes.WaitHealthy(ctx, ns); err != nil {
+	statuses, err := nodes.WaitHealthy(ctx, ns)
+	if err != nil {
+		return cluster, h.failCluster(ctx, cluster, err)
+	}
+
+	err = h.saveAgents(ctx, cluster.ID, mns, statuses)
+	if err != nil {
+		return cluster, h.failCluster(ctx, cluster, err)
+	}
+
+	cluster.Status = metadata.ClusterCreated
+	return h.db.UpdateCluster(ctx, cluster)
+}
+
+// ScaleCluster resizes a cluster to match the given definition. New nodes are
+// updated and connected to the rest of the cluster if its labapps are already
+// running, and released nodes are removed from the cluster.
+func (h *Helper) ScaleCluster(ctx context.Context, name string, cdef metadata.ClusterDefinition) (metadata.Cluster, error) {
+	logger := zerolog.Ctx(ctx).With().Str("name", name).Logger()
+	ctx = logger.WithContext(ctx)
+
+	cluster, err := h.db.GetCluster(ctx, name)
+	if err != nil {
+		return cluster, errors.Wrapf(err, "failed to get cluster %q", name)
+	}
+
+	if cluster.Status != metadata.ClusterCreated && cluster.Status != metadata.ClusterError {
+		return cluster, errors.Wrapf(errdefs.ErrUnavailable, "cluster %q is %s", name, cluster.Status)
+	}
+
+	err = validateScale(cluster.Definition, cdef)
+	if err != nil {
+		return cluster, err
+	}
+
+	mns, err := h.db.ListNodes(ctx, cluster.ID)
+	if err != nil {
+		return cluster, errors.Wrap(err, "failed to list nodes")
+	}
+
+	cluster.Status = metadata.ClusterScaling
+	cluster, err = h.db.UpdateCluster(ctx, cluster)
+	if err != nil {
+		return cluster, errors.Wrap(err, "failed to update cluster status to scaling")
+	}
+
+	logger.Info().Int("from", cluster.Definition.Size()).Int("to", cdef.Size()).Msg("Resizing node group")
+	ng, err := h.provider.ResizeNodeGroup(ctx, &p2plab.NodeGroup{
+		ID:         cluster.ID,
+		Definition: cluster.Definition,
+		Nodes:      mns,
+	}, cdef)
+	if err != nil {
+		return cluster, h.failCluster(ctx, cluster, errors.Wrap(err, "failed to resize node group"))
+	}
+
+	cluster, err = h.syncNodes(ctx, cluster, mns, ng)
+	if err != nil {
+		return cluster, h.failCluster(ctx, cluster, err)
+	}
+
+	logger.Info().Int("size", cdef.Size()).Msg("Scaled cluster")
+	return cluster, nil
+}
+
+// failCluster marks a cluster as errored after a failed operation, returning
+// the operation's error.
+func (h *Helper) failCluster(ctx context.Context, cluster metadata.Cluster, err error) error {
+	cluster.Status = metadata.ClusterError
+	_, uerr := h.db.UpdateCluster(ctx, cluster)
+	if uerr != nil {
+		zerolog.Ctx(ctx).Error().Err(uerr).Msg("failed to update cluster status to error")
+	}
+	return err
+}
+
+// syncNodes updates a cluster's metadata to match the node group provisioned
+// for it, given the cluster's current nodes.
+func (h *Helper) syncNodes(ctx context.Context, cluster metadata.Cluster, mns []metadata.Node, ng *p2plab.NodeGroup) (metadata.Cluster, error) {
+	cdef := ng.Definition
+	existing := make(map[string]struct{})
+	for _, n := range mns {
+		existing[n.ID] = struct{}{}
+	}
+
+	var added, kept []metadata.Node
+	for _, n := range ng.Nodes {
+		if _, ok := existing[n.ID]; ok {
+			kept = append(kept, n)
+			delete(existing, n.ID)
+		} else {
+			added = append(added, n)
+		}
+	}
+
+	var removed []string
+	for id := range existing {
+		removed = append(removed, id)
+	}
+
+	cluster.Status = metadata.ClusterConnecting
+	cluster.Labels = relabel(cluster.Labels, cluster.Definition.GenerateLabels(), append([]string{
+		cluster.ID,
+	}, cdef.GenerateLabels()...))
+	cluster.Definition = cdef
+	if err := h.db.Update(ctx, func(tx *bolt.Tx) error {
+		var err error
+		tctx := metadata.WithTransactionContext(ctx, tx)
+		cluster, err = h.db.UpdateCluster(tctx, cluster)
+		if err != nil {
+			return err
+		}
+
+		if len(removed) > 0 {
+			err = h.db.DeleteNodes(tctx, cluster.ID, removed...)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(added) > 0 {
+			added, err = h.db.CreateNodes(tctx, cluster.ID, added)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
 		return cluster, err
 	}
 
+	if len(added) > 0 {
+		err := h.joinCluster(ctx, cluster.ID, kept, added)
+		if err != nil {
+			return cluster, err
+		}
+	}
+
 	cluster.Status = metadata.ClusterCreated
 	return h.db.UpdateCluster(ctx, cluster)
 }
 
+// joinCluster waits for added nodes to be healthy, and if the kept nodes are
+// running labapp, updates the added nodes and connects them to the cluster.
+// Otherwise they are left to be updated with the rest of the cluster. The
+// statuses of the added nodes' agents are saved either way.
+func (h *Helper) joinCluster(ctx context.Context, clusterID string, kept, added []metadata.Node) error {
+	var (
+		keptNodes  = make([]p2plab.Node, len(kept))
+		addedNodes = make([]p2plab.Node, len(added))
+	)
+	for i, n := range kept {
+		keptNodes[i] = controlapi.NewNode(h.client, n)
+	}
+	for i, n := range added {
+		addedNodes[i] = controlapi.NewNode(h.client, n)
+	}
+
+	statuses, err := nodes.WaitHealthy(ctx, addedNodes)
+	if err != nil {
+		return err
+	}
+
+	err = h.saveAgents(ctx, clusterID, added, statuses)
+	if err != nil {
+		return err
+	}
+
+	running, err := appsRunning(ctx, keptNodes)
+	if err != nil {
+		return err
+	}
+
+	if !running {
+		zerolog.Ctx(ctx).Info().Msg("Cluster has not been updated, skipping update of new nodes")
+		return nil
+	}
+
+	err = nodes.Update(ctx, h.builder, addedNodes)
+	if err != nil {
+		return errors.Wrap(err, "failed to update new nodes")
+	}
+
+	statuses, err = agentStatuses(ctx, addedNodes)
+	if err != nil {
+		return err
+	}
+
+	err = h.saveAgents(ctx, clusterID, added, statuses)
+	if err != nil {
+		return err
+	}
+
+	return nodes.Connect(ctx, append(keptNodes, addedNodes...))
+}
+
+// saveAgents saves the statuses reported by the agents of the nodes, which
+// are index-aligned with mns.
+func (h *Helper) saveAgents(ctx context.Context, clusterID string, mns []metadata.Node, statuses []metadata.AgentStatus) error {
+	return h.db.Update(ctx, func(tx *bolt.Tx) error {
+		tctx := metadata.WithTransactionContext(ctx, tx)
+		for i := range mns {
+			mns[i].Agent = statuses[i]
+			n, err := h.db.UpdateNode(tctx, clusterID, mns[i])
+			if err != nil {
+				return errors.Wrapf(err, "failed to save agent status of node %q", mns[i].ID)
+			}
+			mns[i] = n
+		}
+		return nil
+	})
+}
+
+// appsRunning returns whether every node is running labapp. It returns false
+// if there are no nodes.
+func appsRunning(ctx context.Context, ns []p2plab.Node) (bool, error) {
+	if len(ns) == 0 {
+		return false, nil
+	}
+
+	statuses, err := agentStatuses(ctx, ns)
+	if err != nil {
+		return false, err
+	}
+
+	for _, status := range statuses {
+		if status.App.Status != metadata.AppRunning {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// agentStatuses returns the statuses of the nodes' agents, in the same order
+// as ns.
+func agentStatuses(ctx context.Context, ns []p2plab.Node) ([]metadata.AgentStatus, error) {
+	statuses := make([]metadata.AgentStatus, len(ns))
+	checks, gctx := errgroup.WithContext(ctx)
+	for i, n := range ns {
+		i, n := i, n
+		checks.Go(func() error {
+			status, err := n.Status(gctx)
+			if err != nil {
+				return err
+			}
+
+			statuses[i] = status
+			return nil
+		})
+	}
+
+	err := checks.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// validateScale checks that a cluster definition only changes the size of the
+// groups of the current definition, or adds or removes groups at its end.
+func validateScale(current, cdef metadata.ClusterDefinition) error {
+	size := cdef.Size()
+	if size < 1 || size > metadata.ClusterSizeMax {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "cluster size must be between 1 and %d", metadata.ClusterSizeMax)
+	}
+
+	for i, group := range cdef.Groups {
+		if group.Size < 0 {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "group %d has a negative size", i)
+		}
+
+		if group.Peer == nil {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "group %d has no peer definition", i)
+		}
+
+		if i >= len(current.Groups) {
+			continue
+		}
+
+		prev := current.Groups[i]
+		if group.Region != prev.Region || group.InstanceType != prev.InstanceType || peersPerInstance(group) != peersPerInstance(prev) {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "group %d can only change in size", i)
+		}
+	}
+
+	return nil
+}
+
+func peersPerInstance(group metadata.ClusterGroup) int {
+	if group.PeersPerInstance < 1 {
+		return 1
+	}
+	return group.PeersPerInstance
+}
+
+// relabel replaces the labels generated from a cluster's previous definition
+// with those generated from its new definition, keeping any other labels.
+func relabel(labels, prev, next []string) []string {
+	labelSet := make(map[string]struct{})
+	for _, l := range labels {
+		labelSet[l] = struct{}{}
+	}
+	for _, l := range prev {
+		delete(labelSet, l)
+	}
+	for _, l := range next {
+		labelSet[l] = struct{}{}
+	}
+
+	var relabeled []string
+	for l := range labelSet {
+		relabeled = append(relabeled, l)
+	}
+	sort.Strings(relabeled)
+
+	return relabeled
+}
+
 func (h *Helper) DeleteCluster(ctx context.Context, name string) error {
 	logger := zerolog.Ctx(ctx).With().Str("name", name).Logger()
 	ctx = logger.WithContext(ctx)
@@ -106,8 +423,9 @@ func (h *Helper) DeleteCluster(ctx context.Context, name string) error {
 	}
 
 	ng := &p2plab.NodeGroup{
-		ID:    cluster.ID,
-		Nodes: ns,
+		ID:         cluster.ID,
+		Definition: cluster.Definition,
+		Nodes:      ns,
 	}
 
 	logger.Info().Msg("Destroying node group")
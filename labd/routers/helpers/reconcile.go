@@ -0,0 +1,260 @@
+package helpers
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Reconcile compares clusters with the node groups the provider has. Clusters
+// whose nodes differ from the provider's are marked as degraded, and node
+// groups without a cluster are reported as orphans. On startup, clusters left
+// in a transitional status were interrupted by a restart of labd, so they are
+// marked as errored.
+func (h *Helper) Reconcile(ctx context.Context, startup bool) error {
+	logger := zerolog.Ctx(ctx)
+
+	ngs, err := h.provider.ListNodeGroups(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list node groups")
+	}
+
+	ngByID := make(map[string]*p2plab.NodeGroup)
+	for _, ng := range ngs {
+		ngByID[ng.ID] = ng
+	}
+
+	clusters, err := h.db.ListClusters(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list clusters")
+	}
+
+	for _, cluster := range clusters {
+		ng := ngByID[cluster.ID]
+		delete(ngByID, cluster.ID)
+
+		err = h.reconcileCluster(ctx, cluster, ng, startup)
+		if err != nil {
+			return errors.Wrapf(err, "failed to reconcile cluster %q", cluster.ID)
+		}
+	}
+
+	for id, ng := range ngByID {
+		logger.Warn().Str("name", id).Int("nodes", len(ng.Nodes)).Msg("Found orphaned node group, run `labctl cluster repair --orphans` to destroy it")
+	}
+
+	return nil
+}
+
+func (h *Helper) reconcileCluster(ctx context.Context, cluster metadata.Cluster, ng *p2plab.NodeGroup, startup bool) error {
+	logger := zerolog.Ctx(ctx).With().Str("name", cluster.ID).Logger()
+
+	var status metadata.ClusterStatus
+	switch cluster.Status {
+	case metadata.ClusterCreating, metadata.ClusterConnecting, metadata.ClusterScaling, metadata.ClusterRepairing, metadata.ClusterDestroying:
+		if !startup {
+			return nil
+		}
+
+		logger.Warn().Str("status", string(cluster.Status)).Msg("Cluster was interrupted, run `labctl cluster repair` to repair it")
+		status = metadata.ClusterError
+	case metadata.ClusterCreated, metadata.ClusterDegraded:
+		mns, err := h.db.ListNodes(ctx, cluster.ID)
+		if err != nil {
+			return err
+		}
+
+		if ng != nil && ng.Nodes == nil {
+			logger.Debug().Msg("Provider cannot list the cluster's nodes, skipping verification")
+		}
+
+		missing, orphaned := diffNodes(mns, ng)
+		status = metadata.ClusterCreated
+		if len(missing) > 0 || len(orphaned) > 0 {
+			logger.Warn().Strs("missing", missing).Strs("orphaned", orphaned).Msg("Cluster nodes differ from provider, run `labctl cluster repair` to repair it")
+			status = metadata.ClusterDegraded
+		}
+	default:
+		return nil
+	}
+
+	if status == cluster.Status {
+		return nil
+	}
+
+	// The cluster may have changed since it was listed, in which case it is
+	// reconciled again on the next pass.
+	return h.db.Update(ctx, func(tx *bolt.Tx) error {
+		tctx := metadata.WithTransactionContext(ctx, tx)
+		current, err := h.db.GetCluster(tctx, cluster.ID)
+		if err != nil {
+			return err
+		}
+
+		if current.Status != cluster.Status {
+			return nil
+		}
+
+		current.Status = status
+		_, err = h.db.UpdateCluster(tctx, current)
+		return err
+	})
+}
+
+// diffNodes returns the IDs of a cluster's nodes missing from its node group,
+// and of the node group's nodes missing from the cluster. Nothing is missing
+// from a node group whose nodes cannot be listed.
+func diffNodes(mns []metadata.Node, ng *p2plab.NodeGroup) (missing, orphaned []string) {
+	if ng != nil && ng.Nodes == nil {
+		return nil, nil
+	}
+
+	nodeSet := make(map[string]struct{})
+	for _, n := range mns {
+		nodeSet[n.ID] = struct{}{}
+	}
+
+	if ng != nil {
+		for _, n := range ng.Nodes {
+			if _, ok := nodeSet[n.ID]; ok {
+				delete(nodeSet, n.ID)
+			} else {
+				orphaned = append(orphaned, n.ID)
+			}
+		}
+	}
+
+	for id := range nodeSet {
+		missing = append(missing, id)
+	}
+	sort.Strings(missing)
+	sort.Strings(orphaned)
+
+	return missing, orphaned
+}
+
+// RepairCluster brings a cluster back to its definition. Clusters that were
+// being destroyed are deleted. Otherwise the provider's node group is resized
+// to the cluster's definition, or created again if the provider has none, and
+// the cluster's nodes are updated to match.
+func (h *Helper) RepairCluster(ctx context.Context, name string) error {
+	logger := zerolog.Ctx(ctx).With().Str("name", name).Logger()
+	ctx = logger.WithContext(ctx)
+
+	cluster, err := h.db.GetCluster(ctx, name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get cluster %q", name)
+	}
+
+	if cluster.Status == metadata.ClusterDestroying {
+		return h.DeleteCluster(ctx, name)
+	}
+
+	mns, err := h.db.ListNodes(ctx, cluster.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to list nodes")
+	}
+
+	ngs, err := h.provider.ListNodeGroups(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list node groups")
+	}
+
+	var current *p2plab.NodeGroup
+	for _, ng := range ngs {
+		if ng.ID == cluster.ID {
+			current = ng
+			break
+		}
+	}
+
+	if current != nil && current.Nodes == nil {
+		logger.Warn().Msg("Provider cannot list the cluster's nodes, so missing nodes are not repaired")
+	}
+
+	cluster.Status = metadata.ClusterRepairing
+	cluster, err = h.db.UpdateCluster(ctx, cluster)
+	if err != nil {
+		return errors.Wrap(err, "failed to update cluster status to repairing")
+	}
+
+	var ng *p2plab.NodeGroup
+	if current == nil {
+		logger.Info().Msg("Creating node group")
+		ng, err = h.provider.CreateNodeGroup(ctx, cluster.ID, cluster.Definition)
+	} else {
+		logger.Info().Msg("Resizing node group")
+		ng, err = h.provider.ResizeNodeGroup(ctx, &p2plab.NodeGroup{
+			ID:         cluster.ID,
+			Definition: cluster.Definition,
+			Nodes:      providerNodes(mns, current),
+		}, cluster.Definition)
+	}
+	if err != nil {
+		return h.failCluster(ctx, cluster, errors.Wrap(err, "failed to repair node group"))
+	}
+
+	cluster, err = h.syncNodes(ctx, cluster, mns, ng)
+	if err != nil {
+		return h.failCluster(ctx, cluster, err)
+	}
+
+	logger.Info().Msg("Repaired cluster")
+	return nil
+}
+
+// providerNodes returns the nodes of a node group, preferring the cluster's
+// metadata of nodes it knows about. If the node group's nodes cannot be
+// listed, the cluster's nodes are returned.
+func providerNodes(mns []metadata.Node, ng *p2plab.NodeGroup) []metadata.Node {
+	if ng.Nodes == nil {
+		return mns
+	}
+
+	nodeByID := make(map[string]metadata.Node)
+	for _, n := range mns {
+		nodeByID[n.ID] = n
+	}
+
+	var ns []metadata.Node
+	for _, n := range ng.Nodes {
+		if mn, ok := nodeByID[n.ID]; ok {
+			n = mn
+		}
+		ns = append(ns, n)
+	}
+
+	return ns
+}
+
+// RepairOrphans destroys the node groups of the provider that have no cluster.
+func (h *Helper) RepairOrphans(ctx context.Context) error {
+	ngs, err := h.provider.ListNodeGroups(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list node groups")
+	}
+
+	for _, ng := range ngs {
+		_, err := h.db.GetCluster(ctx, ng.ID)
+		if err == nil {
+			continue
+		} else if !errdefs.IsNotFound(err) {
+			return errors.Wrapf(err, "failed to get cluster %q", ng.ID)
+		}
+
+		logger := zerolog.Ctx(ctx).With().Str("name", ng.ID).Logger()
+		logger.Info().Msg("Destroying orphaned node group")
+		err = h.provider.DestroyNodeGroup(logger.WithContext(ctx), ng)
+		if err != nil {
+			return errors.Wrapf(err, "failed to destroy node group %q", ng.ID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package helpers
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider keeps node groups in memory without provisioning anything.
+type fakeProvider struct {
+	ngs       map[string]*p2plab.NodeGroup
+	destroyed []string
+}
+
+func (p *fakeProvider) CreateNodeGroup(ctx context.Context, id string, cdef metadata.ClusterDefinition) (*p2plab.NodeGroup, error) {
+	return nil, errors.Wrap(errdefs.ErrUnavailable, "out of capacity")
+}
+
+func (p *fakeProvider) ResizeNodeGroup(ctx context.Context, ng *p2plab.NodeGroup, cdef metadata.ClusterDefinition) (*p2plab.NodeGroup, error) {
+	ng = &p2plab.NodeGroup{ID: ng.ID, Definition: cdef, Nodes: ng.Nodes}
+	p.ngs[ng.ID] = ng
+	return ng, nil
+}
+
+func (p *fakeProvider) DestroyNodeGroup(ctx context.Context, ng *p2plab.NodeGroup) error {
+	p.destroyed = append(p.destroyed, ng.ID)
+	delete(p.ngs, ng.ID)
+	return nil
+}
+
+func (p *fakeProvider) ListNodeGroups(ctx context.Context) ([]*p2plab.NodeGroup, error) {
+	var ngs []*p2plab.NodeGroup
+	for _, ng := range p.ngs {
+		ngs = append(ngs, ng)
+	}
+	sort.Slice(ngs, func(i, j int) bool {
+		return ngs[i].ID < ngs[j].ID
+	})
+	return ngs, nil
+}
+
+var testDefinition = metadata.ClusterDefinition{
+	Groups: []metadata.ClusterGroup{
+		{Size: 2, InstanceType: "t2.micro", Region: "us-west-2"},
+	},
+}
+
+func newTestHelper(t *testing.T) (*Helper, *fakeProvider, func()) {
+	root, err := ioutil.TempDir("", "helpers-test")
+	require.NoError(t, err)
+
+	db, err := metadata.NewDB(filepath.Join(root, "db"))
+	require.NoError(t, err)
+
+	provider := &fakeProvider{ngs: make(map[string]*p2plab.NodeGroup)}
+	return New(db, provider, nil, nil), provider, func() {
+		db.Close()
+		os.RemoveAll(root)
+	}
+}
+
+// createTestCluster creates a cluster with nodes mns in the metadata and, if
+// hasGroup, a node group with nodes pns in the provider. The node group's nodes
+// are unknown when pns is nil.
+func createTestCluster(t *testing.T, h *Helper, provider *fakeProvider, status metadata.ClusterStatus, mns, pns []string, hasGroup bool) {
+	ctx := context.Background()
+	_, err := h.db.CreateCluster(ctx, metadata.Cluster{
+		ID:         "test",
+		Status:     status,
+		Definition: testDefinition,
+		Labels:     append([]string{"test"}, testDefinition.GenerateLabels()...),
+	})
+	require.NoError(t, err)
+
+	if len(mns) > 0 {
+		_, err = h.db.CreateNodes(ctx, "test", testNodes(mns))
+		require.NoError(t, err)
+	}
+
+	if hasGroup {
+		ng := &p2plab.NodeGroup{ID: "test", Definition: testDefinition}
+		if pns != nil {
+			ng.Nodes = testNodes(pns)
+		}
+		provider.ngs["test"] = ng
+	}
+}
+
+func testNodes(ids []string) []metadata.Node {
+	ns := []metadata.Node{}
+	for _, id := range ids {
+		ns = append(ns, metadata.Node{ID: id, Labels: []string{id}})
+	}
+	return ns
+}
+
+func TestDiffNodes(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		mns      []string
+		ng       *p2plab.NodeGroup
+		missing  []string
+		orphaned []string
+	}{
+		{"in sync", []string{"a", "b"}, &p2plab.NodeGroup{Nodes: testNodes([]string{"b", "a"})}, nil, nil},
+		{"missing", []string{"a", "b", "c"}, &p2plab.NodeGroup{Nodes: testNodes([]string{"b"})}, []string{"a", "c"}, nil},
+		{"orphaned", []string{"a"}, &p2plab.NodeGroup{Nodes: testNodes([]string{"a", "c", "b"})}, nil, []string{"b", "c"}},
+		{"missing and orphaned", []string{"a", "b"}, &p2plab.NodeGroup{Nodes: testNodes([]string{"b", "c"})}, []string{"a"}, []string{"c"}},
+		{"no node group", []string{"a", "b"}, nil, []string{"a", "b"}, nil},
+		{"empty node group", []string{"a"}, &p2plab.NodeGroup{Nodes: []metadata.Node{}}, []string{"a"}, nil},
+		{"unknown nodes", []string{"a", "b"}, &p2plab.NodeGroup{}, nil, nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			missing, orphaned := diffNodes(testNodes(test.mns), test.ng)
+			require.Equal(t, test.missing, missing)
+			require.Equal(t, test.orphaned, orphaned)
+		})
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		status   metadata.ClusterStatus
+		startup  bool
+		mns      []string
+		pns      []string
+		hasGroup bool
+		expected metadata.ClusterStatus
+	}{
+		{"in sync", metadata.ClusterCreated, false, []string{"a", "b"}, []string{"a", "b"}, true, metadata.ClusterCreated},
+		{"missing node", metadata.ClusterCreated, false, []string{"a", "b"}, []string{"a"}, true, metadata.ClusterDegraded},
+		{"orphaned node", metadata.ClusterCreated, false, []string{"a"}, []string{"a", "b"}, true, metadata.ClusterDegraded},
+		{"missing node group", metadata.ClusterCreated, false, []string{"a", "b"}, nil, false, metadata.ClusterDegraded},
+		{"unknown nodes", metadata.ClusterCreated, false, []string{"a", "b"}, nil, true, metadata.ClusterCreated},
+		{"recovered", metadata.ClusterDegraded, false, []string{"a", "b"}, []string{"a", "b"}, true, metadata.ClusterCreated},
+		{"creating", metadata.ClusterCreating, false, nil, nil, false, metadata.ClusterCreating},
+		{"stuck creating", metadata.ClusterCreating, true, nil, nil, false, metadata.ClusterError},
+		{"stuck scaling", metadata.ClusterScaling, true, []string{"a"}, []string{"a", "b"}, true, metadata.ClusterError},
+		{"stuck destroying", metadata.ClusterDestroying, true, []string{"a", "b"}, []string{"a", "b"}, true, metadata.ClusterError},
+		{"errored", metadata.ClusterError, true, []string{"a"}, nil, false, metadata.ClusterError},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			h, provider, cleanup := newTestHelper(t)
+			defer cleanup()
+
+			createTestCluster(t, h, provider, test.status, test.mns, test.pns, test.hasGroup)
+			provider.ngs["orphan"] = &p2plab.NodeGroup{ID: "orphan"}
+
+			ctx := context.Background()
+			require.NoError(t, h.Reconcile(ctx, test.startup))
+
+			cluster, err := h.db.GetCluster(ctx, "test")
+			require.NoError(t, err)
+			require.Equal(t, test.expected, cluster.Status)
+
+			// Orphaned node groups are only reported.
+			require.Empty(t, provider.destroyed)
+		})
+	}
+}
+
+func TestRepairCluster(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("missing node", func(t *testing.T) {
+		h, provider, cleanup := newTestHelper(t)
+		defer cleanup()
+
+		createTestCluster(t, h, provider, metadata.ClusterDegraded, []string{"a", "b"}, []string{"a"}, true)
+		require.NoError(t, h.RepairCluster(ctx, "test"))
+
+		cluster, err := h.db.GetCluster(ctx, "test")
+		require.NoError(t, err)
+		require.Equal(t, metadata.ClusterCreated, cluster.Status)
+
+		mns, err := h.db.ListNodes(ctx, "test")
+		require.NoError(t, err)
+		require.Len(t, mns, 1)
+		require.Equal(t, "a", mns[0].ID)
+	})
+
+	t.Run("stuck destroying", func(t *testing.T) {
+		h, provider, cleanup := newTestHelper(t)
+		defer cleanup()
+
+		createTestCluster(t, h, provider, metadata.ClusterDestroying, []string{"a"}, []string{"a"}, true)
+		require.NoError(t, h.RepairCluster(ctx, "test"))
+
+		_, err := h.db.GetCluster(ctx, "test")
+		require.True(t, errdefs.IsNotFound(err))
+		require.Equal(t, []string{"test"}, provider.destroyed)
+	})
+
+	t.Run("failed to recreate", func(t *testing.T) {
+		h, provider, cleanup := newTestHelper(t)
+		defer cleanup()
+
+		createTestCluster(t, h, provider, metadata.ClusterCreating, nil, nil, false)
+		err := h.RepairCluster(ctx, "test")
+		require.True(t, errdefs.IsUnavailable(err))
+
+		cluster, err := h.db.GetCluster(ctx, "test")
+		require.NoError(t, err)
+		require.Equal(t, metadata.ClusterError, cluster.Status)
+	})
+}
+
+func TestRepairOrphans(t *testing.T) {
+	h, provider, cleanup := newTestHelper(t)
+	defer cleanup()
+
+	createTestCluster(t, h, provider, metadata.ClusterCreated, []string{"a"}, []string{"a"}, true)
+	provider.ngs["orphan"] = &p2plab.NodeGroup{ID: "orphan"}
+
+	require.NoError(t, h.RepairOrphans(context.Background()))
+	require.Equal(t, []string{"orphan"}, provider.destroyed)
+}
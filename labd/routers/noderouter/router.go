@@ -22,11 +22,16 @@ import (
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/daemon"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/labd/controlapi"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/httputil"
 	"github.com/Netflix/p2plab/pkg/stringutil"
 	"github.com/Netflix/p2plab/query"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
 	bolt "go.etcd.io/bbolt"
+	"golang.org/x/sync/errgroup"
 )
 
 type router struct {
@@ -43,6 +48,9 @@ func (s *router) Routes() []daemon.Route {
 		// GET
 		daemon.NewGetRoute("/clusters/{name}/nodes/json", s.getNodes),
 		daemon.NewGetRoute("/clusters/{name}/nodes/{id}/json", s.getNodeById),
+		daemon.NewGetRoute("/clusters/{name}/nodes/{id}/tasks/json", s.getNodeTasks),
+		// POST
+		daemon.NewPostRoute("/clusters/{name}/nodes/{id}/tasks/{task}/cancel", s.postNodeTaskCancel),
 		// PUT
 		daemon.NewPutRoute("/clusters/{name}/nodes/label", s.putNodesLabel),
 		daemon.NewPutRoute("/clusters/{name}/nodes/update", s.putNodesUpdate),
@@ -56,9 +64,61 @@ func (s *router) getNodes(ctx context.Context, w http.ResponseWriter, r *http.Re
 		return err
 	}
 
+	if r.FormValue("healthcheck") == "true" {
+		matchedNodes, err = s.healthcheckNodes(ctx, clusterId, matchedNodes)
+		if err != nil {
+			return err
+		}
+	}
+
 	return daemon.WriteJSON(w, &matchedNodes)
 }
 
+// healthcheckNodes refreshes the status of nodes from their agents. Nodes
+// whose agent cannot be reached keep their last known status with an unknown
+// app status.
+func (s *router) healthcheckNodes(ctx context.Context, clusterId string, ns []metadata.Node) ([]metadata.Node, error) {
+	healthchecks, gctx := errgroup.WithContext(ctx)
+	for i := range ns {
+		i := i
+		healthchecks.Go(func() error {
+			status, err := controlapi.NewNode(s.client, ns[i]).Status(gctx)
+			if err != nil {
+				zerolog.Ctx(ctx).Debug().Err(err).Str("node", ns[i].ID).Msg("Failed to healthcheck node")
+				ns[i].Agent.App.Status = metadata.AppUnknown
+				return nil
+			}
+			ns[i].Agent = status
+			return nil
+		})
+	}
+
+	err := healthchecks.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	var updated []metadata.Node
+	err = s.db.Update(ctx, func(tx *bolt.Tx) error {
+		tctx := metadata.WithTransactionContext(ctx, tx)
+
+		for _, n := range ns {
+			n, err := s.db.UpdateNode(tctx, clusterId, n)
+			if err != nil {
+				return err
+			}
+			updated = append(updated, n)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
 func (s *router) getNodeById(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	clusterId, id := vars["name"], vars["id"]
 	node, err := s.db.GetNode(ctx, clusterId, id)
@@ -69,6 +129,32 @@ func (s *router) getNodeById(ctx context.Context, w http.ResponseWriter, r *http
 	return daemon.WriteJSON(w, &node)
 }
 
+func (s *router) getNodeTasks(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	clusterId, id := vars["name"], vars["id"]
+	node, err := s.db.GetNode(ctx, clusterId, id)
+	if err != nil {
+		return err
+	}
+
+	execs, err := controlapi.NewNode(s.client, node).ListTasks(ctx)
+	if err != nil {
+		return err
+	}
+
+	return daemon.WriteJSON(w, &execs)
+}
+
+func (s *router) postNodeTaskCancel(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	clusterId, id := vars["name"], vars["id"]
+	node, err := s.db.GetNode(ctx, clusterId, id)
+	if err != nil {
+		return err
+	}
+
+	zerolog.Ctx(ctx).Info().Str("node", id).Str("task", vars["task"]).Msg("Canceling task on node")
+	return controlapi.NewNode(s.client, node).CancelTask(ctx, vars["task"])
+}
+
 func (s *router) putNodesLabel(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	ids := strings.Split(r.FormValue("ids"), ",")
 	addLabels := stringutil.Coalesce(strings.Split(r.FormValue("adds"), ","))
@@ -120,8 +206,18 @@ func (s *router) putNodesUpdate(ctx context.Context, w http.ResponseWriter, r *h
 			if pdef.Routing != "" {
 				n.Peer.Routing = pdef.Routing
 			}
+			err := updateConnManager(&n.Peer, pdef)
+			if err != nil {
+				return errors.Wrapf(err, "node %q", n.ID)
+			}
+			if pdef.ConnManagerGracePeriod != "" {
+				n.Peer.ConnManagerGracePeriod = pdef.ConnManagerGracePeriod
+			}
+			if pdef.RestartPolicy != "" {
+				n.Peer.RestartPolicy = pdef.RestartPolicy
+			}
+			updateLimits(&n.Peer, pdef)
 
-			var err error
 			n, err = s.db.UpdateNode(tctx, clusterId, n)
 			if err != nil {
 				return err
@@ -138,6 +234,55 @@ func (s *router) putNodesUpdate(ctx context.Context, w http.ResponseWriter, r *h
 	return daemon.WriteJSON(w, &ns)
 }
 
+// updateConnManager copies the connection manager watermarks set in an update
+// to a node's peer definition. Either watermark may be updated alone, and a
+// high watermark of metadata.ClearLimit disables the connection manager.
+func updateConnManager(peer *metadata.PeerDefinition, pdef metadata.PeerDefinition) error {
+	switch {
+	case pdef.ConnManagerHigh == metadata.ClearLimit:
+		peer.ConnManagerLow = 0
+		peer.ConnManagerHigh = 0
+		return nil
+	case pdef.ConnManagerHigh > 0:
+		peer.ConnManagerHigh = pdef.ConnManagerHigh
+	}
+	if pdef.ConnManagerLow > 0 {
+		peer.ConnManagerLow = pdef.ConnManagerLow
+	}
+
+	if peer.ConnManagerLow > peer.ConnManagerHigh {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "connection manager low watermark %d must not exceed high watermark %d", peer.ConnManagerLow, peer.ConnManagerHigh)
+	}
+	return nil
+}
+
+// updateLimits copies the resource limits set in an update to a node's peer
+// definition. Limits set to metadata.ClearLimit or metadata.ClearMemoryLimit
+// are removed.
+func updateLimits(peer *metadata.PeerDefinition, pdef metadata.PeerDefinition) {
+	switch {
+	case pdef.CPUQuota == metadata.ClearLimit:
+		peer.CPUQuota = 0
+	case pdef.CPUQuota > 0:
+		peer.CPUQuota = pdef.CPUQuota
+	}
+
+	switch pdef.MemoryLimit {
+	case "":
+	case metadata.ClearMemoryLimit:
+		peer.MemoryLimit = ""
+	default:
+		peer.MemoryLimit = pdef.MemoryLimit
+	}
+
+	switch {
+	case pdef.IOWeight == metadata.ClearLimit:
+		peer.IOWeight = 0
+	case pdef.IOWeight > 0:
+		peer.IOWeight = pdef.IOWeight
+	}
+}
+
 func (s *router) matchNodes(ctx context.Context, clusterId, q string) ([]metadata.Node, error) {
 	ns, err := s.db.ListNodes(ctx, clusterId)
 	if err != nil {
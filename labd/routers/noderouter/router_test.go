@@ -0,0 +1,126 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package noderouter
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateLimits(t *testing.T) {
+	limited := metadata.PeerDefinition{
+		CPUQuota:    0.5,
+		MemoryLimit: "512MiB",
+		IOWeight:    200,
+	}
+
+	for _, tc := range []struct {
+		name     string
+		update   metadata.PeerDefinition
+		expected metadata.PeerDefinition
+	}{
+		{
+			"unset",
+			metadata.PeerDefinition{},
+			limited,
+		},
+		{
+			"set",
+			metadata.PeerDefinition{CPUQuota: 2, MemoryLimit: "1GiB", IOWeight: 500},
+			metadata.PeerDefinition{CPUQuota: 2, MemoryLimit: "1GiB", IOWeight: 500},
+		},
+		{
+			"clear",
+			metadata.PeerDefinition{CPUQuota: metadata.ClearLimit, MemoryLimit: metadata.ClearMemoryLimit, IOWeight: metadata.ClearLimit},
+			metadata.PeerDefinition{},
+		},
+		{
+			"clear memory only",
+			metadata.PeerDefinition{MemoryLimit: metadata.ClearMemoryLimit},
+			metadata.PeerDefinition{CPUQuota: 0.5, IOWeight: 200},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			peer := limited
+			updateLimits(&peer, tc.update)
+			require.Equal(t, tc.expected, peer)
+		})
+	}
+}
+
+func TestUpdateConnManager(t *testing.T) {
+	enabled := metadata.PeerDefinition{
+		ConnManagerLow:  100,
+		ConnManagerHigh: 400,
+	}
+
+	for _, tc := range []struct {
+		name     string
+		update   metadata.PeerDefinition
+		expected metadata.PeerDefinition
+		invalid  bool
+	}{
+		{
+			"unset",
+			metadata.PeerDefinition{},
+			enabled,
+			false,
+		},
+		{
+			"low only",
+			metadata.PeerDefinition{ConnManagerLow: 200},
+			metadata.PeerDefinition{ConnManagerLow: 200, ConnManagerHigh: 400},
+			false,
+		},
+		{
+			"high only",
+			metadata.PeerDefinition{ConnManagerHigh: 800},
+			metadata.PeerDefinition{ConnManagerLow: 100, ConnManagerHigh: 800},
+			false,
+		},
+		{
+			"both",
+			metadata.PeerDefinition{ConnManagerLow: 10, ConnManagerHigh: 20},
+			metadata.PeerDefinition{ConnManagerLow: 10, ConnManagerHigh: 20},
+			false,
+		},
+		{
+			"disable",
+			metadata.PeerDefinition{ConnManagerHigh: metadata.ClearLimit},
+			metadata.PeerDefinition{},
+			false,
+		},
+		{
+			"low above high",
+			metadata.PeerDefinition{ConnManagerLow: 500},
+			metadata.PeerDefinition{},
+			true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			peer := enabled
+			err := updateConnManager(&peer, tc.update)
+			if tc.invalid {
+				require.True(t, errdefs.IsInvalidArgument(err))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, peer)
+		})
+	}
+}
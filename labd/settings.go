@@ -15,6 +15,8 @@
 package labd
 
 import (
+	"time"
+
 	"github.com/Netflix/p2plab/downloaders"
 	"github.com/Netflix/p2plab/providers"
 	"github.com/Netflix/p2plab/uploaders"
@@ -29,6 +31,10 @@ type LabdSettings struct {
 	Uploader           string
 	UploaderSettings   uploaders.UploaderSettings
 	DownloaderSettings downloaders.DownloaderSettings
+
+	// ReconcileInterval is how often clusters are compared with the provider's
+	// node groups. Zero only reconciles on startup.
+	ReconcileInterval time.Duration
 }
 
 func WithLibp2pPort(port int) LabdOption {
@@ -72,3 +78,10 @@ func WithDownloaderSettings(settings downloaders.DownloaderSettings) LabdOption
 		return nil
 	}
 }
+
+func WithReconcileInterval(interval time.Duration) LabdOption {
+	return func(s *LabdSettings) error {
+		s.ReconcileInterval = interval
+		return nil
+	}
+}
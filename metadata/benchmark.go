@@ -20,6 +20,7 @@ import (
 
 	"github.com/Netflix/p2plab/errdefs"
 	cid "github.com/ipfs/go-cid"
+	digest "github.com/opencontainers/go-digest"
 	"github.com/pkg/errors"
 	bolt "go.etcd.io/bbolt"
 )
@@ -56,6 +57,10 @@ var (
 type ScenarioPlan struct {
 	Objects map[string]cid.Cid
 
+	// Digests are the digests of the content read by read tasks, keyed by
+	// their target of the form "<object>[/<path>]", used to verify them.
+	Digests map[string]digest.Digest
+
 	Seed ScenarioStage
 
 	Benchmark ScenarioStage
@@ -63,6 +68,53 @@ type ScenarioPlan struct {
 
 type ScenarioStage map[string]Task
 
+// TaskStatus is the current status of an asynchronous task.
+type TaskStatus string
+
+var (
+	TaskRunning TaskStatus = "running"
+
+	TaskDone TaskStatus = "done"
+
+	TaskError TaskStatus = "error"
+
+	TaskCanceled TaskStatus = "canceled"
+)
+
+// TaskExecution is a task executed asynchronously on a node.
+type TaskExecution struct {
+	ID string
+
+	Task Task
+
+	Status TaskStatus
+
+	// Error is the reason the task failed when its status is TaskError.
+	Error string `json:",omitempty"`
+
+	// Progress is the progress of the task since it started.
+	Progress TaskProgress
+
+	Start time.Time
+
+	// End is the zero time while the task is running.
+	End time.Time
+}
+
+// TaskProgress is a snapshot of the progress of a task running on a node.
+type TaskProgress struct {
+	BlocksReceived uint64
+
+	DataReceived uint64
+
+	// Wantlist is the number of blocks the node is still waiting for.
+	Wantlist int
+
+	// Providers is the number of connected peers that have sent blocks to the
+	// node.
+	Providers int
+}
+
 type Task struct {
 	Type TaskType
 
@@ -77,6 +129,47 @@ var (
 	TaskConnect    TaskType = "connect"
 	TaskConnectOne TaskType = "connect-one"
 	TaskDisconnect TaskType = "disconnect"
+
+	// TaskGetPath fetches the full DAG of a Unixfs path within an object. The
+	// subject is of the form "<cid>/<path>".
+	TaskGetPath TaskType = "get-path"
+
+	// TaskGetRange fetches only the blocks spanning a byte range of a Unixfs
+	// file. The subject is of the form "<cid>[/<path>]?offset=<n>&length=<n>".
+	TaskGetRange TaskType = "get-range"
+
+	// TaskGetDepth fetches a DAG up to a maximum depth of links. The subject is
+	// of the form "<cid>[/<path>]?depth=<n>".
+	TaskGetDepth TaskType = "get-depth"
+
+	// TaskRead streams the full Unixfs file tree to a sink. The subject is of
+	// the form "<cid>[/<path>][?digest=<digest>]", where the content is
+	// verified against the digest if given.
+	TaskRead TaskType = "read"
+
+	// TaskAdd adds content locally on the node. The subject is a JSON encoded
+	// ObjectDefinition of type "random" or "dir", where the source of a "dir"
+	// is a path on the node. The added content is pinned.
+	TaskAdd TaskType = "add"
+
+	// TaskPin fetches and pins a DAG. The subject is of the form
+	// "<cid>[/<path>]".
+	TaskPin TaskType = "pin"
+
+	// TaskUnpin unpins a DAG. The subject is of the form "<cid>[/<path>]".
+	TaskUnpin TaskType = "unpin"
+
+	// TaskGC removes every block that is not pinned. The subject is ignored.
+	TaskGC TaskType = "gc"
+
+	// TaskProvide announces the node as a provider of a cid to the content
+	// routing system. The subject is of the form "<cid>[/<path>]".
+	TaskProvide TaskType = "provide"
+
+	// TaskFindProvs finds providers of a cid from the content routing system,
+	// recording the lookup time in the node's report. The subject is of the
+	// form "<cid>[/<path>][?count=<n>]".
+	TaskFindProvs TaskType = "findprovs"
 )
 
 func (m *db) GetBenchmark(ctx context.Context, id string) (Benchmark, error) {
@@ -320,6 +413,22 @@ func readPlan(bkt *bolt.Bucket, plan *ScenarioPlan) error {
 		plan.Objects = objects
 	}
 
+	m, err = readMap(bkt, bucketKeyDigests)
+	if err != nil {
+		return err
+	}
+
+	if m != nil {
+		digests := make(map[string]digest.Digest)
+		for k, v := range m {
+			digests[k], err = digest.Parse(v)
+			if err != nil {
+				return err
+			}
+		}
+		plan.Digests = digests
+	}
+
 	plan.Seed, err = readTaskMap(bkt, bucketKeySeed)
 	if err != nil {
 		return nil
@@ -473,6 +582,16 @@ func writePlan(bkt *bolt.Bucket, plan *ScenarioPlan) error {
 		return err
 	}
 
+	m = make(map[string]string)
+	for k, v := range plan.Digests {
+		m[k] = v.String()
+	}
+
+	err = writeMap(bkt, bucketKeyDigests, m)
+	if err != nil {
+		return err
+	}
+
 	err = writeTaskMap(bkt, bucketKeySeed, plan.Seed)
 	if err != nil {
 		return err
@@ -27,34 +27,54 @@ var (
 	bucketKeyExperiments = []byte("experiments")
 
 	// Cluster buckets.
-	bucketKeySize         = []byte("size")
-	bucketKeyInstanceType = []byte("instanceType")
-	bucketKeyRegion       = []byte("region")
+	bucketKeySize             = []byte("size")
+	bucketKeyInstanceType     = []byte("instanceType")
+	bucketKeyRegion           = []byte("region")
+	bucketKeyPeersPerInstance = []byte("peersPerInstance")
 
 	// Scenario buckets.
-	bucketKeyObjects   = []byte("objects")
-	bucketKeySeed      = []byte("seed")
-	bucketKeyBenchmark = []byte("benchmark")
-	bucketKeyType      = []byte("type")
-	bucketKeySource    = []byte("source")
-	bucketKeyLayout    = []byte("layout")
-	bucketKeyChunker   = []byte("chunker")
-	bucketKeyRawLeaves = []byte("rawLeaves")
-	bucketKeyHashFunc  = []byte("hashFunc")
-	bucketKeyMaxLinks  = []byte("maxLinks")
+	bucketKeyObjects        = []byte("objects")
+	bucketKeyDigests        = []byte("digests")
+	bucketKeySeed           = []byte("seed")
+	bucketKeyBenchmark      = []byte("benchmark")
+	bucketKeyType           = []byte("type")
+	bucketKeySource         = []byte("source")
+	bucketKeyLayout         = []byte("layout")
+	bucketKeyChunker        = []byte("chunker")
+	bucketKeyRawLeaves      = []byte("rawLeaves")
+	bucketKeyHashFunc       = []byte("hashFunc")
+	bucketKeyMaxLinks       = []byte("maxLinks")
+	bucketKeyHidden         = []byte("hidden")
+	bucketKeyShard          = []byte("shard")
+	bucketKeyShardThreshold = []byte("shardThreshold")
+	bucketKeyLeafCodec      = []byte("leafCodec")
+	bucketKeyCidVersion     = []byte("cidVersion")
+	bucketKeyInline         = []byte("inline")
+	bucketKeyInlineLimit    = []byte("inlineLimit")
 
 	// Node buckets.
-	bucketKeyAddress            = []byte("address")
-	bucketKeyAgentPort          = []byte("agentPort")
-	bucketKeyAppPort            = []byte("appPort")
-	bucketKeyPort               = []byte("port")
-	bucketKeyTransports         = []byte("transports")
-	bucketKeyMuxers             = []byte("muxers")
-	bucketKeySecurityTransports = []byte("securityTransports")
-	bucketKeyRouting            = []byte("routing")
+	bucketKeyAddress                = []byte("address")
+	bucketKeyAgentPort              = []byte("agentPort")
+	bucketKeyAppPort                = []byte("appPort")
+	bucketKeyInstance               = []byte("instance")
+	bucketKeyGroup                  = []byte("group")
+	bucketKeyPort                   = []byte("port")
+	bucketKeyTransports             = []byte("transports")
+	bucketKeyMuxers                 = []byte("muxers")
+	bucketKeySecurityTransports     = []byte("securityTransports")
+	bucketKeyRouting                = []byte("routing")
+	bucketKeyConnManagerLow         = []byte("connManagerLow")
+	bucketKeyConnManagerHigh        = []byte("connManagerHigh")
+	bucketKeyConnManagerGracePeriod = []byte("connManagerGracePeriod")
+	bucketKeyRestartPolicy          = []byte("restartPolicy")
+	bucketKeyAgent                  = []byte("agent")
+	bucketKeyCPUQuota               = []byte("cpuQuota")
+	bucketKeyMemoryLimit            = []byte("memoryLimit")
+	bucketKeyIOWeight               = []byte("ioWeight")
 
 	// Build buckets
-	bucketKeyLink = []byte("link")
+	bucketKeyLink   = []byte("link")
+	bucketKeyDigest = []byte("digest")
 
 	// Benchmark buckets.
 	bucketKeyCluster  = []byte("cluster")
@@ -16,9 +16,11 @@ package metadata
 
 import (
 	"context"
+	"strconv"
 	"time"
 
 	"github.com/Netflix/p2plab/errdefs"
+	digest "github.com/opencontainers/go-digest"
 	"github.com/pkg/errors"
 	bolt "go.etcd.io/bbolt"
 )
@@ -28,6 +30,13 @@ type Build struct {
 
 	Link string
 
+	// Digest is the SHA-256 digest of the build's binary. Agents verify the
+	// binary they download against it.
+	Digest digest.Digest
+
+	// Size is the size of the build's binary in bytes.
+	Size int64
+
 	CreatedAt, UpdatedAt time.Time
 }
 
@@ -153,6 +162,14 @@ func readBuild(bkt *bolt.Bucket, build *Build) error {
 			build.ID = string(v)
 		case string(bucketKeyLink):
 			build.Link = string(v)
+		case string(bucketKeyDigest):
+			build.Digest = digest.Digest(v)
+		case string(bucketKeySize):
+			size, err := strconv.ParseInt(string(v), 10, 64)
+			if err != nil {
+				return err
+			}
+			build.Size = size
 		}
 
 		return nil
@@ -168,6 +185,8 @@ func writeBuild(bkt *bolt.Bucket, build *Build) error {
 	for _, f := range []field{
 		{bucketKeyID, []byte(build.ID)},
 		{bucketKeyLink, []byte(build.Link)},
+		{bucketKeyDigest, []byte(build.Digest)},
+		{bucketKeySize, []byte(strconv.FormatInt(build.Size, 10))},
 	} {
 		err = bkt.Put(f.key, f.value)
 		if err != nil {
@@ -54,9 +54,15 @@ var (
 	ClusterCreating   ClusterStatus = "creating"
 	ClusterConnecting ClusterStatus = "connecting"
 	ClusterCreated    ClusterStatus = "created"
+	ClusterScaling    ClusterStatus = "scaling"
+	ClusterRepairing  ClusterStatus = "repairing"
 	ClusterDestroying ClusterStatus = "destroying"
 	ClusterDestroyed  ClusterStatus = "destroyed"
 	ClusterError      ClusterStatus = "error"
+
+	// ClusterDegraded is the status of a cluster whose nodes differ from the
+	// nodes its provider has.
+	ClusterDegraded ClusterStatus = "degraded"
 )
 
 type ClusterDefinition struct {
@@ -91,11 +97,27 @@ func (d ClusterDefinition) GenerateLabels() (labels []string) {
 }
 
 type ClusterGroup struct {
-	Size         int
+	// Size is the number of nodes in the group.
+	Size int
+
 	InstanceType string
 	Region       string
-	Peer         *PeerDefinition `json:"peer,omitempty"`
-	Labels       []string
+
+	// PeersPerInstance is the number of nodes packed onto each instance, each
+	// running its own labapp under the instance's labagent. Zero is the same as
+	// one.
+	PeersPerInstance int
+
+	Peer   *PeerDefinition `json:"peer,omitempty"`
+	Labels []string
+}
+
+// Instances returns the number of instances needed to host the group's nodes.
+func (g ClusterGroup) Instances() int {
+	if g.PeersPerInstance <= 1 {
+		return g.Size
+	}
+	return (g.Size + g.PeersPerInstance - 1) / g.PeersPerInstance
 }
 
 func (m *db) GetCluster(ctx context.Context, id string) (Cluster, error) {
@@ -347,6 +369,12 @@ func readClusterDefinition(bkt *bolt.Bucket) (ClusterDefinition, error) {
 				group.InstanceType = string(v)
 			case string(bucketKeyRegion):
 				group.Region = string(v)
+			case string(bucketKeyPeersPerInstance):
+				peersPerInstance, err := strconv.Atoi(string(v))
+				if err != nil {
+					return err
+				}
+				group.PeersPerInstance = peersPerInstance
 			}
 			return nil
 		})
@@ -420,6 +448,7 @@ func writeClusterDefinition(bkt *bolt.Bucket, cdef ClusterDefinition) error {
 			{bucketKeySize, []byte(strconv.Itoa(group.Size))},
 			{bucketKeyInstanceType, []byte(group.InstanceType)},
 			{bucketKeyRegion, []byte(group.Region)},
+			{bucketKeyPeersPerInstance, []byte(strconv.Itoa(group.PeersPerInstance))},
 		} {
 			err = gbkt.Put(f.key, f.value)
 			if err != nil {
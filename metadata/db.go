@@ -75,6 +75,8 @@ type NodeStore interface {
 	UpdateNode(ctx context.Context, cluster string, node Node) (Node, error)
 
 	LabelNodes(ctx context.Context, cluster string, ids, adds, removes []string) ([]Node, error)
+
+	DeleteNodes(ctx context.Context, cluster string, ids ...string) error
 }
 
 type ScenarioStore interface {
@@ -16,6 +16,7 @@ package metadata
 
 import (
 	"context"
+	"encoding/json"
 	"strconv"
 	"strings"
 	"time"
@@ -33,6 +34,20 @@ var (
 		SecurityTransports: []string{"secio"},
 		Routing:            "nil",
 	}
+
+	// Transports are the libp2p transports supported by labapp.
+	Transports = []string{"tcp", "ws", "quic"}
+)
+
+const (
+	// ClearLimit is the CPU quota, IO weight or connection manager high
+	// watermark of a peer definition update that removes the limit from the
+	// updated nodes.
+	ClearLimit = -1
+
+	// ClearMemoryLimit is the memory limit of a peer definition update that
+	// removes the limit from the updated nodes.
+	ClearMemoryLimit = "none"
 )
 
 type Node struct {
@@ -44,8 +59,19 @@ type Node struct {
 
 	AppPort int
 
+	// Instance is the index of the node's labapp among those supervised by its
+	// labagent.
+	Instance int
+
+	// Group is the index of the cluster group the node was provisioned for.
+	Group int
+
 	Peer PeerDefinition
 
+	// Agent is the status of the node's labagent as last reported by its
+	// healthcheck.
+	Agent AgentStatus
+
 	Labels []string
 
 	CreatedAt, UpdatedAt time.Time
@@ -61,6 +87,109 @@ type PeerDefinition struct {
 	SecurityTransports []string
 
 	Routing string
+
+	// ConnManagerLow is the number of connections the connection manager trims
+	// down to once ConnManagerHigh is exceeded.
+	ConnManagerLow int
+
+	// ConnManagerHigh is the number of connections above which the connection
+	// manager starts trimming. A zero value disables the connection manager.
+	ConnManagerHigh int
+
+	// ConnManagerGracePeriod is the duration new connections are protected from
+	// being trimmed, e.g. "20s".
+	ConnManagerGracePeriod string
+
+	// RestartPolicy is whether labagent restarts labapp when it exits
+	// unexpectedly. Defaults to "never".
+	RestartPolicy string
+
+	// CPUQuota is the number of CPUs labapp may use, e.g. 0.5 for half a CPU.
+	// Zero means unlimited.
+	CPUQuota float64
+
+	// MemoryLimit is the maximum memory labapp may use, e.g. "512MiB". Empty
+	// means unlimited.
+	MemoryLimit string
+
+	// IOWeight is the relative share of block IO given to labapp, from 1 to
+	// 10000. Zero leaves the system default of 100.
+	IOWeight int
+}
+
+type RestartPolicy string
+
+var (
+	RestartNever RestartPolicy = "never"
+
+	// RestartOnFailure restarts labapp with an exponential backoff when it
+	// exits with a non-zero status or by a signal.
+	RestartOnFailure RestartPolicy = "on-failure"
+)
+
+// AgentStatus is the status of a labagent and the host it runs on.
+type AgentStatus struct {
+	// Version and Revision identify the labagent binary.
+	Version, Revision string
+
+	OS, Kernel, Arch string
+
+	CPUs int
+
+	// Memory is the total memory of the host in bytes.
+	Memory uint64
+
+	// Transports are the libp2p transports supported by the host.
+	Transports []string
+
+	// BuildID is the ID of the build labapp was last updated to.
+	BuildID string
+
+	// Peer is the peer definition labapp was last updated with.
+	Peer PeerDefinition
+
+	// App is the state of the supervised labapp.
+	App AppState
+}
+
+// AppStatus is the current status of a labapp process.
+type AppStatus string
+
+var (
+	// AppUnknown is the status of an app that was never started, or whose
+	// labagent could not be reached.
+	AppUnknown AppStatus = ""
+
+	AppRunning AppStatus = "running"
+
+	AppRestarting AppStatus = "restarting"
+
+	AppExited AppStatus = "exited"
+)
+
+// AppState is the state of a labapp process supervised by labagent.
+type AppState struct {
+	Status AppStatus
+
+	PID int
+
+	// Restarts is the number of times labapp was restarted by the restart
+	// policy since it was last updated.
+	Restarts int
+
+	StartedAt time.Time
+
+	// ExitedAt is when labapp last exited, or the zero time if it never has.
+	ExitedAt time.Time
+
+	// ExitCode is the exit code of the last exit, or -1 when labapp was
+	// terminated by a signal.
+	ExitCode int
+
+	Signal string `json:",omitempty"`
+
+	// LastLines are the last lines logged by labapp before it exited.
+	LastLines []string `json:",omitempty"`
 }
 
 func (m *db) GetNode(ctx context.Context, cluster, id string) (Node, error) {
@@ -290,6 +419,15 @@ func readNode(bkt *bolt.Bucket, node *Node) error {
 			node.AgentPort, _ = strconv.Atoi(string(v))
 		case string(bucketKeyAppPort):
 			node.AppPort, _ = strconv.Atoi(string(v))
+		case string(bucketKeyInstance):
+			node.Instance, _ = strconv.Atoi(string(v))
+		case string(bucketKeyGroup):
+			node.Group, _ = strconv.Atoi(string(v))
+		case string(bucketKeyAgent):
+			err := json.Unmarshal(v, &node.Agent)
+			if err != nil {
+				return err
+			}
 		}
 
 		return nil
@@ -326,6 +464,20 @@ func readPeerDefinition(bkt *bolt.Bucket) (PeerDefinition, error) {
 			}
 		case string(bucketKeyRouting):
 			pdef.Routing = string(v)
+		case string(bucketKeyConnManagerLow):
+			pdef.ConnManagerLow, _ = strconv.Atoi(string(v))
+		case string(bucketKeyConnManagerHigh):
+			pdef.ConnManagerHigh, _ = strconv.Atoi(string(v))
+		case string(bucketKeyConnManagerGracePeriod):
+			pdef.ConnManagerGracePeriod = string(v)
+		case string(bucketKeyRestartPolicy):
+			pdef.RestartPolicy = string(v)
+		case string(bucketKeyCPUQuota):
+			pdef.CPUQuota, _ = strconv.ParseFloat(string(v), 64)
+		case string(bucketKeyMemoryLimit):
+			pdef.MemoryLimit = string(v)
+		case string(bucketKeyIOWeight):
+			pdef.IOWeight, _ = strconv.Atoi(string(v))
 		}
 
 		return nil
@@ -349,11 +501,19 @@ func writeNode(bkt *bolt.Bucket, node *Node) error {
 		return err
 	}
 
+	agent, err := json.Marshal(&node.Agent)
+	if err != nil {
+		return err
+	}
+
 	for _, f := range []field{
 		{bucketKeyID, []byte(node.ID)},
 		{bucketKeyAddress, []byte(node.Address)},
+		{bucketKeyAgent, agent},
 		{bucketKeyAgentPort, []byte(strconv.Itoa(node.AgentPort))},
 		{bucketKeyAppPort, []byte(strconv.Itoa(node.AppPort))},
+		{bucketKeyInstance, []byte(strconv.Itoa(node.Instance))},
+		{bucketKeyGroup, []byte(strconv.Itoa(node.Group))},
 	} {
 		err = bkt.Put(f.key, f.value)
 		if err != nil {
@@ -384,6 +544,13 @@ func writePeerDefinition(bkt *bolt.Bucket, pdef PeerDefinition) error {
 		{bucketKeyMuxers, []byte(strings.Join(pdef.Muxers, ","))},
 		{bucketKeySecurityTransports, []byte(strings.Join(pdef.SecurityTransports, ","))},
 		{bucketKeyRouting, []byte(pdef.Routing)},
+		{bucketKeyConnManagerLow, []byte(strconv.Itoa(pdef.ConnManagerLow))},
+		{bucketKeyConnManagerHigh, []byte(strconv.Itoa(pdef.ConnManagerHigh))},
+		{bucketKeyConnManagerGracePeriod, []byte(pdef.ConnManagerGracePeriod)},
+		{bucketKeyRestartPolicy, []byte(pdef.RestartPolicy)},
+		{bucketKeyCPUQuota, []byte(strconv.FormatFloat(pdef.CPUQuota, 'f', -1, 64))},
+		{bucketKeyMemoryLimit, []byte(pdef.MemoryLimit)},
+		{bucketKeyIOWeight, []byte(strconv.Itoa(pdef.IOWeight))},
 	} {
 		err = dbkt.Put(f.key, f.value)
 		if err != nil {
@@ -0,0 +1,67 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import "time"
+
+// PeerState is a snapshot of the internal state of a node's peer, used to
+// debug what a peer is waiting for.
+type PeerState struct {
+	// Wantlist is the cids the peer is waiting for.
+	Wantlist []string
+
+	Ledgers []PeerLedger
+
+	Peers []PeerConnection
+
+	Blockstore BlockstoreStat
+
+	Provider ProviderStat
+}
+
+// PeerLedger is the bitswap accounting with a partner peer.
+type PeerLedger struct {
+	Peer      string
+	Value     float64
+	Sent      uint64
+	Recv      uint64
+	Exchanged uint64
+}
+
+// PeerConnection is a connected peer.
+type PeerConnection struct {
+	ID string
+
+	Addrs []string
+
+	// Latency is the moving average of the round trip time to the peer, and is
+	// zero when it has not been measured.
+	Latency time.Duration
+}
+
+type BlockstoreStat struct {
+	Keys uint64
+
+	Size uint64
+}
+
+type ProviderStat struct {
+	// QueueLength is the number of cids waiting to be announced.
+	QueueLength uint64
+
+	// BitswapBufferLength is the number of blocks waiting to be provided by
+	// bitswap.
+	BitswapBufferLength int
+}
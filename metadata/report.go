@@ -52,6 +52,14 @@ type ReportNode struct {
 	Bitswap ReportBitswap
 
 	Bandwidth ReportBandwidth
+
+	Connections ReportConnections
+
+	Reads ReportReads
+
+	FindProviders ReportFindProviders
+
+	Resources ReportResources
 }
 
 type ReportBitswap struct {
@@ -64,6 +72,70 @@ type ReportBitswap struct {
 	MessagesReceived uint64
 }
 
+type ReportConnections struct {
+	// Samples is the number of open connections sampled over time.
+	Samples []ReportConnectionsSample `json:",omitempty"`
+
+	Peak uint64
+
+	Average float64
+}
+
+type ReportConnectionsSample struct {
+	Time  time.Time
+	Count int
+}
+
+// ReportReads summarizes the content read back out of a node by read tasks.
+type ReportReads struct {
+	Count uint64
+
+	Bytes uint64
+
+	Elapsed time.Duration
+
+	// Throughput is the number of bytes read per second.
+	Throughput float64
+}
+
+// ReportFindProviders summarizes the provider lookups of a node by findprovs
+// tasks.
+type ReportFindProviders struct {
+	Count uint64
+
+	// Providers is the number of providers found across all lookups.
+	Providers uint64
+
+	Elapsed time.Duration
+
+	// FirstProvider is the time spent until the first provider of each lookup
+	// was found, summed across lookups. Lookups that found no provider count
+	// as zero.
+	FirstProvider time.Duration
+}
+
+// ReportResources summarizes the host resources used by a node's labapp,
+// sampled from /proc.
+type ReportResources struct {
+	// PeakCPU and AverageCPU are the number of CPUs in use, e.g. 1.5 when one
+	// and a half cores were busy.
+	PeakCPU    float64
+	AverageCPU float64
+
+	// PeakRSS and AverageRSS are the resident set size in bytes.
+	PeakRSS    uint64
+	AverageRSS uint64
+
+	// DiskRead and DiskWritten are the bytes read from and written to
+	// storage.
+	DiskRead    uint64
+	DiskWritten uint64
+
+	// PeakFDs and AverageFDs are the number of open file descriptors.
+	PeakFDs    uint64
+	AverageFDs float64
+}
+
 type ReportBandwidth struct {
 	Totals metrics.Stats
 
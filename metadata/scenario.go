@@ -52,7 +52,8 @@ type ScenarioDefinition struct {
 // into IPFS datastructures.
 type ObjectDefinition struct {
 	// Type specifies what type is the source of the data and how the data is
-	// retrieved. Types must be one of the following: ["oci-image"].
+	// retrieved. Types must be one of the following: ["oci-image", "dir",
+	// "random"].
 	Type string `json:"type"`
 
 	Source string `json:"source"`
@@ -66,9 +67,33 @@ type ObjectDefinition struct {
 
 	RawLeaves bool `json:"rawLeaves"`
 
+	// LeafCodec specify the codec for leaf blocks, one of ["dag-pb", "raw"].
+	// When set, it takes precedence over RawLeaves.
+	LeafCodec string `json:"leafCodec,omitempty"`
+
 	HashFunc string `json:"hashFunc"`
 
 	MaxLinks int `json:"maxLinks"`
+
+	// CidVersion specify the CID version of the blocks. Defaults to CIDv1 when
+	// unset.
+	CidVersion *int `json:"cidVersion,omitempty"`
+
+	// Inline specify whether blocks up to InlineLimit bytes are inlined into
+	// their CIDs using the identity hash.
+	Inline bool `json:"inline"`
+
+	InlineLimit int `json:"inlineLimit"`
+
+	// Hidden specify whether hidden files are included when adding directories.
+	Hidden bool `json:"hidden"`
+
+	// Shard forces every directory to be sharded into a HAMT.
+	Shard bool `json:"shard"`
+
+	// ShardThreshold specify the number of entries above which a directory is
+	// sharded into a HAMT.
+	ShardThreshold int `json:"shardThreshold"`
 }
 
 // ObjectType is the type of data retrieved.
@@ -77,6 +102,13 @@ type ObjectType string
 var (
 	// ObjectContainerImage indicates that the object is an OCI image.
 	ObjectContainerImage ObjectType = "oci-image"
+
+	// ObjectDirectory indicates that the object is a local directory tree.
+	ObjectDirectory ObjectType = "dir"
+
+	// ObjectRandom indicates that the object is pseudo-random content generated
+	// from a size and seed.
+	ObjectRandom ObjectType = "random"
 )
 
 func (m *db) GetScenario(ctx context.Context, id string) (Scenario, error) {
@@ -399,6 +431,23 @@ func readObjects(bkt *bolt.Bucket) (map[string]ObjectDefinition, error) {
 				object.HashFunc = string(v)
 			case string(bucketKeyMaxLinks):
 				object.MaxLinks, _ = strconv.Atoi(string(v))
+			case string(bucketKeyHidden):
+				object.Hidden, _ = strconv.ParseBool(string(v))
+			case string(bucketKeyShard):
+				object.Shard, _ = strconv.ParseBool(string(v))
+			case string(bucketKeyShardThreshold):
+				object.ShardThreshold, _ = strconv.Atoi(string(v))
+			case string(bucketKeyLeafCodec):
+				object.LeafCodec = string(v)
+			case string(bucketKeyCidVersion):
+				cidVersion, err := strconv.Atoi(string(v))
+				if err == nil {
+					object.CidVersion = &cidVersion
+				}
+			case string(bucketKeyInline):
+				object.Inline, _ = strconv.ParseBool(string(v))
+			case string(bucketKeyInlineLimit):
+				object.InlineLimit, _ = strconv.Atoi(string(v))
 			}
 			return nil
 		})
@@ -436,12 +485,25 @@ func writeObjects(bkt *bolt.Bucket, objects map[string]ObjectDefinition) error {
 			{bucketKeyRawLeaves, []byte(strconv.FormatBool(object.RawLeaves))},
 			{bucketKeyHashFunc, []byte(object.HashFunc)},
 			{bucketKeyMaxLinks, []byte(strconv.Itoa(object.MaxLinks))},
+			{bucketKeyHidden, []byte(strconv.FormatBool(object.Hidden))},
+			{bucketKeyShard, []byte(strconv.FormatBool(object.Shard))},
+			{bucketKeyShardThreshold, []byte(strconv.Itoa(object.ShardThreshold))},
+			{bucketKeyLeafCodec, []byte(object.LeafCodec)},
+			{bucketKeyInline, []byte(strconv.FormatBool(object.Inline))},
+			{bucketKeyInlineLimit, []byte(strconv.Itoa(object.InlineLimit))},
 		} {
 			err = nbkt.Put(f.key, f.value)
 			if err != nil {
 				return err
 			}
 		}
+
+		if object.CidVersion != nil {
+			err = nbkt.Put(bucketKeyCidVersion, []byte(strconv.Itoa(*object.CidVersion)))
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -0,0 +1,54 @@
+package metadata
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScenarioObjectsRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := newTestDB(t, "scenariometatest")
+	defer func() {
+		if err := cleanup(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	cidVersion := 0
+	objects := map[string]ObjectDefinition{
+		"dir": {
+			Type:           string(ObjectDirectory),
+			Source:         "/tmp/dataset",
+			Layout:         "trickle",
+			Chunker:        "rabin",
+			RawLeaves:      true,
+			LeafCodec:      "raw",
+			HashFunc:       "sha2-256",
+			MaxLinks:       64,
+			CidVersion:     &cidVersion,
+			Inline:         true,
+			InlineLimit:    16,
+			Hidden:         true,
+			Shard:          true,
+			ShardThreshold: 128,
+		},
+		"image": {
+			Type:   string(ObjectContainerImage),
+			Source: "docker.io/library/alpine:latest",
+		},
+	}
+
+	_, err := db.CreateScenario(ctx, Scenario{
+		ID: "scenario",
+		Definition: ScenarioDefinition{
+			Objects: objects,
+		},
+	})
+	require.NoError(t, err)
+
+	scenario, err := db.GetScenario(ctx, "scenario")
+	require.NoError(t, err)
+	require.Equal(t, objects, scenario.Definition.Objects)
+}
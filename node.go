@@ -16,6 +16,9 @@ package p2plab
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"time"
 
 	"github.com/Netflix/p2plab/metadata"
 )
@@ -28,6 +31,13 @@ type NodeAPI interface {
 	Label(ctx context.Context, cluster string, ids, adds, removes []string) ([]Node, error)
 
 	List(ctx context.Context, cluster string, opts ...ListOption) ([]Node, error)
+
+	// Tasks returns the tasks a node runs in the background.
+	Tasks(ctx context.Context, cluster, id string) ([]metadata.TaskExecution, error)
+
+	// CancelTask cancels a task running in the background of a node, such as
+	// one stuck in a benchmark.
+	CancelTask(ctx context.Context, cluster, id, task string) error
 }
 
 // Node is an instance running the P2P application to be benchmarked.
@@ -46,19 +56,116 @@ type NodeProvider interface {
 	// CreateNodeGroup returns a healthy cluster of nodes.
 	CreateNodeGroup(ctx context.Context, id string, cdef metadata.ClusterDefinition) (*NodeGroup, error)
 
+	// ResizeNodeGroup provisions or releases nodes so that the cluster matches
+	// the given definition, returning all of the cluster's nodes. Nodes that
+	// are kept retain their IDs.
+	ResizeNodeGroup(ctx context.Context, ng *NodeGroup, cdef metadata.ClusterDefinition) (*NodeGroup, error)
+
 	// DestroyNodeGroup destroys a cluster of nodes.
 	DestroyNodeGroup(ctx context.Context, ng *NodeGroup) error
+
+	// ListNodeGroups returns the clusters of nodes the provider has. The nodes
+	// of a group are nil if the provider cannot list them.
+	ListNodeGroups(ctx context.Context) ([]*NodeGroup, error)
 }
 
 // NodeGroup is a cluster of nodes.
 type NodeGroup struct {
-	ID    string
+	ID string
+
+	// Definition is the cluster definition the nodes were provisioned for.
+	Definition metadata.ClusterDefinition
+
 	Nodes []metadata.Node
 }
 
+// LogsOption is an option to modify how logs are retrieved.
+type LogsOption func(*LogsSettings) error
+
+// LogsSettings specify which logs to retrieve from a node.
+type LogsSettings struct {
+	Follow bool
+
+	Since time.Time
+}
+
+// WithLogsFollow keeps streaming new logs until the context is done.
+func WithLogsFollow() LogsOption {
+	return func(s *LogsSettings) error {
+		s.Follow = true
+		return nil
+	}
+}
+
+// WithLogsSince only retrieves logs logged at or after t, including those of
+// earlier runs of labapp.
+func WithLogsSince(t time.Time) LogsOption {
+	return func(s *LogsSettings) error {
+		s.Since = t
+		return nil
+	}
+}
+
 // SSHOption is an option to modify SSH settings.
-type SSHOption func(SSHSettings) error
+type SSHOption func(*SSHSettings) error
 
 // SSHSetttings specify ssh settings when connecting to a node.
 type SSHSettings struct {
+	// Command is executed instead of an interactive shell when non-empty.
+	Command []string
+
+	// TTY allocates a pseudo-terminal of Size for the session, resized
+	// whenever a new size is received from Resize.
+	TTY    bool
+	Size   TerminalSize
+	Resize <-chan TerminalSize
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// TerminalSize is the size of a terminal in characters.
+type TerminalSize struct {
+	Rows uint16
+	Cols uint16
+}
+
+// WithSSHCommand executes a command instead of an interactive shell.
+func WithSSHCommand(args ...string) SSHOption {
+	return func(s *SSHSettings) error {
+		s.Command = args
+		return nil
+	}
+}
+
+// WithSSHTTY allocates a pseudo-terminal for the session.
+func WithSSHTTY(size TerminalSize, resize <-chan TerminalSize) SSHOption {
+	return func(s *SSHSettings) error {
+		s.TTY = true
+		s.Size = size
+		s.Resize = resize
+		return nil
+	}
+}
+
+// WithSSHStdio attaches the session to readers and writers. Without it, the
+// session has no input and its output is discarded.
+func WithSSHStdio(stdin io.Reader, stdout, stderr io.Writer) SSHOption {
+	return func(s *SSHSettings) error {
+		s.Stdin = stdin
+		s.Stdout = stdout
+		s.Stderr = stderr
+		return nil
+	}
+}
+
+// ExitError is returned when a command executed on a node exits with a
+// non-zero status.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
 }
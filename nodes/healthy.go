@@ -20,14 +20,18 @@ import (
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/logutil"
 	"github.com/Netflix/p2plab/pkg/traceutil"
+	"github.com/Netflix/p2plab/version"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"golang.org/x/sync/errgroup"
 )
 
-func WaitHealthy(ctx context.Context, ns []p2plab.Node) error {
+// WaitHealthy waits for the agents of every node to become healthy and returns
+// the status each agent reported, in the same order as ns.
+func WaitHealthy(ctx context.Context, ns []p2plab.Node) ([]metadata.AgentStatus, error) {
 	span, ctx := traceutil.StartSpanFromContext(ctx, "nodes.WaitHealthy")
 	defer span.Finish()
 	span.SetTag("nodes", len(ns))
@@ -36,22 +40,57 @@ func WaitHealthy(ctx context.Context, ns []p2plab.Node) error {
 
 	zerolog.Ctx(ctx).Info().Msg("Waiting for healthy nodes")
 	go logutil.Elapsed(gctx, 20*time.Second, "Waiting for healthy nodes")
-	for _, n := range ns {
-		n := n
+
+	statuses := make([]metadata.AgentStatus, len(ns))
+	for i, n := range ns {
+		i, n := i, n
 		healthchecks.Go(func() error {
-			ok := n.Healthcheck(gctx)
-			if !ok {
+			status, err := n.Healthcheck(gctx)
+			if err != nil {
 				return errors.Wrapf(errdefs.ErrUnavailable, "node %q", n.ID())
 			}
-			return nil
+			statuses[i] = status
+
+			return checkAgent(gctx, n, status)
 		})
 	}
 
 	err := healthchecks.Wait()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return statuses, nil
+}
 
+// checkAgent warns when a node's agent was built from a different version than
+// labd, and fails if its host doesn't support the node's transports. Agents
+// that predate reporting their status are not checked.
+func checkAgent(ctx context.Context, n p2plab.Node, status metadata.AgentStatus) error {
+	if status.Version == "" {
+		return nil
+	}
+
+	if status.Version != version.Version || status.Revision != version.Revision {
+		zerolog.Ctx(ctx).Warn().
+			Str("node", n.ID()).
+			Str("agentVersion", status.Version).
+			Str("agentRevision", status.Revision).
+			Str("version", version.Version).
+			Str("revision", version.Revision).
+			Msg("Node's agent version differs from labd")
+	}
+
+	supported := make(map[string]struct{})
+	for _, transport := range status.Transports {
+		supported[transport] = struct{}{}
+	}
+
+	for _, transport := range n.Metadata().Peer.Transports {
+		if _, ok := supported[transport]; !ok {
+			return errors.Wrapf(errdefs.ErrInvalidArgument, "node %q does not support transport %q", n.ID(), transport)
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,67 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/Netflix/p2plab/pkg/traceutil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"golang.org/x/sync/errgroup"
+)
+
+// CollectLogs writes the logs of every node since a given time into a file
+// per node in dir.
+func CollectLogs(ctx context.Context, ns []p2plab.Node, dir string, since time.Time) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "nodes.CollectLogs")
+	defer span.Finish()
+	span.SetTag("nodes", len(ns))
+
+	err := os.MkdirAll(dir, 0711)
+	if err != nil {
+		return err
+	}
+
+	collectLogs, gctx := errgroup.WithContext(ctx)
+
+	zerolog.Ctx(ctx).Info().Msg("Collecting logs")
+	go logutil.Elapsed(gctx, 20*time.Second, "Collecting logs")
+
+	for _, n := range ns {
+		n := n
+		collectLogs.Go(func() error {
+			f, err := os.Create(filepath.Join(dir, fmt.Sprintf("%s.log", n.ID())))
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			err = n.Logs(gctx, f, p2plab.WithLogsSince(since))
+			if err != nil {
+				return errors.Wrapf(err, "failed to collect logs from %q", n.ID())
+			}
+			return nil
+		})
+	}
+
+	return collectLogs.Wait()
+}
@@ -0,0 +1,146 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/Netflix/p2plab/pkg/traceutil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"golang.org/x/sync/errgroup"
+)
+
+// ProfilingStopTimeout is the time given to stop profiling on the nodes that
+// started, when profiling failed to start on others.
+var ProfilingStopTimeout = 10 * time.Second
+
+// StartProfiling starts capturing profiles on every node. If any node fails to
+// start profiling, the nodes that started are stopped again.
+func StartProfiling(ctx context.Context, ns []p2plab.Node) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "nodes.StartProfiling")
+	defer span.Finish()
+	span.SetTag("nodes", len(ns))
+
+	startProfiling, gctx := errgroup.WithContext(ctx)
+
+	var (
+		mu      sync.Mutex
+		started []p2plab.Node
+	)
+
+	zerolog.Ctx(ctx).Info().Msg("Starting profiling")
+	for _, n := range ns {
+		n := n
+		startProfiling.Go(func() error {
+			err := n.StartProfiling(gctx)
+			if err != nil {
+				return errors.Wrapf(err, "failed to start profiling on %q", n.ID())
+			}
+
+			mu.Lock()
+			started = append(started, n)
+			mu.Unlock()
+			return nil
+		})
+	}
+
+	err := startProfiling.Wait()
+	if err != nil {
+		stopProfiling(ctx, started)
+		return err
+	}
+
+	return nil
+}
+
+// stopProfiling stops profiling on nodes, discarding their profiles. The nodes
+// are stopped even if ctx is done.
+func stopProfiling(ctx context.Context, ns []p2plab.Node) {
+	logger := zerolog.Ctx(ctx)
+	sctx, cancel := context.WithTimeout(logger.WithContext(context.Background()), ProfilingStopTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, n := range ns {
+		n := n
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := n.StopProfiling(sctx)
+			if err != nil {
+				logger.Warn().Err(err).Str("node", n.ID()).Msg("Failed to stop profiling")
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// CollectProfiles stops capturing profiles on every node and writes them into
+// dir as <node>/<kind>.pb.gz.
+func CollectProfiles(ctx context.Context, ns []p2plab.Node, dir string) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "nodes.CollectProfiles")
+	defer span.Finish()
+	span.SetTag("nodes", len(ns))
+
+	collectProfiles, gctx := errgroup.WithContext(ctx)
+
+	zerolog.Ctx(ctx).Info().Msg("Collecting profiles")
+	go logutil.Elapsed(gctx, 20*time.Second, "Collecting profiles")
+
+	for _, n := range ns {
+		n := n
+		collectProfiles.Go(func() error {
+			err := n.StopProfiling(gctx)
+			if err != nil {
+				return errors.Wrapf(err, "failed to stop profiling on %q", n.ID())
+			}
+
+			ndir := filepath.Join(dir, n.ID())
+			err = os.MkdirAll(ndir, 0711)
+			if err != nil {
+				return err
+			}
+
+			for _, kind := range p2plab.ProfileKinds {
+				err = collectProfile(gctx, n, kind, filepath.Join(ndir, fmt.Sprintf("%s.pb.gz", kind)))
+				if err != nil {
+					return errors.Wrapf(err, "failed to collect %s profile from %q", kind, n.ID())
+				}
+			}
+			return nil
+		})
+	}
+
+	return collectProfiles.Wait()
+}
+
+func collectProfile(ctx context.Context, n p2plab.Node, kind, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return n.Profile(ctx, kind, f)
+}
@@ -0,0 +1,72 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodes
+
+import (
+	"context"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/pkg/traceutil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"golang.org/x/sync/errgroup"
+)
+
+// StartSampling starts sampling the connections of every node, so that their
+// reports only cover the benchmark.
+func StartSampling(ctx context.Context, ns []p2plab.Node) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "nodes.StartSampling")
+	defer span.Finish()
+	span.SetTag("nodes", len(ns))
+
+	startSampling, gctx := errgroup.WithContext(ctx)
+
+	zerolog.Ctx(ctx).Info().Msg("Starting sampling")
+	for _, n := range ns {
+		n := n
+		startSampling.Go(func() error {
+			err := n.StartSampling(gctx)
+			if err != nil {
+				return errors.Wrapf(err, "failed to start sampling on %q", n.ID())
+			}
+			return nil
+		})
+	}
+
+	return startSampling.Wait()
+}
+
+// StopSampling stops sampling the connections of every node.
+func StopSampling(ctx context.Context, ns []p2plab.Node) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "nodes.StopSampling")
+	defer span.Finish()
+	span.SetTag("nodes", len(ns))
+
+	stopSampling, gctx := errgroup.WithContext(ctx)
+
+	zerolog.Ctx(ctx).Info().Msg("Stopping sampling")
+	for _, n := range ns {
+		n := n
+		stopSampling.Go(func() error {
+			err := n.StopSampling(gctx)
+			if err != nil {
+				return errors.Wrapf(err, "failed to stop sampling on %q", n.ID())
+			}
+			return nil
+		})
+	}
+
+	return stopSampling.Wait()
+}
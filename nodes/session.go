@@ -59,7 +59,7 @@ func Session(ctx context.Context, ns []p2plab.Node, fn func(context.Context) err
 			lctx, cancel := context.WithCancel(gctx)
 			cancels = append(cancels, cancel)
 			pdef := n.Metadata().Peer
-			err := n.Update(lctx, n.ID(), "", pdef)
+			err := n.Update(lctx, n.ID(), metadata.Build{}, pdef)
 			if err != nil && !errdefs.IsCancelled(err) {
 				return errors.Wrapf(err, "failed to update node %q", n.ID())
 			}
@@ -68,7 +68,7 @@ func Session(ctx context.Context, ns []p2plab.Node, fn func(context.Context) err
 		})
 	}
 	*/
-	err := WaitHealthy(ctx, ns)
+	_, err := WaitHealthy(ctx, ns)
 	if err != nil {
 		return nil, err
 	}
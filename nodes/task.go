@@ -0,0 +1,92 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodes
+
+import (
+	"context"
+	"time"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/traceutil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+var (
+	// TaskPollInterval is the interval between polls of a task running in the
+	// background of a node.
+	TaskPollInterval = time.Second
+
+	// TaskCancelTimeout is the time given to cancel a task after its context is
+	// done.
+	TaskCancelTimeout = 10 * time.Second
+)
+
+// RunTask starts a task in the background of a node and polls it until it is
+// no longer running. Each poll is passed to fn if it is not nil. The task is
+// canceled on the node if the context is done first.
+func RunTask(ctx context.Context, n p2plab.Node, task metadata.Task, fn func(metadata.TaskExecution)) (metadata.TaskExecution, error) {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "nodes.RunTask")
+	defer span.Finish()
+	span.SetTag("node", n.ID())
+	span.SetTag("task", string(task.Type))
+
+	exec, err := n.StartTask(ctx, task)
+	if err != nil {
+		return exec, errors.Wrapf(err, "failed to start task on %q", n.ID())
+	}
+	span.SetTag("id", exec.ID)
+
+	logger := zerolog.Ctx(ctx).With().Str("node", n.ID()).Str("id", exec.ID).Logger()
+	logger.Debug().Msg("Started task")
+
+	ticker := time.NewTicker(TaskPollInterval)
+	defer ticker.Stop()
+
+	for exec.Status == metadata.TaskRunning {
+		select {
+		case <-ctx.Done():
+			cctx, cancel := context.WithTimeout(logger.WithContext(context.Background()), TaskCancelTimeout)
+			defer cancel()
+
+			err = n.CancelTask(cctx, exec.ID)
+			if err != nil {
+				logger.Warn().Err(err).Msg("Failed to cancel task")
+			}
+			return exec, ctx.Err()
+		case <-ticker.C:
+		}
+
+		exec, err = n.GetTask(ctx, exec.ID)
+		if err != nil {
+			return exec, errors.Wrapf(err, "failed to get task on %q", n.ID())
+		}
+
+		if fn != nil {
+			fn(exec)
+		}
+	}
+
+	logger.Debug().Str("status", string(exec.Status)).Dur("elapsed", exec.End.Sub(exec.Start)).Msg("Task completed")
+
+	// The result has been collected, so the node no longer needs to keep it.
+	err = n.DeleteTask(ctx, exec.ID)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to delete task")
+	}
+
+	return exec, nil
+}
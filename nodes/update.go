@@ -16,11 +16,13 @@ package nodes
 
 import (
 	"context"
+	"reflect"
 	"sort"
 	"sync"
 	"time"
 
 	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/pkg/logutil"
 	"github.com/Netflix/p2plab/pkg/traceutil"
 	"github.com/rs/zerolog"
@@ -48,7 +50,7 @@ func Update(ctx context.Context, builder p2plab.Builder, ns []p2plab.Node) error
 	}
 	sort.Strings(commits)
 
-	linkByCommit, err := BuildCommits(ctx, builder, commits)
+	buildByCommit, err := BuildCommits(ctx, builder, commits)
 	if err != nil {
 		return err
 	}
@@ -61,9 +63,9 @@ func Update(ctx context.Context, builder p2plab.Builder, ns []p2plab.Node) error
 	for _, n := range ns {
 		n := n
 		pdef := n.Metadata().Peer
-		link := linkByCommit[commitByRef[pdef.GitReference]]
+		build := buildByCommit[commitByRef[pdef.GitReference]]
 		updatePeers.Go(func() error {
-			return n.Update(gctx, n.ID(), link, pdef)
+			return n.Update(gctx, n.ID(), build, pdef)
 		})
 	}
 
@@ -75,6 +77,104 @@ func Update(ctx context.Context, builder p2plab.Builder, ns []p2plab.Node) error
 	return nil
 }
 
+// Prepare brings the peers of the nodes to a clean state and connects them.
+// Nodes whose labapp does not run their peer definition are updated, which
+// restarts them, and the others are reset in-place, which is much faster.
+func Prepare(ctx context.Context, builder p2plab.Builder, ns []p2plab.Node) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "nodes.Prepare")
+	defer span.Finish()
+	span.SetTag("nodes", len(ns))
+
+	outdated, current, err := Outdated(ctx, builder, ns)
+	if err != nil {
+		return err
+	}
+	zerolog.Ctx(ctx).Info().Int("outdated", len(outdated)).Int("current", len(current)).Msg("Preparing cluster")
+
+	if len(outdated) > 0 {
+		err = Update(ctx, builder, outdated)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(current) > 0 {
+		err = Reset(ctx, current)
+		if err != nil {
+			return err
+		}
+	}
+
+	return Connect(ctx, ns)
+}
+
+// Outdated splits the nodes into those whose labapp must be updated to run
+// their peer definition, and those whose labapp already runs it. A labapp is
+// outdated when it is not running, or when it was last updated with another
+// build or peer definition. Nodes whose agent cannot be reached are outdated.
+func Outdated(ctx context.Context, builder p2plab.Builder, ns []p2plab.Node) (outdated, current []p2plab.Node, err error) {
+	commitByRef, err := ResolveUniqueCommits(ctx, builder, ns)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	statuses := make([]metadata.AgentStatus, len(ns))
+	checks, gctx := errgroup.WithContext(ctx)
+	for i, n := range ns {
+		i, n := i, n
+		checks.Go(func() error {
+			status, err := n.Status(gctx)
+			if err != nil {
+				zerolog.Ctx(ctx).Debug().Err(err).Str("node", n.ID()).Msg("Failed to get node status")
+				return nil
+			}
+			statuses[i] = status
+			return nil
+		})
+	}
+
+	err = checks.Wait()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, n := range ns {
+		pdef := n.Metadata().Peer
+		if runsPeerDefinition(statuses[i], commitByRef[pdef.GitReference], pdef) {
+			current = append(current, n)
+		} else {
+			outdated = append(outdated, n)
+		}
+	}
+
+	return outdated, current, nil
+}
+
+// runsPeerDefinition returns whether labapp runs the build of commit with the
+// peer definition.
+func runsPeerDefinition(status metadata.AgentStatus, commit string, pdef metadata.PeerDefinition) bool {
+	if status.App.Status != metadata.AppRunning || status.BuildID != commit {
+		return false
+	}
+
+	// The git reference may resolve to another commit over time, which the
+	// build ID already accounts for.
+	applied := status.Peer
+	applied.GitReference = pdef.GitReference
+	return reflect.DeepEqual(normalizePeerDefinition(applied), normalizePeerDefinition(pdef))
+}
+
+// normalizePeerDefinition replaces empty lists with nil, which are equivalent
+// but may differ after being decoded.
+func normalizePeerDefinition(pdef metadata.PeerDefinition) metadata.PeerDefinition {
+	for _, l := range []*[]string{&pdef.Transports, &pdef.Muxers, &pdef.SecurityTransports} {
+		if len(*l) == 0 {
+			*l = nil
+		}
+	}
+	return pdef
+}
+
 func ResolveUniqueCommits(ctx context.Context, builder p2plab.Builder, ns []p2plab.Node) (commitByRef map[string]string, err error) {
 	span, ctx := traceutil.StartSpanFromContext(ctx, "nodes.ResolveUniqueCommits")
 	defer span.Finish()
@@ -111,7 +211,7 @@ func ResolveUniqueCommits(ctx context.Context, builder p2plab.Builder, ns []p2pl
 	return commitByRef, nil
 }
 
-func BuildCommits(ctx context.Context, builder p2plab.Builder, commits []string) (linkByCommit map[string]string, err error) {
+func BuildCommits(ctx context.Context, builder p2plab.Builder, commits []string) (buildByCommit map[string]metadata.Build, err error) {
 	logger := zerolog.Ctx(ctx).With().Strs("commits", commits).Logger()
 	ctx = logger.WithContext(ctx)
 	building, gctx := errgroup.WithContext(ctx)
@@ -120,17 +220,17 @@ func BuildCommits(ctx context.Context, builder p2plab.Builder, commits []string)
 	go logutil.Elapsed(gctx, 20*time.Second, "Building p2p app(s)")
 
 	var mu sync.Mutex
-	linkByCommit = make(map[string]string)
+	buildByCommit = make(map[string]metadata.Build)
 	for _, commit := range commits {
 		commit := commit
 		building.Go(func() error {
-			link, err := builder.Build(ctx, commit)
+			build, err := builder.Build(ctx, commit)
 			if err != nil {
 				return err
 			}
 
 			mu.Lock()
-			linkByCommit[commit] = link
+			buildByCommit[commit] = build
 			mu.Unlock()
 			return nil
 		})
@@ -141,6 +241,6 @@ func BuildCommits(ctx context.Context, builder p2plab.Builder, commits []string)
 		return nil, err
 	}
 
-	zerolog.Ctx(ctx).Debug().Int("links", len(linkByCommit)).Msg("Built unique links")
-	return linkByCommit, nil
+	zerolog.Ctx(ctx).Debug().Int("builds", len(buildByCommit)).Msg("Built unique builds")
+	return buildByCommit, nil
 }
@@ -0,0 +1,81 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodes
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunsPeerDefinition(t *testing.T) {
+	pdef := metadata.PeerDefinition{
+		GitReference: "HEAD",
+		Transports:   []string{"tcp"},
+		Routing:      "nil",
+	}
+	running := metadata.AppState{Status: metadata.AppRunning}
+
+	for _, tc := range []struct {
+		name     string
+		status   metadata.AgentStatus
+		expected bool
+	}{
+		{
+			name:     "same build and definition",
+			status:   metadata.AgentStatus{BuildID: "abc", Peer: pdef, App: running},
+			expected: true,
+		},
+		{
+			name: "reference resolved to the same commit",
+			status: metadata.AgentStatus{BuildID: "abc", App: running, Peer: metadata.PeerDefinition{
+				GitReference: "abc",
+				Transports:   []string{"tcp"},
+				Routing:      "nil",
+			}},
+			expected: true,
+		},
+		{
+			name:   "another build",
+			status: metadata.AgentStatus{BuildID: "def", Peer: pdef, App: running},
+		},
+		{
+			name: "another definition",
+			status: metadata.AgentStatus{BuildID: "abc", App: running, Peer: metadata.PeerDefinition{
+				GitReference: "HEAD",
+				Transports:   []string{"tcp"},
+				Routing:      "kaddht",
+			}},
+		},
+		{
+			name:   "not running",
+			status: metadata.AgentStatus{BuildID: "abc", Peer: pdef, App: metadata.AppState{Status: metadata.AppExited}},
+		},
+		{
+			name: "unreachable agent",
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, runsPeerDefinition(tc.status, "abc", pdef))
+		})
+	}
+
+	// Empty and missing lists are equivalent.
+	empty := metadata.PeerDefinition{Transports: []string{}}
+	status := metadata.AgentStatus{BuildID: "abc", App: running}
+	require.True(t, runsPeerDefinition(status, "abc", empty))
+}
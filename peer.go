@@ -17,13 +17,23 @@ package p2plab
 import (
 	"context"
 	"io"
+	"strings"
 
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/metadata"
 	cid "github.com/ipfs/go-cid"
+	cidutil "github.com/ipfs/go-cidutil"
 	files "github.com/ipfs/go-ipfs-files"
 	ipld "github.com/ipfs/go-ipld-format"
 	host "github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/peer"
+	multihash "github.com/multiformats/go-multihash"
+	"github.com/pkg/errors"
+)
+
+var (
+	// DefaultInlineLimit is the maximum size of blocks inlined into their CIDs.
+	DefaultInlineLimit = 32
 )
 
 // Peer is a minimal IPFS node that can distribute IPFS DAGs.
@@ -40,32 +50,183 @@ type Peer interface {
 	// Disconnect disconnects from libp2p peers.
 	Disconnect(ctx context.Context, infos []peer.AddrInfo) error
 
-	// Add adds content from an io.Reader into the Peer's storage.
-	Add(ctx context.Context, r io.Reader, opts ...AddOption) (ipld.Node, error)
+	// Add adds a file or directory tree into the Peer's storage.
+	Add(ctx context.Context, n files.Node, opts ...AddOption) (ipld.Node, error)
 
 	// Get returns an Unixfsv1 file from a given cid.
 	Get(ctx context.Context, c cid.Cid) (files.Node, error)
 
-	// FetchGraph fetches the full DAG rooted at a given cid.
-	FetchGraph(ctx context.Context, c cid.Cid) error
+	// Read streams the full Unixfs file tree at a given cid into w and returns
+	// the number of bytes read.
+	Read(ctx context.Context, c cid.Cid, w io.Writer) (int64, error)
+
+	// Resolve resolves a Unixfs path relative to a given cid.
+	Resolve(ctx context.Context, c cid.Cid, path string) (cid.Cid, error)
+
+	// FetchGraph fetches the DAG rooted at a given cid.
+	FetchGraph(ctx context.Context, c cid.Cid, opts ...FetchOption) error
+
+	// Pin fetches the full DAG rooted at a given cid and protects it from
+	// garbage collection. Pins are persisted with the peer's blocks until the
+	// peer is reset.
+	Pin(ctx context.Context, c cid.Cid) error
+
+	// Unpin removes the protection from garbage collection for a DAG.
+	Unpin(ctx context.Context, c cid.Cid) error
+
+	// GC removes every block not reachable from a pinned DAG and returns the
+	// number of blocks removed.
+	GC(ctx context.Context) (int, error)
+
+	// Provide announces that the peer can provide a given cid.
+	Provide(ctx context.Context, c cid.Cid) error
+
+	// FindProviders finds up to count providers of a given cid.
+	FindProviders(ctx context.Context, c cid.Cid, count int) ([]peer.AddrInfo, error)
+
+	// FetchRange fetches only the blocks spanning length bytes from offset of
+	// the Unixfs file at a given cid. Only the path of the fetch options
+	// applies.
+	FetchRange(ctx context.Context, c cid.Cid, offset, length int64, opts ...FetchOption) error
+
+	// Progress returns the blocks received since the peer was last reset, and
+	// the blocks still wanted.
+	Progress() (metadata.TaskProgress, error)
 
 	// Report returns all the metrics collected from the peer.
 	Report(ctx context.Context) (metadata.ReportNode, error)
 }
 
+// FetchOption is an option for FetchSettings.
+type FetchOption func(*FetchSettings) error
+
+// FetchSettings describe the settings for fetching a DAG.
+type FetchSettings struct {
+	// Depth limits how many links deep the DAG is fetched. A negative depth
+	// fetches the full DAG.
+	Depth int
+
+	// Path is a Unixfs path relative to the cid that is resolved in the same
+	// session as the fetch, so that the blocks along the path are only
+	// requested once.
+	Path string
+}
+
+// WithDepth sets the maximum depth of links to fetch.
+func WithDepth(depth int) FetchOption {
+	return func(s *FetchSettings) error {
+		s.Depth = depth
+		return nil
+	}
+}
+
+// WithPath sets the Unixfs path to fetch relative to the cid.
+func WithPath(path string) FetchOption {
+	return func(s *FetchSettings) error {
+		s.Path = path
+		return nil
+	}
+}
+
 // AddOption is an option for AddSettings.
 type AddOption func(*AddSettings) error
 
 // AddSettings describe the settings for adding content to the peer.
 type AddSettings struct {
-	Layout    string
-	Chunker   string
-	RawLeaves bool
-	Hidden    bool
-	Shard     bool
-	NoCopy    bool
-	HashFunc  string
-	MaxLinks  int
+	Layout         string
+	Chunker        string
+	RawLeaves      bool
+	LeafCodec      string
+	Hidden         bool
+	Shard          bool
+	ShardThreshold int
+
+	// NoCopy is not supported. Referencing file data instead of copying it
+	// requires a filestore, but peers store every block in their blockstore so
+	// that it can be served over bitswap after the source is gone.
+	NoCopy bool
+
+	HashFunc    string
+	MaxLinks    int
+	CidVersion  int
+	Inline      bool
+	InlineLimit int
+}
+
+// CidBuilder returns the builder of the CIDs of blocks added with the
+// settings.
+func (s AddSettings) CidBuilder() (cid.Builder, error) {
+	if s.CidVersion != 0 && s.CidVersion != 1 {
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "unrecognized CID version %d", s.CidVersion)
+	}
+
+	hashFuncCode, ok := multihash.Names[strings.ToLower(s.HashFunc)]
+	if !ok {
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "unrecognized hash function %q", s.HashFunc)
+	}
+	if s.CidVersion == 0 && hashFuncCode != multihash.SHA2_256 {
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "CIDv0 only supports sha2-256, not %q", s.HashFunc)
+	}
+
+	var builder cid.Builder = cid.Prefix{
+		Version:  uint64(s.CidVersion),
+		Codec:    cid.DagProtobuf,
+		MhType:   hashFuncCode,
+		MhLength: -1,
+	}
+	if s.Inline {
+		// Blocks smaller than the limit are embedded directly into their CIDs
+		// using the identity hash, so they are never sent over bitswap.
+		builder = cidutil.InlineBuilder{
+			Builder: builder,
+			Limit:   s.InlineLimit,
+		}
+	}
+
+	return builder, nil
+}
+
+// AddOptionsFromDefinition returns the add options specified by an object
+// definition.
+func AddOptionsFromDefinition(odef metadata.ObjectDefinition) []AddOption {
+	var opts []AddOption
+	if odef.Layout != "" {
+		opts = append(opts, WithLayout(odef.Layout))
+	}
+	if odef.Chunker != "" {
+		opts = append(opts, WithChunker(odef.Chunker))
+	}
+	if odef.RawLeaves {
+		opts = append(opts, WithRawLeaves(true))
+	}
+	if odef.LeafCodec != "" {
+		opts = append(opts, WithLeafCodec(odef.LeafCodec))
+	}
+	if odef.HashFunc != "" {
+		opts = append(opts, WithHashFunc(odef.HashFunc))
+	}
+	if odef.MaxLinks > 0 {
+		opts = append(opts, WithMaxLinks(odef.MaxLinks))
+	}
+	if odef.CidVersion != nil {
+		opts = append(opts, WithCidVersion(*odef.CidVersion))
+	}
+	if odef.Inline {
+		opts = append(opts, WithInline(true))
+	}
+	if odef.InlineLimit > 0 {
+		opts = append(opts, WithInlineLimit(odef.InlineLimit))
+	}
+	if odef.Hidden {
+		opts = append(opts, WithHidden(true))
+	}
+	if odef.Shard {
+		opts = append(opts, WithShard(true))
+	}
+	if odef.ShardThreshold > 0 {
+		opts = append(opts, WithShardThreshold(odef.ShardThreshold))
+	}
+	return opts
 }
 
 // WithLayout sets the format for DAG generation.
@@ -92,6 +253,14 @@ func WithRawLeaves(rawLeaves bool) AddOption {
 	}
 }
 
+// WithLeafCodec sets the codec for leaf nodes, overriding WithRawLeaves.
+func WithLeafCodec(codec string) AddOption {
+	return func(s *AddSettings) error {
+		s.LeafCodec = codec
+		return nil
+	}
+}
+
 // WithHashFunc sets the hashing function for the blocks.
 func WithHashFunc(hashFunc string) AddOption {
 	return func(s *AddSettings) error {
@@ -107,3 +276,53 @@ func WithMaxLinks(maxLinks int) AddOption {
 		return nil
 	}
 }
+
+// WithHidden sets whether to include hidden files when adding directories.
+func WithHidden(hidden bool) AddOption {
+	return func(s *AddSettings) error {
+		s.Hidden = hidden
+		return nil
+	}
+}
+
+// WithShard sets whether to always shard directories into a HAMT.
+func WithShard(shard bool) AddOption {
+	return func(s *AddSettings) error {
+		s.Shard = shard
+		return nil
+	}
+}
+
+// WithShardThreshold sets the number of entries above which a directory is
+// sharded into a HAMT. A threshold of zero disables automatic sharding.
+func WithShardThreshold(threshold int) AddOption {
+	return func(s *AddSettings) error {
+		s.ShardThreshold = threshold
+		return nil
+	}
+}
+
+// WithCidVersion sets the CID version for the blocks.
+func WithCidVersion(version int) AddOption {
+	return func(s *AddSettings) error {
+		s.CidVersion = version
+		return nil
+	}
+}
+
+// WithInline sets whether to inline blocks into their CIDs with the identity
+// hash.
+func WithInline(inline bool) AddOption {
+	return func(s *AddSettings) error {
+		s.Inline = inline
+		return nil
+	}
+}
+
+// WithInlineLimit sets the maximum size of blocks that are inlined.
+func WithInlineLimit(limit int) AddOption {
+	return func(s *AddSettings) error {
+		s.InlineLimit = limit
+		return nil
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package connsampler samples the number of open connections of a libp2p
+// network during benchmarks.
+package connsampler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/libp2p/go-libp2p-core/network"
+)
+
+// MaxSamples is the number of samples retained, after which the oldest
+// samples are dropped.
+var MaxSamples = 3600
+
+// Conns lists the open connections of a libp2p network.
+type Conns interface {
+	Conns() []network.Conn
+}
+
+// Sampler periodically samples the number of open connections between Start
+// and Stop.
+type Sampler struct {
+	conns    Conns
+	interval time.Duration
+
+	runMu  sync.Mutex
+	cancel func()
+	done   chan struct{}
+
+	mu      sync.Mutex
+	samples []metadata.ReportConnectionsSample
+}
+
+// New returns a sampler of conns that samples every interval once started.
+func New(conns Conns, interval time.Duration) *Sampler {
+	return &Sampler{
+		conns:    conns,
+		interval: interval,
+	}
+}
+
+// Start discards the samples of the previous session and starts sampling
+// until Stop is called. Starting a sampler that is already started restarts
+// it.
+func (s *Sampler) Start() {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	s.stop()
+	s.Reset()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(ctx, s.done)
+}
+
+// Stop stops sampling. The samples are kept until the sampler is started again
+// or reset.
+func (s *Sampler) Stop() {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	s.stop()
+}
+
+func (s *Sampler) stop() {
+	if s.cancel == nil {
+		return
+	}
+
+	s.cancel()
+	<-s.done
+	s.cancel = nil
+	s.done = nil
+}
+
+func (s *Sampler) run(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.sample()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Sampler) sample() {
+	sample := metadata.ReportConnectionsSample{
+		Time:  time.Now().UTC(),
+		Count: len(s.conns.Conns()),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples = append(s.samples, sample)
+	if len(s.samples) > MaxSamples {
+		s.samples = s.samples[len(s.samples)-MaxSamples:]
+	}
+}
+
+// Reset discards the samples.
+func (s *Sampler) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples = nil
+}
+
+// Report returns the samples with their peak and average.
+func (s *Sampler) Report() metadata.ReportConnections {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := metadata.ReportConnections{
+		Samples: make([]metadata.ReportConnectionsSample, len(s.samples)),
+	}
+	copy(report.Samples, s.samples)
+
+	var total int
+	for _, sample := range s.samples {
+		if uint64(sample.Count) > report.Peak {
+			report.Peak = uint64(sample.Count)
+		}
+		total += sample.Count
+	}
+	if len(s.samples) > 0 {
+		report.Average = float64(total) / float64(len(s.samples))
+	}
+
+	return report
+}
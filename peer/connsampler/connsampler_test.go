@@ -0,0 +1,108 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connsampler
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeConns struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (c *fakeConns) Conns() []network.Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return make([]network.Conn, c.count)
+}
+
+func (c *fakeConns) set(count int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count = count
+}
+
+func TestSamplerReport(t *testing.T) {
+	conns := &fakeConns{}
+	s := New(conns, time.Hour)
+
+	report := s.Report()
+	require.Empty(t, report.Samples)
+	require.Zero(t, report.Peak)
+	require.Zero(t, report.Average)
+
+	for _, count := range []int{1, 4, 2, 1} {
+		conns.set(count)
+		s.sample()
+	}
+
+	report = s.Report()
+	require.Len(t, report.Samples, 4)
+	require.Equal(t, uint64(4), report.Peak)
+	require.Equal(t, 2.0, report.Average)
+
+	s.Reset()
+	require.Empty(t, s.Report().Samples)
+}
+
+func TestSamplerMaxSamples(t *testing.T) {
+	conns := &fakeConns{}
+	s := New(conns, time.Hour)
+
+	for i := 0; i < MaxSamples+10; i++ {
+		conns.set(i)
+		s.sample()
+	}
+
+	report := s.Report()
+	require.Len(t, report.Samples, MaxSamples)
+	require.Equal(t, 10, report.Samples[0].Count)
+}
+
+func TestSamplerSession(t *testing.T) {
+	conns := &fakeConns{count: 3}
+	s := New(conns, time.Millisecond)
+
+	// Nothing is sampled before the session starts.
+	time.Sleep(10 * time.Millisecond)
+	require.Empty(t, s.Report().Samples)
+
+	s.Start()
+	require.Eventually(t, func() bool {
+		return len(s.Report().Samples) >= 3
+	}, time.Second, time.Millisecond)
+	s.Stop()
+
+	// Samples are kept but no longer taken once the session stops.
+	stopped := len(s.Report().Samples)
+	time.Sleep(10 * time.Millisecond)
+	require.Len(t, s.Report().Samples, stopped)
+	require.Equal(t, uint64(3), s.Report().Peak)
+
+	// Starting a new session discards the samples of the previous one.
+	conns.set(1)
+	s.Start()
+	defer s.Stop()
+	require.Eventually(t, func() bool {
+		report := s.Report()
+		return len(report.Samples) > 0 && report.Peak == 1
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,181 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"context"
+	"sort"
+
+	"github.com/Netflix/p2plab/metadata"
+	datastore "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	"github.com/pkg/errors"
+)
+
+// providerQueueName namespaces the provider queue in the datastore.
+const providerQueueName = "repro"
+
+// clearProviderQueue drops the cids waiting to be provided.
+func (p *Peer) clearProviderQueue() error {
+	results, err := p.ds.Query(query.Query{
+		Prefix:   datastore.NewKey("/" + providerQueueName + "/queue").String(),
+		KeysOnly: true,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to query provider queue")
+	}
+	defer results.Close()
+
+	for result := range results.Next() {
+		if result.Error != nil {
+			return result.Error
+		}
+
+		err = p.ds.Delete(datastore.NewKey(result.Key))
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete %q from provider queue", result.Key)
+		}
+	}
+
+	return nil
+}
+
+// Wantlist returns the cids the peer is waiting for.
+func (p *Peer) Wantlist() []string {
+	var wantlist []string
+	for _, c := range p.bswap.GetWantlist() {
+		wantlist = append(wantlist, c.String())
+	}
+	sort.Strings(wantlist)
+	return wantlist
+}
+
+// Ledgers returns the bitswap ledger of every connected peer.
+func (p *Peer) Ledgers() []metadata.PeerLedger {
+	var ledgers []metadata.PeerLedger
+	for _, id := range p.host.Network().Peers() {
+		receipt := p.bswap.LedgerForPeer(id)
+		if receipt == nil {
+			continue
+		}
+
+		ledgers = append(ledgers, metadata.PeerLedger{
+			Peer:      receipt.Peer,
+			Value:     receipt.Value,
+			Sent:      receipt.Sent,
+			Recv:      receipt.Recv,
+			Exchanged: receipt.Exchanged,
+		})
+	}
+	sort.Slice(ledgers, func(i, j int) bool {
+		return ledgers[i].Peer < ledgers[j].Peer
+	})
+	return ledgers
+}
+
+// ConnectedPeers returns the connected peers and their latency.
+func (p *Peer) ConnectedPeers() []metadata.PeerConnection {
+	var conns []metadata.PeerConnection
+	for _, id := range p.host.Network().Peers() {
+		var addrs []string
+		for _, conn := range p.host.Network().ConnsToPeer(id) {
+			addrs = append(addrs, conn.RemoteMultiaddr().String())
+		}
+
+		conns = append(conns, metadata.PeerConnection{
+			ID:      id.Pretty(),
+			Addrs:   addrs,
+			Latency: p.host.Peerstore().LatencyEWMA(id),
+		})
+	}
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].ID < conns[j].ID
+	})
+	return conns
+}
+
+// BlockstoreStat returns the number of blocks and their total size.
+func (p *Peer) BlockstoreStat(ctx context.Context) (metadata.BlockstoreStat, error) {
+	var stat metadata.BlockstoreStat
+
+	keys, err := p.bs.AllKeysChan(ctx)
+	if err != nil {
+		return stat, errors.Wrap(err, "failed to list blocks")
+	}
+
+	for c := range keys {
+		size, err := p.bs.GetSize(c)
+		if err != nil {
+			return stat, errors.Wrapf(err, "failed to get size of block %q", c)
+		}
+
+		stat.Keys++
+		stat.Size += uint64(size)
+	}
+
+	return stat, ctx.Err()
+}
+
+// ProviderStat returns the depth of the queues of cids waiting to be
+// announced.
+func (p *Peer) ProviderStat() (metadata.ProviderStat, error) {
+	var stat metadata.ProviderStat
+
+	bstat, err := p.bswap.Stat()
+	if err != nil {
+		return stat, err
+	}
+	stat.BitswapBufferLength = bstat.ProvideBufLen
+
+	results, err := p.ds.Query(query.Query{
+		Prefix:   datastore.NewKey("/" + providerQueueName + "/queue").String(),
+		KeysOnly: true,
+	})
+	if err != nil {
+		return stat, errors.Wrap(err, "failed to query provider queue")
+	}
+	defer results.Close()
+
+	for result := range results.Next() {
+		if result.Error != nil {
+			return stat, result.Error
+		}
+		stat.QueueLength++
+	}
+
+	return stat, nil
+}
+
+// State returns a snapshot of the internal state of the peer.
+func (p *Peer) State(ctx context.Context) (metadata.PeerState, error) {
+	state := metadata.PeerState{
+		Wantlist: p.Wantlist(),
+		Ledgers:  p.Ledgers(),
+		Peers:    p.ConnectedPeers(),
+	}
+
+	var err error
+	state.Blockstore, err = p.BlockstoreStat(ctx)
+	if err != nil {
+		return state, err
+	}
+
+	state.Provider, err = p.ProviderStat()
+	if err != nil {
+		return state, err
+	}
+
+	return state, nil
+}
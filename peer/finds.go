@@ -0,0 +1,52 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+)
+
+// findCounter accumulates the providers found and time spent looking them up.
+type findCounter struct {
+	mu     sync.Mutex
+	report metadata.ReportFindProviders
+}
+
+func (c *findCounter) Record(providers int, elapsed, first time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.report.Count++
+	c.report.Providers += uint64(providers)
+	c.report.Elapsed += elapsed
+	c.report.FirstProvider += first
+}
+
+func (c *findCounter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.report = metadata.ReportFindProviders{}
+}
+
+func (c *findCounter) Report() metadata.ReportFindProviders {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.report
+}
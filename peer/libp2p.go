@@ -17,11 +17,13 @@ package peer
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/metadata"
 	nilrouting "github.com/ipfs/go-ipfs-routing/none"
 	libp2p "github.com/libp2p/go-libp2p"
+	connmgr "github.com/libp2p/go-libp2p-connmgr"
 	host "github.com/libp2p/go-libp2p-core/host"
 	metrics "github.com/libp2p/go-libp2p-core/metrics"
 	"github.com/libp2p/go-libp2p-core/routing"
@@ -36,6 +38,12 @@ import (
 	"github.com/pkg/errors"
 )
 
+var (
+	// DefaultConnManagerGracePeriod is the duration new connections are
+	// protected from being trimmed by the connection manager.
+	DefaultConnManagerGracePeriod = 20 * time.Second
+)
+
 func NewLibp2pPeer(ctx context.Context, port int, pdef metadata.PeerDefinition, reporter metrics.Reporter) (host.Host, routing.ContentRouting, error) {
 	var (
 		addresses        []string
@@ -73,6 +81,11 @@ func NewLibp2pPeer(ctx context.Context, port int, pdef metadata.PeerDefinition,
 		return nil, nil, errors.Wrap(err, "failed to create routing option")
 	}
 
+	connManagerOption, err := NewConnManagerOption(pdef)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create connection manager option")
+	}
+
 	host, err := libp2p.New(
 		ctx,
 		libp2p.ListenAddrStrings(addresses...),
@@ -81,6 +94,7 @@ func NewLibp2pPeer(ctx context.Context, port int, pdef metadata.PeerDefinition,
 		libp2p.ChainOptions(securityOptions...),
 		libp2p.BandwidthReporter(reporter),
 		routingOption,
+		connManagerOption,
 	)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to create libp2p host")
@@ -124,6 +138,31 @@ func NewSecurityOption(securityType string) (libp2p.Option, error) {
 	}
 }
 
+func NewConnManagerOption(pdef metadata.PeerDefinition) (libp2p.Option, error) {
+	if pdef.ConnManagerHigh == 0 {
+		return libp2p.ChainOptions(), nil
+	}
+
+	if pdef.ConnManagerLow < 0 || pdef.ConnManagerLow > pdef.ConnManagerHigh {
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "connection manager low watermark %d must be between 0 and high watermark %d", pdef.ConnManagerLow, pdef.ConnManagerHigh)
+	}
+
+	gracePeriod := DefaultConnManagerGracePeriod
+	if pdef.ConnManagerGracePeriod != "" {
+		var err error
+		gracePeriod, err = time.ParseDuration(pdef.ConnManagerGracePeriod)
+		if err != nil {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "connection manager grace period %q", pdef.ConnManagerGracePeriod)
+		}
+	}
+
+	return libp2p.ConnectionManager(connmgr.NewConnManager(pdef.ConnManagerLow, pdef.ConnManagerHigh, gracePeriod)), nil
+}
+
+type dhtRouting struct {
+	*kaddht.IpfsDHT
+}
+
 func NewRoutingOption(ctx context.Context, routingType string) (libp2p.Option, routing.ContentRouting, error) {
 	switch routingType {
 	case "nil":
@@ -133,13 +172,15 @@ func NewRoutingOption(ctx context.Context, routingType string) (libp2p.Option, r
 		}
 		return libp2p.Routing(nil), routing, nil
 	case "kaddht":
-		var dht *kaddht.IpfsDHT
+		// The DHT is only constructed once the host is, so the returned content
+		// routing must be filled in after the fact.
+		r := &dhtRouting{}
 		newDHT := func(h host.Host) (routing.PeerRouting, error) {
 			var err error
-			dht, err = kaddht.New(ctx, h)
-			return dht, err
+			r.IpfsDHT, err = kaddht.New(ctx, h)
+			return r.IpfsDHT, err
 		}
-		return libp2p.Routing(newDHT), dht, nil
+		return libp2p.Routing(newDHT), r, nil
 	default:
 		return nil, nil, errors.Wrapf(errdefs.ErrInvalidArgument, "routing %q", routingType)
 	}
@@ -18,13 +18,19 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/dag"
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/peer/connsampler"
+	"github.com/Netflix/p2plab/peer/pinset"
 	bitswap "github.com/ipfs/go-bitswap"
 	"github.com/ipfs/go-bitswap/network"
 	blockservice "github.com/ipfs/go-blockservice"
@@ -32,7 +38,6 @@ import (
 	datastore "github.com/ipfs/go-datastore"
 	badger "github.com/ipfs/go-ds-badger"
 	blockstore "github.com/ipfs/go-ipfs-blockstore"
-	chunker "github.com/ipfs/go-ipfs-chunker"
 	files "github.com/ipfs/go-ipfs-files"
 	provider "github.com/ipfs/go-ipfs-provider"
 	"github.com/ipfs/go-ipfs-provider/queue"
@@ -41,9 +46,8 @@ import (
 	ipld "github.com/ipfs/go-ipld-format"
 	merkledag "github.com/ipfs/go-merkledag"
 	unixfile "github.com/ipfs/go-unixfs/file"
-	"github.com/ipfs/go-unixfs/importer/balanced"
 	"github.com/ipfs/go-unixfs/importer/helpers"
-	"github.com/ipfs/go-unixfs/importer/trickle"
+	unixfsio "github.com/ipfs/go-unixfs/io"
 	host "github.com/libp2p/go-libp2p-core/host"
 	metrics "github.com/libp2p/go-libp2p-core/metrics"
 	libp2ppeer "github.com/libp2p/go-libp2p-core/peer"
@@ -51,7 +55,6 @@ import (
 	swarm "github.com/libp2p/go-libp2p-swarm"
 	filter "github.com/libp2p/go-maddr-filter"
 	multiaddr "github.com/multiformats/go-multiaddr"
-	multihash "github.com/multiformats/go-multihash"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/sync/errgroup"
@@ -65,19 +68,43 @@ func init() {
 
 var (
 	ReprovideInterval = 12 * time.Hour
+
+	// ResetWantlistTimeout is how long a reset waits for the wants of canceled
+	// fetches to leave the bitswap wantlist.
+	ResetWantlistTimeout = 5 * time.Second
+
+	// DefaultShardThreshold is the number of entries above which a directory is
+	// sharded into a HAMT.
+	DefaultShardThreshold = 1024
+
+	// ConnectionSampleInterval is the interval between samples of the number
+	// of open connections while sampling.
+	ConnectionSampleInterval = time.Second
 )
 
 type Peer struct {
-	host     host.Host
-	dserv    ipld.DAGService
-	system   provider.System
-	r        routing.ContentRouting
-	bswap    *bitswap.Bitswap
-	bserv    blockservice.BlockService
-	bs       blockstore.Blockstore
-	ds       datastore.Batching
-	swarm    *swarm.Swarm
-	reporter metrics.Reporter
+	host      host.Host
+	dserv     ipld.DAGService
+	system    provider.System
+	r         routing.ContentRouting
+	bswap     *bitswap.Bitswap
+	bserv     blockservice.BlockService
+	bs        blockstore.Blockstore
+	ds        datastore.Batching
+	swarm     *swarm.Swarm
+	reporter  *metrics.BandwidthCounter
+	conns     *connsampler.Sampler
+	resources *resourceSampler
+	pins      *pinset.Set
+	reads     *readCounter
+	finds     *findCounter
+
+	// mu makes resets mutually exclusive with reading the peer's counters.
+	mu sync.RWMutex
+
+	// bswapBaseline is the bitswap stat at the last reset, as bitswap's
+	// counters cannot be cleared.
+	bswapBaseline bitswap.Stat
 }
 
 func New(ctx context.Context, root string, port int, pdef metadata.PeerDefinition) (*Peer, error) {
@@ -135,18 +162,28 @@ func New(ctx context.Context, root string, port int, pdef metadata.PeerDefinitio
 		}
 	}()
 
+	conns := connsampler.New(h.Network(), ConnectionSampleInterval)
+
+	resources := &resourceSampler{}
+	go resources.Run(ctx, ResourceSampleInterval)
+
 	dserv := merkledag.NewDAGService(bserv)
 	return &Peer{
-		host:     h,
-		dserv:    dserv,
-		system:   system,
-		r:        r,
-		bswap:    bswap,
-		bserv:    bserv,
-		bs:       bs,
-		ds:       ds,
-		swarm:    swarm,
-		reporter: reporter,
+		host:      h,
+		dserv:     dserv,
+		system:    system,
+		r:         r,
+		bswap:     bswap,
+		bserv:     bserv,
+		bs:        bs,
+		ds:        ds,
+		swarm:     swarm,
+		reporter:  reporter,
+		conns:     conns,
+		resources: resources,
+		pins:      pinset.New(ds),
+		reads:     &readCounter{},
+		finds:     &findCounter{},
 	}, nil
 }
 
@@ -207,15 +244,20 @@ func (p *Peer) Disconnect(ctx context.Context, infos []libp2ppeer.AddrInfo) erro
 	return g.Wait()
 }
 
-func (p *Peer) Add(ctx context.Context, r io.Reader, opts ...p2plab.AddOption) (ipld.Node, error) {
+func (p *Peer) Add(ctx context.Context, n files.Node, opts ...p2plab.AddOption) (ipld.Node, error) {
 	settings := p2plab.AddSettings{
-		Layout:    "balanced",
-		Chunker:   "size-262144",
-		RawLeaves: false,
-		Hidden:    false,
-		NoCopy:    false,
-		HashFunc:  "sha2-256",
-		MaxLinks:  helpers.DefaultLinksPerBlock,
+		Layout:         "balanced",
+		Chunker:        "size-262144",
+		RawLeaves:      false,
+		Hidden:         false,
+		Shard:          false,
+		ShardThreshold: DefaultShardThreshold,
+		NoCopy:         false,
+		HashFunc:       "sha2-256",
+		MaxLinks:       helpers.DefaultLinksPerBlock,
+		CidVersion:     1,
+		Inline:         false,
+		InlineLimit:    p2plab.DefaultInlineLimit,
 	}
 	for _, opt := range opts {
 		err := opt(&settings)
@@ -224,51 +266,139 @@ func (p *Peer) Add(ctx context.Context, r io.Reader, opts ...p2plab.AddOption) (
 		}
 	}
 
-	prefix, err := merkledag.PrefixForCidVersion(1)
+	return dag.Add(ctx, p.dserv, n, settings)
+}
+
+func (p *Peer) Resolve(ctx context.Context, c cid.Cid, path string) (cid.Cid, error) {
+	ng := merkledag.NewSession(ctx, p.dserv)
+	nd, err := resolvePath(ctx, ng, c, path)
 	if err != nil {
-		return nil, errors.Wrap(err, "unrecognized CID version")
+		return cid.Undef, err
 	}
 
-	hashFuncCode, ok := multihash.Names[strings.ToLower(settings.HashFunc)]
-	if !ok {
-		return nil, errors.Wrapf(err, "unrecognized hash function %q", settings.HashFunc)
+	return nd.Cid(), nil
+}
+
+// resolvePath returns the node at a Unixfs path relative to c, getting the
+// nodes along the path from ng.
+func resolvePath(ctx context.Context, ng ipld.NodeGetter, c cid.Cid, path string) (ipld.Node, error) {
+	dserv := merkledag.NewReadOnlyDagService(ng)
+
+	nd, err := dserv.Get(ctx, c)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get %q", c)
 	}
-	prefix.MhType = hashFuncCode
 
-	dbp := helpers.DagBuilderParams{
-		Dagserv:    p.dserv,
-		RawLeaves:  settings.RawLeaves,
-		Maxlinks:   settings.MaxLinks,
-		NoCopy:     settings.NoCopy,
-		CidBuilder: &prefix,
+	for _, name := range strings.Split(path, "/") {
+		if name == "" {
+			continue
+		}
+
+		// Directories are resolved through the Unixfs directory interface so
+		// that HAMT sharded directories only fetch the shards on the path.
+		dir, err := unixfsio.NewDirectoryFromNode(dserv, nd)
+		if err != nil {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "%q is not a directory in path %q", name, path)
+		}
+
+		nd, err = dir.Find(ctx, name)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, errors.Wrapf(errdefs.ErrNotFound, "%q in path %q", name, path)
+			}
+			return nil, err
+		}
 	}
 
-	chnk, err := chunker.FromString(r, settings.Chunker)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create chunker")
+	return nd, nil
+}
+
+func (p *Peer) FetchGraph(ctx context.Context, c cid.Cid, opts ...p2plab.FetchOption) error {
+	settings := p2plab.FetchSettings{
+		Depth: -1,
+	}
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return err
+		}
 	}
 
-	dbh, err := dbp.New(chnk)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create dag builder")
+	ng := merkledag.NewSession(ctx, p.dserv)
+	if settings.Path != "" {
+		nd, err := resolvePath(ctx, ng, c, settings.Path)
+		if err != nil {
+			return err
+		}
+		c = nd.Cid()
 	}
 
-	var nd ipld.Node
-	switch settings.Layout {
-	case "trickle":
-		nd, err = trickle.Layout(dbh)
-	case "balanced":
-		nd, err = balanced.Layout(dbh)
-	default:
-		return nil, errors.Errorf("unrecognized layout %q", settings.Layout)
+	return dag.WalkDepth(ctx, c, ng, settings.Depth)
+}
+
+// Provide announces to the content routing system that the peer can provide
+// c.
+func (p *Peer) Provide(ctx context.Context, c cid.Cid) error {
+	return p.r.Provide(ctx, c, true)
+}
+
+// FindProviders finds up to count providers of c from the content routing
+// system.
+func (p *Peer) FindProviders(ctx context.Context, c cid.Cid, count int) ([]libp2ppeer.AddrInfo, error) {
+	var (
+		infos []libp2ppeer.AddrInfo
+		first time.Duration
+		start = time.Now()
+	)
+	for info := range p.r.FindProvidersAsync(ctx, c, count) {
+		if len(infos) == 0 {
+			first = time.Since(start)
+		}
+		infos = append(infos, info)
+	}
+
+	if ctx.Err() != nil {
+		return infos, ctx.Err()
 	}
 
-	return nd, err
+	p.finds.Record(len(infos), time.Since(start), first)
+	return infos, nil
 }
 
-func (p *Peer) FetchGraph(ctx context.Context, c cid.Cid) error {
+func (p *Peer) FetchRange(ctx context.Context, c cid.Cid, offset, length int64, opts ...p2plab.FetchOption) error {
+	var settings p2plab.FetchSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return err
+		}
+	}
+
 	ng := merkledag.NewSession(ctx, p.dserv)
-	return dag.Walk(ctx, c, ng)
+	nd, err := resolvePath(ctx, ng, c, settings.Path)
+	if err != nil {
+		return err
+	}
+
+	// The DAG reader only fetches the blocks it needs to read, so seeking skips
+	// over the blocks before offset.
+	dr, err := unixfsio.NewDagReader(ctx, nd, ng)
+	if err != nil {
+		return errors.Wrapf(errdefs.ErrInvalidArgument, "%q is not a file: %s", c, err)
+	}
+	defer dr.Close()
+
+	_, err = dr.Seek(offset, io.SeekStart)
+	if err != nil {
+		return errors.Wrapf(err, "failed to seek to %d", offset)
+	}
+
+	_, err = io.CopyN(ioutil.Discard, dr, length)
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	return nil
 }
 
 func (p *Peer) Get(ctx context.Context, c cid.Cid) (files.Node, error) {
@@ -280,11 +410,148 @@ func (p *Peer) Get(ctx context.Context, c cid.Cid) (files.Node, error) {
 	return unixfile.NewUnixfsFile(ctx, p.dserv, nd)
 }
 
+// Read streams the full Unixfs file tree at a given cid into w and records
+// the bytes read and time taken in the peer's report.
+func (p *Peer) Read(ctx context.Context, c cid.Cid, w io.Writer) (int64, error) {
+	start := time.Now()
+	n, err := p.Get(ctx, c)
+	if err != nil {
+		return 0, err
+	}
+
+	written, err := dag.Read(ctx, n, w)
+	if err != nil {
+		return written, errors.Wrapf(err, "failed to read %q", c)
+	}
+
+	p.reads.Record(written, time.Since(start))
+	return written, nil
+}
+
+// Reset clears the peer's blocks, bitswap ledgers, bandwidth counters and
+// connections. Progress and reports wait for a reset to complete.
+func (p *Peer) Reset(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Closing every connection also drops the bitswap ledgers of each peer.
+	for _, conn := range p.host.Network().Conns() {
+		err := conn.Close()
+		if err != nil {
+			return errors.Wrapf(err, "failed to close connection to %q", conn.RemotePeer())
+		}
+	}
+
+	err := p.clearProviderQueue()
+	if err != nil {
+		return err
+	}
+
+	// Wants are only removed from the wantlist once the fetches wanting them
+	// are canceled, which callers must do before resetting.
+	err = p.waitWantlistEmpty(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys, err := p.bs.AllKeysChan(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list blocks")
+	}
+
+	for c := range keys {
+		err = p.bs.DeleteBlock(c)
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete block %q", c)
+		}
+	}
+
+	err = p.pins.Reset()
+	if err != nil {
+		return errors.Wrap(err, "failed to reset pins")
+	}
+
+	stat, err := p.bswap.Stat()
+	if err != nil {
+		return err
+	}
+	p.bswapBaseline = *stat
+
+	p.reporter.Reset()
+	p.conns.Reset()
+	p.resources.Reset()
+	p.reads.Reset()
+	p.finds.Reset()
+	return nil
+}
+
+func (p *Peer) waitWantlistEmpty(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, ResetWantlistTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		wantlist := p.bswap.GetWantlist()
+		if len(wantlist) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(errdefs.ErrUnavailable, "wantlist still has %d blocks", len(wantlist))
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Peer) Progress() (metadata.TaskProgress, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stat, err := p.bswap.Stat()
+	if err != nil {
+		return metadata.TaskProgress{}, err
+	}
+	base := p.bswapBaseline
+
+	var providers int
+	for _, id := range p.host.Network().Peers() {
+		if p.bswap.LedgerForPeer(id).Recv > 0 {
+			providers++
+		}
+	}
+
+	return metadata.TaskProgress{
+		BlocksReceived: stat.BlocksReceived - base.BlocksReceived,
+		DataReceived:   stat.DataReceived - base.DataReceived,
+		Wantlist:       len(stat.Wantlist),
+		Providers:      providers,
+	}, nil
+}
+
+// StartSampling starts sampling the number of open connections, discarding
+// the samples of the previous benchmark.
+func (p *Peer) StartSampling() {
+	p.conns.Start()
+}
+
+// StopSampling stops sampling the number of open connections. The samples are
+// reported until sampling starts again or the peer is reset.
+func (p *Peer) StopSampling() {
+	p.conns.Stop()
+}
+
 func (p *Peer) Report(ctx context.Context) (metadata.ReportNode, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	stat, err := p.bswap.Stat()
 	if err != nil {
 		return metadata.ReportNode{}, err
 	}
+	base := p.bswapBaseline
 
 	peers := make(map[string]metrics.Stats)
 	peersByID := p.reporter.GetBandwidthByPeer()
@@ -294,19 +561,23 @@ func (p *Peer) Report(ctx context.Context) (metadata.ReportNode, error) {
 
 	return metadata.ReportNode{
 		Bitswap: metadata.ReportBitswap{
-			BlocksReceived:   stat.BlocksReceived,
-			DataReceived:     stat.DataReceived,
-			BlocksSent:       stat.BlocksSent,
-			DataSent:         stat.DataSent,
-			DupBlksReceived:  stat.DupBlksReceived,
-			DupDataReceived:  stat.DupDataReceived,
-			MessagesReceived: stat.MessagesReceived,
+			BlocksReceived:   stat.BlocksReceived - base.BlocksReceived,
+			DataReceived:     stat.DataReceived - base.DataReceived,
+			BlocksSent:       stat.BlocksSent - base.BlocksSent,
+			DataSent:         stat.DataSent - base.DataSent,
+			DupBlksReceived:  stat.DupBlksReceived - base.DupBlksReceived,
+			DupDataReceived:  stat.DupDataReceived - base.DupDataReceived,
+			MessagesReceived: stat.MessagesReceived - base.MessagesReceived,
 		},
 		Bandwidth: metadata.ReportBandwidth{
 			Totals:    p.reporter.GetBandwidthTotals(),
 			Peers:     peers,
 			Protocols: p.reporter.GetBandwidthByProtocol(),
 		},
+		Connections:   p.conns.Report(),
+		Reads:         p.reads.Report(),
+		FindProviders: p.finds.Report(),
+		Resources:     p.resources.Report(),
 	}, nil
 }
 
@@ -328,7 +599,7 @@ func NewBlockstore(ctx context.Context, ds datastore.Batching) (blockstore.Block
 }
 
 func NewProviderSystem(ctx context.Context, ds datastore.Batching, bs blockstore.Blockstore, r routing.ContentRouting) (provider.System, error) {
-	queue, err := queue.NewQueue(ctx, "repro", ds)
+	queue, err := queue.NewQueue(ctx, providerQueueName, ds)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create new queue")
 	}
@@ -0,0 +1,45 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"context"
+
+	cid "github.com/ipfs/go-cid"
+	"github.com/pkg/errors"
+)
+
+// Pin fetches the full DAG rooted at c and protects it from garbage
+// collection.
+func (p *Peer) Pin(ctx context.Context, c cid.Cid) error {
+	err := p.FetchGraph(ctx, c)
+	if err != nil {
+		return errors.Wrapf(err, "failed to fetch %q", c)
+	}
+
+	return p.pins.Add(c)
+}
+
+// Unpin removes the protection from garbage collection for the DAG rooted at
+// c.
+func (p *Peer) Unpin(ctx context.Context, c cid.Cid) error {
+	return p.pins.Remove(c)
+}
+
+// GC removes every block that is not reachable from a pinned DAG root and
+// returns the number of blocks removed.
+func (p *Peer) GC(ctx context.Context) (int, error) {
+	return p.pins.GC(ctx, p.bs)
+}
@@ -0,0 +1,202 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pinset keeps the DAG roots of a peer that are protected from
+// garbage collection.
+package pinset
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Netflix/p2plab/errdefs"
+	cid "github.com/ipfs/go-cid"
+	datastore "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	ipld "github.com/ipfs/go-ipld-format"
+	"github.com/pkg/errors"
+)
+
+// Prefix is the key under which pins are persisted in the datastore.
+var Prefix = datastore.NewKey("/pins")
+
+// Set is the set of DAG roots that are protected from garbage collection.
+// Pins are persisted in the peer's datastore, so they survive restarts of the
+// peer like the blocks they protect.
+type Set struct {
+	mu sync.Mutex
+	ds datastore.Datastore
+}
+
+// New returns the pin set persisted in ds.
+func New(ds datastore.Datastore) *Set {
+	return &Set{ds: ds}
+}
+
+// Add pins c.
+func (s *Set) Add(c cid.Cid) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.ds.Put(key(c), []byte{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to pin %q", c)
+	}
+	return nil
+}
+
+// Remove unpins c, and returns an error if c is not pinned.
+func (s *Set) Remove(c cid.Cid) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ok, err := s.ds.Has(key(c))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.Wrapf(errdefs.ErrNotFound, "pin %q", c)
+	}
+
+	err = s.ds.Delete(key(c))
+	if err != nil {
+		return errors.Wrapf(err, "failed to unpin %q", c)
+	}
+	return nil
+}
+
+// Reset removes every pin.
+func (s *Set) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, err := s.keys()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range keys {
+		err = s.ds.Delete(key(c))
+		if err != nil {
+			return errors.Wrapf(err, "failed to unpin %q", c)
+		}
+	}
+	return nil
+}
+
+// Keys returns the pinned DAG roots.
+func (s *Set) Keys() ([]cid.Cid, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.keys()
+}
+
+func (s *Set) keys() ([]cid.Cid, error) {
+	results, err := s.ds.Query(query.Query{
+		Prefix:   Prefix.String(),
+		KeysOnly: true,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query pins")
+	}
+	defer results.Close()
+
+	var keys []cid.Cid
+	for result := range results.Next() {
+		if result.Error != nil {
+			return nil, result.Error
+		}
+
+		c, err := cid.Parse(datastore.NewKey(result.Key).BaseNamespace())
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pin %q", result.Key)
+		}
+		keys = append(keys, c)
+	}
+
+	return keys, nil
+}
+
+// GC removes every block of bs that is not reachable from a pinned DAG root
+// and returns the number of blocks removed.
+func (s *Set) GC(ctx context.Context, bs blockstore.Blockstore) (int, error) {
+	roots, err := s.Keys()
+	if err != nil {
+		return 0, err
+	}
+
+	reachable := cid.NewSet()
+	for _, root := range roots {
+		err := markReachable(ctx, bs, root, reachable)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	keys, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list blocks")
+	}
+
+	var removed int
+	for c := range keys {
+		if reachable.Has(c) {
+			continue
+		}
+
+		err = bs.DeleteBlock(c)
+		if err != nil {
+			return removed, errors.Wrapf(err, "failed to delete block %q", c)
+		}
+		removed++
+	}
+
+	return removed, nil
+}
+
+// markReachable adds every block reachable from root in the blockstore to
+// set, without fetching blocks from the network.
+func markReachable(ctx context.Context, bs blockstore.Blockstore, root cid.Cid, set *cid.Set) error {
+	stack := []cid.Cid{root}
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if !set.Visit(c) {
+			continue
+		}
+
+		blk, err := bs.Get(c)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get pinned block %q", c)
+		}
+
+		nd, err := ipld.Decode(blk)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode block %q", c)
+		}
+
+		for _, link := range nd.Links() {
+			stack = append(stack, link.Cid)
+		}
+	}
+
+	return nil
+}
+
+func key(c cid.Cid) datastore.Key {
+	return Prefix.ChildString(c.String())
+}
@@ -0,0 +1,126 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pinset
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	cid "github.com/ipfs/go-cid"
+	datastore "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	ipld "github.com/ipfs/go-ipld-format"
+	merkledag "github.com/ipfs/go-merkledag"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStores() (datastore.Batching, blockstore.Blockstore) {
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	return ds, blockstore.NewBlockstore(ds)
+}
+
+// addDAG adds a root with a link to a leaf to bs.
+func addDAG(t *testing.T, bs blockstore.Blockstore, data string) (root, leaf cid.Cid) {
+	leafNode := merkledag.NodeWithData([]byte(data))
+	rootNode := merkledag.NodeWithData([]byte("root " + data))
+	require.NoError(t, rootNode.AddNodeLink("leaf", leafNode))
+
+	for _, nd := range []ipld.Node{leafNode, rootNode} {
+		require.NoError(t, bs.Put(nd))
+	}
+	return rootNode.Cid(), leafNode.Cid()
+}
+
+func TestSet(t *testing.T) {
+	ds, bs := newTestStores()
+	s := New(ds)
+
+	a, _ := addDAG(t, bs, "a")
+	b, _ := addDAG(t, bs, "b")
+
+	require.NoError(t, s.Add(a))
+	require.NoError(t, s.Add(b))
+	require.NoError(t, s.Add(a))
+
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []cid.Cid{a, b}, keys)
+
+	require.NoError(t, s.Remove(a))
+	require.True(t, errdefs.IsNotFound(s.Remove(a)))
+
+	// Pins are persisted in the datastore.
+	keys, err = New(ds).Keys()
+	require.NoError(t, err)
+	require.Equal(t, []cid.Cid{b}, keys)
+
+	require.NoError(t, s.Reset())
+	keys, err = s.Keys()
+	require.NoError(t, err)
+	require.Empty(t, keys)
+}
+
+func TestGC(t *testing.T) {
+	ctx := context.Background()
+	ds, bs := newTestStores()
+	s := New(ds)
+
+	pinned, pinnedLeaf := addDAG(t, bs, "pinned")
+	unpinned, unpinnedLeaf := addDAG(t, bs, "unpinned")
+	require.NoError(t, s.Add(pinned))
+
+	removed, err := s.GC(ctx, bs)
+	require.NoError(t, err)
+	require.Equal(t, 2, removed)
+
+	for c, expected := range map[cid.Cid]bool{
+		pinned:       true,
+		pinnedLeaf:   true,
+		unpinned:     false,
+		unpinnedLeaf: false,
+	} {
+		has, err := bs.Has(c)
+		require.NoError(t, err)
+		require.Equal(t, expected, has, c.String())
+	}
+
+	// The pins themselves are not blocks, and survive garbage collection.
+	keys, err := s.Keys()
+	require.NoError(t, err)
+	require.Equal(t, []cid.Cid{pinned}, keys)
+
+	require.NoError(t, s.Remove(pinned))
+	removed, err = s.GC(ctx, bs)
+	require.NoError(t, err)
+	require.Equal(t, 2, removed)
+}
+
+func TestGCMissingPinnedBlock(t *testing.T) {
+	ds, bs := newTestStores()
+	s := New(ds)
+
+	pinned, leaf := addDAG(t, bs, "pinned")
+	require.NoError(t, s.Add(pinned))
+	require.NoError(t, bs.DeleteBlock(leaf))
+
+	_, err := s.GC(context.Background(), bs)
+	require.Error(t, err)
+
+	has, err := bs.Has(pinned)
+	require.NoError(t, err)
+	require.True(t, has)
+}
@@ -0,0 +1,62 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+)
+
+// readCounter accumulates the bytes and time spent reading content back out of
+// the peer.
+type readCounter struct {
+	mu      sync.Mutex
+	count   uint64
+	bytes   uint64
+	elapsed time.Duration
+}
+
+func (c *readCounter) Record(n int64, elapsed time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.count++
+	c.bytes += uint64(n)
+	c.elapsed += elapsed
+}
+
+func (c *readCounter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.count, c.bytes, c.elapsed = 0, 0, 0
+}
+
+func (c *readCounter) Report() metadata.ReportReads {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := metadata.ReportReads{
+		Count:   c.count,
+		Bytes:   c.bytes,
+		Elapsed: c.elapsed,
+	}
+	if c.elapsed > 0 {
+		report.Throughput = float64(c.bytes) / c.elapsed.Seconds()
+	}
+	return report
+}
@@ -0,0 +1,132 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package peer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/procutil"
+)
+
+var (
+	// ResourceSampleInterval is the interval between samples of the host
+	// resources used by the peer's process.
+	ResourceSampleInterval = time.Second
+)
+
+// resourceSampler periodically samples the CPU, memory, disk and file
+// descriptors used by the current process. Samples are only available where
+// /proc is, so reports are empty on other platforms.
+type resourceSampler struct {
+	mu sync.Mutex
+
+	// base is the sample at the last reset, from which disk usage is counted.
+	base     procutil.Stat
+	last     procutil.Stat
+	lastTime time.Time
+
+	samples    int
+	cpuSamples int
+	totalCPU   float64
+	peakCPU    float64
+	totalRSS   float64
+	peakRSS    uint64
+	totalFDs   float64
+	peakFDs    uint64
+}
+
+func (s *resourceSampler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.sample()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *resourceSampler) sample() {
+	st, err := procutil.Self()
+	if err != nil {
+		return
+	}
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lastTime.IsZero() {
+		s.base = st
+	} else {
+		cpu := (st.CPUTime - s.last.CPUTime).Seconds() / now.Sub(s.lastTime).Seconds()
+		s.totalCPU += cpu
+		if cpu > s.peakCPU {
+			s.peakCPU = cpu
+		}
+		s.cpuSamples++
+	}
+	s.last, s.lastTime = st, now
+
+	s.samples++
+	s.totalRSS += float64(st.RSS)
+	if st.RSS > s.peakRSS {
+		s.peakRSS = st.RSS
+	}
+	s.totalFDs += float64(st.FDs)
+	if uint64(st.FDs) > s.peakFDs {
+		s.peakFDs = uint64(st.FDs)
+	}
+}
+
+func (s *resourceSampler) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.base = s.last
+	s.samples, s.cpuSamples = 0, 0
+	s.totalCPU, s.peakCPU = 0, 0
+	s.totalRSS, s.peakRSS = 0, 0
+	s.totalFDs, s.peakFDs = 0, 0
+}
+
+func (s *resourceSampler) Report() metadata.ReportResources {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := metadata.ReportResources{
+		PeakCPU:     s.peakCPU,
+		PeakRSS:     s.peakRSS,
+		DiskRead:    s.last.ReadBytes - s.base.ReadBytes,
+		DiskWritten: s.last.WriteBytes - s.base.WriteBytes,
+		PeakFDs:     s.peakFDs,
+	}
+	if s.cpuSamples > 0 {
+		report.AverageCPU = s.totalCPU / float64(s.cpuSamples)
+	}
+	if s.samples > 0 {
+		report.AverageRSS = uint64(s.totalRSS / float64(s.samples))
+		report.AverageFDs = s.totalFDs / float64(s.samples)
+	}
+
+	return report
+}
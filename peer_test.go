@@ -0,0 +1,89 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package p2plab
+
+import (
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	cid "github.com/ipfs/go-cid"
+	multihash "github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCidBuilder(t *testing.T) {
+	small := []byte("small")
+	large := make([]byte, 64)
+
+	for _, tc := range []struct {
+		name     string
+		settings AddSettings
+		data     []byte
+		version  uint64
+		mhType   uint64
+	}{
+		{
+			name:     "v0",
+			settings: AddSettings{HashFunc: "sha2-256"},
+			data:     small,
+			version:  0,
+			mhType:   multihash.SHA2_256,
+		},
+		{
+			name:     "v1 blake2b",
+			settings: AddSettings{HashFunc: "blake2b-256", CidVersion: 1},
+			data:     small,
+			version:  1,
+			mhType:   multihash.BLAKE2B_MIN + 31,
+		},
+		{
+			name:     "inlined",
+			settings: AddSettings{HashFunc: "sha2-256", CidVersion: 1, Inline: true, InlineLimit: 32},
+			data:     small,
+			version:  1,
+			mhType:   multihash.IDENTITY,
+		},
+		{
+			name:     "above inline limit",
+			settings: AddSettings{HashFunc: "sha2-256", CidVersion: 1, Inline: true, InlineLimit: 32},
+			data:     large,
+			version:  1,
+			mhType:   multihash.SHA2_256,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			builder, err := tc.settings.CidBuilder()
+			require.NoError(t, err)
+
+			c, err := builder.Sum(tc.data)
+			require.NoError(t, err)
+
+			prefix := c.Prefix()
+			require.Equal(t, tc.version, prefix.Version)
+			require.Equal(t, uint64(cid.DagProtobuf), prefix.Codec)
+			require.Equal(t, tc.mhType, prefix.MhType)
+		})
+	}
+
+	for _, settings := range []AddSettings{
+		{HashFunc: "sha2-256", CidVersion: 2},
+		{HashFunc: "unknown", CidVersion: 1},
+		{HashFunc: "sha3-256", CidVersion: 0},
+	} {
+		_, err := settings.CidBuilder()
+		require.True(t, errdefs.IsInvalidArgument(err), "%+v", settings)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package procutil
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// userHZ is the number of clock ticks per second used by /proc to report CPU
+// time, which is 100 on every architecture supported by Linux.
+const userHZ = 100
+
+// Stat is a snapshot of the resources used by a process.
+type Stat struct {
+	// CPUTime is the user and system CPU time consumed by the process.
+	CPUTime time.Duration
+
+	// RSS is the resident set size of the process in bytes.
+	RSS uint64
+
+	// ReadBytes and WriteBytes are the number of bytes the process caused to
+	// be read from or written to storage.
+	ReadBytes  uint64
+	WriteBytes uint64
+
+	// FDs is the number of open file descriptors.
+	FDs int
+}
+
+// Self returns the resources used by the current process.
+func Self() (Stat, error) {
+	return Read("self")
+}
+
+// Read returns the resources used by the process with the given pid, read
+// from /proc. It is only supported on Linux.
+func Read(pid string) (Stat, error) {
+	var st Stat
+	root := filepath.Join("/proc", pid)
+
+	content, err := ioutil.ReadFile(filepath.Join(root, "stat"))
+	if err != nil {
+		return st, err
+	}
+
+	st, err = parseStat(content)
+	if err != nil {
+		return st, err
+	}
+
+	content, err = ioutil.ReadFile(filepath.Join(root, "io"))
+	if err != nil && !os.IsNotExist(err) {
+		return st, err
+	}
+	st.ReadBytes, st.WriteBytes, err = parseIO(content)
+	if err != nil {
+		return st, err
+	}
+
+	fds, err := ioutil.ReadDir(filepath.Join(root, "fd"))
+	if err != nil {
+		return st, err
+	}
+	st.FDs = len(fds)
+
+	return st, nil
+}
+
+// parseStat parses the CPU time and RSS from the contents of /proc/<pid>/stat.
+func parseStat(content []byte) (Stat, error) {
+	var st Stat
+
+	// The command name is wrapped in parentheses and may itself contain spaces
+	// or parentheses, so fields are counted from the last closing parenthesis.
+	i := bytes.LastIndexByte(content, ')')
+	if i < 0 {
+		return st, errors.New("invalid stat: missing command name")
+	}
+
+	// fields[0] is the process state, the third field of stat.
+	fields := strings.Fields(string(content[i+1:]))
+	if len(fields) < 22 {
+		return st, errors.Errorf("invalid stat: expected at least 24 fields but got %d", len(fields)+2)
+	}
+
+	var values [3]uint64
+	for j, field := range []int{11, 12, 21} {
+		var err error
+		values[j], err = strconv.ParseUint(fields[field], 10, 64)
+		if err != nil {
+			return st, errors.Wrapf(err, "invalid stat field %d", field+3)
+		}
+	}
+
+	utime, stime, rss := values[0], values[1], values[2]
+	st.CPUTime = time.Duration(utime+stime) * time.Second / userHZ
+	st.RSS = rss * uint64(os.Getpagesize())
+	return st, nil
+}
+
+// parseIO parses the storage bytes read and written from the contents of
+// /proc/<pid>/io.
+func parseIO(content []byte) (readBytes, writeBytes uint64, err error) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		var (
+			key   string
+			value uint64
+		)
+		_, err = fmt.Sscanf(scanner.Text(), "%s %d", &key, &value)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "invalid io line %q", scanner.Text())
+		}
+
+		switch key {
+		case "read_bytes:":
+			readBytes = value
+		case "write_bytes:":
+			writeBytes = value
+		}
+	}
+
+	return readBytes, writeBytes, scanner.Err()
+}
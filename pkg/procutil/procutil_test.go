@@ -0,0 +1,57 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package procutil
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStat(t *testing.T) {
+	content := []byte("1234 (lab app) (x)) S 1 1234 1234 0 -1 4194560 2045 0 0 0 250 50 0 0 20 0 12 0 4567 1234567 300 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0\n")
+	st, err := parseStat(content)
+	require.NoError(t, err)
+	require.Equal(t, 3*time.Second, st.CPUTime)
+	require.Equal(t, uint64(300*os.Getpagesize()), st.RSS)
+}
+
+func TestParseIO(t *testing.T) {
+	content := []byte("rchar: 100\nwchar: 200\nsyscr: 3\nsyscw: 4\nread_bytes: 4096\nwrite_bytes: 8192\ncancelled_write_bytes: 0\n")
+	readBytes, writeBytes, err := parseIO(content)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4096), readBytes)
+	require.Equal(t, uint64(8192), writeBytes)
+}
+
+func TestParseMemTotal(t *testing.T) {
+	content := []byte("MemTotal:        2048 kB\nMemFree:         1024 kB\n")
+	memTotal, err := parseMemTotal(content)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2048*1024), memTotal)
+}
+
+func TestSelf(t *testing.T) {
+	if _, err := os.Stat("/proc/self/stat"); err != nil {
+		t.Skip("/proc is not available")
+	}
+
+	st, err := Self()
+	require.NoError(t, err)
+	require.NotZero(t, st.RSS)
+	require.NotZero(t, st.FDs)
+}
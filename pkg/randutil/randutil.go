@@ -0,0 +1,60 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package randutil
+
+import (
+	"io"
+	"math/rand"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/Netflix/p2plab/errdefs"
+	humanize "github.com/dustin/go-humanize"
+	"github.com/pkg/errors"
+)
+
+// ParseSource parses a source of random content of the form
+// "<size>[?seed=<n>]", e.g. "100MB?seed=1".
+func ParseSource(source string) (size uint64, seed int64, err error) {
+	parts := strings.SplitN(source, "?", 2)
+	size, err = humanize.ParseBytes(parts[0])
+	if err != nil {
+		return 0, 0, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid size in %q", source)
+	}
+
+	if len(parts) == 2 {
+		params, err := url.ParseQuery(parts[1])
+		if err != nil {
+			return 0, 0, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid params in %q", source)
+		}
+
+		if params.Get("seed") != "" {
+			seed, err = strconv.ParseInt(params.Get("seed"), 10, 64)
+			if err != nil {
+				return 0, 0, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid seed in %q", source)
+			}
+		}
+	}
+
+	return size, seed, nil
+}
+
+// NewReader returns a reader of size pseudo-random bytes. The same seed always
+// produces the same bytes, so content can be generated independently on
+// different hosts.
+func NewReader(size uint64, seed int64) io.Reader {
+	return io.LimitReader(rand.New(rand.NewSource(seed)), int64(size))
+}
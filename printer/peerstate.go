@@ -0,0 +1,123 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/alecthomas/template"
+	humanize "github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+)
+
+var (
+	PeerStateTemplate = template.Must(template.New("peerstate").Parse(`# Blockstore
+Blocks: {{.Blocks}}
+Size: {{.Size}}
+
+# Provider
+Queue: {{.ProviderQueue}}
+Bitswap buffer: {{.BitswapBuffer}}
+
+# Wantlist ({{.WantlistLength}})
+{{.Wantlist}}
+# Ledgers
+{{.LedgersTable}}
+# Peers
+{{.PeersTable}}`))
+)
+
+type PeerStateData struct {
+	Blocks         string
+	Size           string
+	ProviderQueue  string
+	BitswapBuffer  string
+	WantlistLength int
+	Wantlist       string
+	LedgersTable   string
+	PeersTable     string
+}
+
+func printPeerState(state metadata.PeerState) error {
+	var wantlist string
+	for _, c := range state.Wantlist {
+		wantlist += c + "\n"
+	}
+
+	data := PeerStateData{
+		Blocks:         humanize.Comma(int64(state.Blockstore.Keys)),
+		Size:           humanize.Bytes(state.Blockstore.Size),
+		ProviderQueue:  humanize.Comma(int64(state.Provider.QueueLength)),
+		BitswapBuffer:  humanize.Comma(int64(state.Provider.BitswapBufferLength)),
+		WantlistLength: len(state.Wantlist),
+		Wantlist:       wantlist,
+		LedgersTable:   printPeerStateLedgers(state),
+		PeersTable:     printPeerStatePeers(state),
+	}
+
+	err := PeerStateTemplate.Execute(os.Stdout, &data)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func printPeerStateLedgers(state metadata.PeerState) string {
+	buf := new(bytes.Buffer)
+	table := tablewriter.NewWriter(buf)
+	table.SetAutoFormatHeaders(false)
+
+	table.SetHeader([]string{"PEER", "VALUE", "SENT", "RECV", "EXCHANGED"})
+	for _, ledger := range state.Ledgers {
+		table.Append([]string{
+			ledger.Peer,
+			fmt.Sprintf("%.2f", ledger.Value),
+			humanize.Bytes(ledger.Sent),
+			humanize.Bytes(ledger.Recv),
+			humanize.Comma(int64(ledger.Exchanged)),
+		})
+	}
+
+	table.Render()
+	return buf.String()
+}
+
+func printPeerStatePeers(state metadata.PeerState) string {
+	buf := new(bytes.Buffer)
+	table := tablewriter.NewWriter(buf)
+	table.SetAutoFormatHeaders(false)
+
+	table.SetHeader([]string{"ID", "ADDRS", "LATENCY"})
+	for _, conn := range state.Peers {
+		latency := "-"
+		if conn.Latency > 0 {
+			latency = conn.Latency.String()
+		}
+
+		table.Append([]string{
+			conn.ID,
+			strings.Join(conn.Addrs, ","),
+			latency,
+		})
+	}
+
+	table.Render()
+	return buf.String()
+}
@@ -20,6 +20,7 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/alecthomas/template"
@@ -36,25 +37,45 @@ Trace: {{.Trace}}
 # Bandwidth
 {{.BandwidthTable}}
 # Bitswap
-{{.BitswapTable}}`))
+{{.BitswapTable}}
+# Connections
+{{.ConnectionsTable}}
+# Reads
+{{.ReadsTable}}
+# Find Providers
+{{.FindProvidersTable}}
+# Resources
+{{.ResourcesTable}}`))
 )
 
 type ReportData struct {
-	TotalTime      string
-	Trace          string
-	BandwidthTable string
-	BitswapTable   string
+	TotalTime          string
+	Trace              string
+	BandwidthTable     string
+	BitswapTable       string
+	ConnectionsTable   string
+	ReadsTable         string
+	FindProvidersTable string
+	ResourcesTable     string
 }
 
 func printReport(report metadata.Report) error {
 	bwTable := printReportBandwidth(report)
 	bswapTable := printReportBitswap(report)
+	connsTable := printReportConnections(report)
+	readsTable := printReportReads(report)
+	findsTable := printReportFindProviders(report)
+	resourcesTable := printReportResources(report)
 
 	data := ReportData{
-		TotalTime:      durafmt.Parse(report.Summary.TotalTime).String(),
-		Trace:          report.Summary.Trace,
-		BandwidthTable: bwTable,
-		BitswapTable:   bswapTable,
+		TotalTime:          durafmt.Parse(report.Summary.TotalTime).String(),
+		Trace:              report.Summary.Trace,
+		BandwidthTable:     bwTable,
+		BitswapTable:       bswapTable,
+		ConnectionsTable:   connsTable,
+		ReadsTable:         readsTable,
+		FindProvidersTable: findsTable,
+		ResourcesTable:     resourcesTable,
 	}
 
 	err := ReportTemplate.Execute(os.Stdout, &data)
@@ -146,6 +167,164 @@ func printReportBitswap(report metadata.Report) string {
 	return buf.String()
 }
 
+func printReportConnections(report metadata.Report) string {
+	buf := new(bytes.Buffer)
+	table := tablewriter.NewWriter(buf)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoMergeCells(true)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{"QUERY", "NODE", "PEAK", "AVERAGE", "FINAL"})
+
+	qryBuckets, nodeIdsByQryBucket := sortQueryBuckets(report)
+	for _, qryBucket := range qryBuckets {
+		for _, nodeId := range nodeIdsByQryBucket[qryBucket] {
+			conns := report.Nodes[nodeId].Connections
+
+			var final int
+			if len(conns.Samples) > 0 {
+				final = conns.Samples[len(conns.Samples)-1].Count
+			}
+
+			table.Append([]string{
+				qryBucket,
+				nodeId,
+				humanize.Comma(int64(conns.Peak)),
+				fmt.Sprintf("%.1f", conns.Average),
+				humanize.Comma(int64(final)),
+			})
+		}
+	}
+
+	conns := report.Aggregates.Totals.Connections
+	table.SetFooter([]string{
+		"",
+		"TOTAL",
+		humanize.Comma(int64(conns.Peak)),
+		fmt.Sprintf("%.1f", conns.Average),
+		"",
+	})
+
+	table.Render()
+	return buf.String()
+}
+
+func printReportReads(report metadata.Report) string {
+	buf := new(bytes.Buffer)
+	table := tablewriter.NewWriter(buf)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoMergeCells(true)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{"QUERY", "NODE", "READS", "BYTES", "ELAPSED", "THROUGHPUT"})
+
+	qryBuckets, nodeIdsByQryBucket := sortQueryBuckets(report)
+	for _, qryBucket := range qryBuckets {
+		for _, nodeId := range nodeIdsByQryBucket[qryBucket] {
+			reads := report.Nodes[nodeId].Reads
+			table.Append([]string{
+				qryBucket,
+				nodeId,
+				humanize.Comma(int64(reads.Count)),
+				humanize.Bytes(reads.Bytes),
+				durafmt.Parse(reads.Elapsed).String(),
+				fmt.Sprintf("%s/s", humanize.Bytes(uint64(reads.Throughput))),
+			})
+		}
+	}
+
+	reads := report.Aggregates.Totals.Reads
+	table.SetFooter([]string{
+		"",
+		"TOTAL",
+		humanize.Comma(int64(reads.Count)),
+		humanize.Bytes(reads.Bytes),
+		durafmt.Parse(reads.Elapsed).String(),
+		fmt.Sprintf("%s/s", humanize.Bytes(uint64(reads.Throughput))),
+	})
+
+	table.Render()
+	return buf.String()
+}
+
+func printReportFindProviders(report metadata.Report) string {
+	buf := new(bytes.Buffer)
+	table := tablewriter.NewWriter(buf)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoMergeCells(true)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{"QUERY", "NODE", "LOOKUPS", "PROVIDERS", "AVGELAPSED", "AVGFIRSTPROVIDER"})
+
+	qryBuckets, nodeIdsByQryBucket := sortQueryBuckets(report)
+	for _, qryBucket := range qryBuckets {
+		for _, nodeId := range nodeIdsByQryBucket[qryBucket] {
+			finds := report.Nodes[nodeId].FindProviders
+			table.Append(append([]string{qryBucket, nodeId}, findProvidersRow(finds)...))
+		}
+	}
+
+	table.SetFooter(append([]string{"", "TOTAL"}, findProvidersRow(report.Aggregates.Totals.FindProviders)...))
+
+	table.Render()
+	return buf.String()
+}
+
+func findProvidersRow(finds metadata.ReportFindProviders) []string {
+	var elapsed, first time.Duration
+	if finds.Count > 0 {
+		elapsed = finds.Elapsed / time.Duration(finds.Count)
+		first = finds.FirstProvider / time.Duration(finds.Count)
+	}
+
+	return []string{
+		humanize.Comma(int64(finds.Count)),
+		humanize.Comma(int64(finds.Providers)),
+		durafmt.Parse(elapsed).String(),
+		durafmt.Parse(first).String(),
+	}
+}
+
+func printReportResources(report metadata.Report) string {
+	buf := new(bytes.Buffer)
+	table := tablewriter.NewWriter(buf)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoMergeCells(true)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{"QUERY", "NODE", "PEAKCPU", "AVGCPU", "PEAKRSS", "AVGRSS", "DISKREAD", "DISKWRITTEN", "PEAKFDS", "AVGFDS"})
+
+	qryBuckets, nodeIdsByQryBucket := sortQueryBuckets(report)
+	for _, qryBucket := range qryBuckets {
+		for _, nodeId := range nodeIdsByQryBucket[qryBucket] {
+			resources := report.Nodes[nodeId].Resources
+			table.Append(append([]string{qryBucket, nodeId}, resourcesRow(resources)...))
+		}
+	}
+
+	table.SetFooter(append([]string{"", "TOTAL"}, resourcesRow(report.Aggregates.Totals.Resources)...))
+
+	table.Render()
+	return buf.String()
+}
+
+func resourcesRow(resources metadata.ReportResources) []string {
+	return []string{
+		fmt.Sprintf("%.2f", resources.PeakCPU),
+		fmt.Sprintf("%.2f", resources.AverageCPU),
+		humanize.Bytes(resources.PeakRSS),
+		humanize.Bytes(resources.AverageRSS),
+		humanize.Bytes(resources.DiskRead),
+		humanize.Bytes(resources.DiskWritten),
+		humanize.Comma(int64(resources.PeakFDs)),
+		fmt.Sprintf("%.1f", resources.AverageFDs),
+	}
+}
+
 func sortQueryBuckets(report metadata.Report) (qryBuckets []string, nodeIdsByQryBucket map[string][]string) {
 	queriesByNodeId := make(map[string][]string)
 	for qry, nodeIds := range report.Queries {
@@ -19,6 +19,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Netflix/p2plab/metadata"
 	humanize "github.com/dustin/go-humanize"
@@ -48,6 +49,8 @@ func (p *tablePrinter) Print(v interface{}) error {
 		}
 	case metadata.Report:
 		return printReport(t)
+	case metadata.PeerState:
+		return printPeerState(t)
 	default:
 		p.addHeader(table, t)
 		p.addRow(table, t)
@@ -62,7 +65,7 @@ func (p *tablePrinter) addHeader(table *tablewriter.Table, v interface{}) {
 	case metadata.Cluster:
 		table.SetHeader([]string{"ID", "STATUS", "SIZE", "LABELS", "CREATEDAT", "UPDATEDAT"})
 	case metadata.Node:
-		table.SetHeader([]string{"ID", "ADDRESS", "GITREFERENCE", "LABELS", "CREATEDAT", "UPDATEDAT"})
+		table.SetHeader([]string{"ID", "ADDRESS", "GITREFERENCE", "AGENT", "HOST", "BUILD", "APP", "LABELS", "CREATEDAT", "UPDATEDAT"})
 	case metadata.Scenario:
 		table.SetHeader([]string{"ID", "LABELS", "CREATEDAT", "UPDATEDAT"})
 	case metadata.Benchmark:
@@ -70,7 +73,9 @@ func (p *tablePrinter) addHeader(table *tablewriter.Table, v interface{}) {
 	case metadata.Experiment:
 		table.SetHeader([]string{"ID", "STATUS", "LABELS", "CREATEDAT", "UPDATEDAT"})
 	case metadata.Build:
-		table.SetHeader([]string{"ID", "LINK", "CREATEDAT", "UPDATEDAT"})
+		table.SetHeader([]string{"ID", "LINK", "DIGEST", "SIZE", "CREATEDAT", "UPDATEDAT"})
+	case metadata.TaskExecution:
+		table.SetHeader([]string{"ID", "TYPE", "SUBJECT", "STATUS", "RECEIVED", "STARTED", "ELAPSED"})
 	}
 }
 
@@ -90,6 +95,10 @@ func (p *tablePrinter) addRow(table *tablewriter.Table, v interface{}) {
 			t.ID,
 			t.Address,
 			t.Peer.GitReference,
+			t.Agent.Version,
+			hostStatus(t.Agent),
+			t.Agent.BuildID,
+			appStatus(t.Agent.App),
 			strings.Join(t.Labels, ","),
 			humanize.Time(t.CreatedAt),
 			humanize.Time(t.UpdatedAt),
@@ -123,8 +132,72 @@ func (p *tablePrinter) addRow(table *tablewriter.Table, v interface{}) {
 		table.Append([]string{
 			t.ID,
 			string(t.Link),
+			t.Digest.String(),
+			humanize.Bytes(uint64(t.Size)),
 			humanize.Time(t.CreatedAt),
 			humanize.Time(t.UpdatedAt),
 		})
+	case metadata.TaskExecution:
+		end := t.End
+		if t.Status == metadata.TaskRunning {
+			end = time.Now()
+		}
+
+		status := string(t.Status)
+		if t.Error != "" {
+			status = fmt.Sprintf("%s: %s", status, t.Error)
+		}
+
+		table.Append([]string{
+			t.ID,
+			string(t.Task.Type),
+			t.Task.Subject,
+			status,
+			humanize.Bytes(t.Progress.DataReceived),
+			humanize.Time(t.Start),
+			end.Sub(t.Start).Round(time.Millisecond).String(),
+		})
 	}
 }
+
+// hostStatus summarizes the host a node's labagent runs on.
+func hostStatus(status metadata.AgentStatus) string {
+	if status.OS == "" {
+		return ""
+	}
+
+	host := fmt.Sprintf("%s/%s", status.OS, status.Arch)
+	if status.Kernel != "" {
+		host = fmt.Sprintf("%s %s", host, status.Kernel)
+	}
+
+	host = fmt.Sprintf("%s, %d CPUs", host, status.CPUs)
+	if status.Memory > 0 {
+		host = fmt.Sprintf("%s, %s", host, humanize.IBytes(status.Memory))
+	}
+
+	return host
+}
+
+// appStatus summarizes the state of a node's labapp, including why it last
+// exited if it is not running.
+func appStatus(state metadata.AppState) string {
+	status := string(state.Status)
+	if state.Status == metadata.AppUnknown {
+		status = "unknown"
+	}
+
+	if state.Status == metadata.AppExited {
+		if state.Signal != "" {
+			status = fmt.Sprintf("%s (%s)", status, state.Signal)
+		} else {
+			status = fmt.Sprintf("%s (%d)", status, state.ExitCode)
+		}
+	}
+
+	if state.Restarts > 0 {
+		status = fmt.Sprintf("%s, %d restarts", status, state.Restarts)
+	}
+
+	return status
+}
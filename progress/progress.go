@@ -0,0 +1,62 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progress
+
+import (
+	"context"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// TaskField is the log field holding the progress of a task on a single
+	// node.
+	TaskField = "taskProgress"
+
+	// NodesField is the log field holding the merged progress of every node,
+	// keyed by node id.
+	NodesField = "nodesProgress"
+)
+
+var (
+	// TaskInterval is the interval between progress events of a running task.
+	TaskInterval = time.Second
+
+	// MergeInterval is the interval between merged progress events.
+	MergeInterval = 2 * time.Second
+)
+
+// Report logs the progress returned by fn every interval until the context is
+// done.
+func Report(ctx context.Context, interval time.Duration, fn func() (metadata.TaskProgress, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			progress, err := fn()
+			if err != nil {
+				zerolog.Ctx(ctx).Debug().Err(err).Msg("Failed to get task progress")
+				continue
+			}
+			zerolog.Ctx(ctx).Info().Interface(TaskField, &progress).Msg("Task progress")
+		}
+	}
+}
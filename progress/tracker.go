@@ -0,0 +1,177 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progress
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/rs/zerolog"
+)
+
+// Tracker merges the task progress events streamed from many nodes.
+type Tracker struct {
+	stage   string
+	mu      sync.Mutex
+	nodes   map[string]metadata.TaskProgress
+	changed bool
+}
+
+// NewTracker returns a tracker for a stage of a scenario, such as seeding or
+// benchmarking.
+func NewTracker(stage string) *Tracker {
+	return &Tracker{
+		stage: stage,
+		nodes: make(map[string]metadata.TaskProgress),
+	}
+}
+
+// Writer returns a log writer for the remote logs of a node. Task progress
+// events are consumed by the tracker and every other log line is written to
+// w, which may be nil.
+func (t *Tracker) Writer(id string, w io.Writer) io.Writer {
+	return &trackerWriter{tracker: t, id: id, w: w}
+}
+
+// Run logs the merged progress of every node every interval until the context
+// is done. Nothing is logged when no progress was made.
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			nodes, changed := t.snapshot()
+			if !changed {
+				continue
+			}
+			zerolog.Ctx(ctx).Info().Str("stage", t.stage).Interface(NodesField, nodes).Msg("Progress")
+		}
+	}
+}
+
+// Update records the latest progress of a node.
+func (t *Tracker) Update(id string, progress metadata.TaskProgress) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.nodes[id] = progress
+	t.changed = true
+}
+
+func (t *Tracker) snapshot() (map[string]metadata.TaskProgress, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	nodes := make(map[string]metadata.TaskProgress, len(t.nodes))
+	for id, progress := range t.nodes {
+		nodes[id] = progress
+	}
+
+	changed := t.changed
+	t.changed = false
+	return nodes, changed
+}
+
+// trackerWriter buffers the remote logs of a node, which may be split across
+// writes, and decodes them one JSON event at a time.
+type trackerWriter struct {
+	tracker *Tracker
+	id      string
+	w       io.Writer
+	buf     []byte
+}
+
+func (tw *trackerWriter) Write(p []byte) (int, error) {
+	tw.buf = append(tw.buf, p...)
+	for {
+		n, ok := tw.next()
+		if !ok {
+			break
+		}
+
+		err := tw.write(tw.buf[:n])
+		tw.buf = tw.buf[n:]
+		if err != nil {
+			return len(p), err
+		}
+	}
+
+	if len(tw.buf) == 0 {
+		// Release the buffer once everything written has been consumed.
+		tw.buf = nil
+	}
+	return len(p), nil
+}
+
+// next returns the length of the next complete event in the buffer, including
+// the newline ending it. Lines that are not JSON objects are passed on whole.
+func (tw *trackerWriter) next() (int, bool) {
+	dec := json.NewDecoder(bytes.NewReader(tw.buf))
+
+	var raw json.RawMessage
+	err := dec.Decode(&raw)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return 0, false
+	}
+
+	if err != nil || raw[0] != '{' {
+		i := bytes.IndexByte(tw.buf, '\n')
+		if i < 0 {
+			return 0, false
+		}
+		return i + 1, true
+	}
+
+	// Events end with a newline, so wait for it to pass it on with the event.
+	n := int(dec.InputOffset())
+	if n == len(tw.buf) {
+		return 0, false
+	}
+	if tw.buf[n] == '\n' {
+		n++
+	}
+	return n, true
+}
+
+func (tw *trackerWriter) write(p []byte) error {
+	var evt map[string]json.RawMessage
+	err := json.Unmarshal(p, &evt)
+	if err == nil {
+		raw, ok := evt[TaskField]
+		if ok {
+			var progress metadata.TaskProgress
+			err = json.Unmarshal(raw, &progress)
+			if err == nil {
+				tw.tracker.Update(tw.id, progress)
+				return nil
+			}
+		}
+	}
+
+	if tw.w == nil {
+		return nil
+	}
+	_, err = tw.w.Write(p)
+	return err
+}
@@ -0,0 +1,92 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progress
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackerWriter(t *testing.T) {
+	logs := `{"level":"info","message":"Starting"}
+not json
+{"level":"info","taskProgress":{"BlocksReceived":1,"DataReceived":256},"message":"Task progress"}
+{"level":"info","taskProgress":{"BlocksReceived":2,"DataReceived":512,"Wantlist":3},"message":"Task progress"}
+{"level":"info","message":"Done"}
+`
+	expected := `{"level":"info","message":"Starting"}
+not json
+{"level":"info","message":"Done"}
+`
+
+	for _, tc := range []struct {
+		name string
+		size int
+	}{
+		{"all", len(logs)},
+		{"bytes", 1},
+		{"chunks", 7},
+		{"lines", 0},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := NewTracker("test")
+
+			var buf bytes.Buffer
+			w := tracker.Writer("node", &buf)
+
+			var chunks [][]byte
+			if tc.size == 0 {
+				chunks = bytes.SplitAfter([]byte(logs), []byte("\n"))
+			} else {
+				for p := []byte(logs); len(p) > 0; {
+					n := tc.size
+					if n > len(p) {
+						n = len(p)
+					}
+					chunks = append(chunks, p[:n])
+					p = p[n:]
+				}
+			}
+
+			for _, chunk := range chunks {
+				n, err := w.Write(chunk)
+				require.NoError(t, err)
+				require.Equal(t, len(chunk), n)
+			}
+			require.Equal(t, expected, buf.String())
+
+			nodes, changed := tracker.snapshot()
+			require.True(t, changed)
+			require.Equal(t, map[string]metadata.TaskProgress{
+				"node": {BlocksReceived: 2, DataReceived: 512, Wantlist: 3},
+			}, nodes)
+		})
+	}
+}
+
+func TestTrackerWriterNil(t *testing.T) {
+	tracker := NewTracker("test")
+	w := tracker.Writer("node", nil)
+
+	_, err := w.Write([]byte("{\"message\":\"Starting\"}\n{\"taskProgress\":{\"Providers\":1}}\n"))
+	require.NoError(t, err)
+
+	nodes, _ := tracker.snapshot()
+	require.Equal(t, 1, nodes["node"].Providers)
+}
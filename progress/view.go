@@ -0,0 +1,132 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/Netflix/p2plab/metadata"
+	humanize "github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+)
+
+// View is a log writer that renders merged progress events as a table that is
+// redrawn in place. When out is not a terminal, progress events are written as
+// regular log lines instead.
+type View struct {
+	w     io.Writer
+	out   *os.File
+	tty   bool
+	mu    sync.Mutex
+	table []byte
+}
+
+// NewView returns a view that writes log lines to w and renders the progress
+// table to out.
+func NewView(w io.Writer, out *os.File) *View {
+	tty := false
+	stat, err := out.Stat()
+	if err == nil {
+		tty = stat.Mode()&os.ModeCharDevice != 0
+	}
+	return &View{w: w, out: out, tty: tty}
+}
+
+func (v *View) Write(p []byte) (int, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.tty {
+		var evt map[string]json.RawMessage
+		err := json.Unmarshal(p, &evt)
+		if err == nil {
+			raw, ok := evt[NodesField]
+			if ok {
+				var nodes map[string]metadata.TaskProgress
+				err = json.Unmarshal(raw, &nodes)
+				if err == nil {
+					v.clear()
+					v.table = renderTable(nodes)
+					v.out.Write(v.table)
+					return len(p), nil
+				}
+			}
+		}
+	}
+
+	// Keep the table below the latest log line.
+	v.clear()
+	n, err := v.w.Write(p)
+	if v.table != nil {
+		v.out.Write(v.table)
+	}
+	return n, err
+}
+
+// clear erases the last rendered table from the terminal.
+func (v *View) clear() {
+	if v.table == nil {
+		return
+	}
+	fmt.Fprintf(v.out, "\033[%dA\033[J", bytes.Count(v.table, []byte("\n")))
+}
+
+func renderTable(nodes map[string]metadata.TaskProgress) []byte {
+	buf := new(bytes.Buffer)
+	table := tablewriter.NewWriter(buf)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetAutoFormatHeaders(false)
+
+	table.SetHeader([]string{"NODE", "BLOCKS", "DATA", "WANTLIST", "PROVIDERS"})
+
+	var ids []string
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var total metadata.TaskProgress
+	for _, id := range ids {
+		progress := nodes[id]
+		table.Append([]string{
+			id,
+			humanize.Comma(int64(progress.BlocksReceived)),
+			humanize.Bytes(progress.DataReceived),
+			humanize.Comma(int64(progress.Wantlist)),
+			humanize.Comma(int64(progress.Providers)),
+		})
+
+		total.BlocksReceived += progress.BlocksReceived
+		total.DataReceived += progress.DataReceived
+		total.Wantlist += progress.Wantlist
+	}
+
+	table.SetFooter([]string{
+		"TOTAL",
+		humanize.Comma(int64(total.BlocksReceived)),
+		humanize.Bytes(total.DataReceived),
+		humanize.Comma(int64(total.Wantlist)),
+		"",
+	})
+
+	table.Render()
+	return buf.Bytes()
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 
 	"github.com/Netflix/p2plab"
@@ -31,10 +32,12 @@ import (
 
 type provider struct {
 	root      string
-	nodes     map[string][]*node
 	logger    *zerolog.Logger
 	agentOpts []labagent.LabagentOption
-	mu        sync.Mutex
+
+	mu sync.Mutex
+	// nodes maps cluster IDs to their nodes.
+	nodes map[string][]*node
 }
 
 func New(root string, db metadata.DB, logger *zerolog.Logger, agentOpts ...labagent.LabagentOption) (p2plab.NodeProvider, error) {
@@ -63,11 +66,11 @@ func New(root string, db metadata.DB, logger *zerolog.Logger, agentOpts ...labag
 		}
 
 		for _, node := range nodes {
-			n, err := p.newNode(node.ID, node.AgentPort, node.AppPort)
+			n, err := p.newNode(node.ID, node.Group, node.AgentPort, node.AppPort)
 			if err != nil {
 				return nil, err
 			}
-			p.nodes[node.ID] = append(p.nodes[node.ID], n)
+			p.nodes[cluster.ID] = append(p.nodes[cluster.ID], n)
 		}
 	}
 
@@ -78,54 +81,76 @@ func (p *provider) CreateNodeGroup(ctx context.Context, id string, cdef metadata
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	numPorts := 0
-	for _, group := range cdef.Groups {
-		numPorts += group.Size
+	var ns []metadata.Node
+	for i, group := range cdef.Groups {
+		gns, err := p.createNodes(id, i, group, group.Size)
+		if err != nil {
+			return nil, err
+		}
+		ns = append(ns, gns...)
 	}
 
-	freePorts, err := freeport.GetFreePorts(numPorts * 2)
-	if err != nil {
-		return nil, err
+	return &p2plab.NodeGroup{
+		ID:         id,
+		Definition: cdef,
+		Nodes:      ns,
+	}, nil
+}
+
+func (p *provider) ResizeNodeGroup(ctx context.Context, ng *p2plab.NodeGroup, cdef metadata.ClusterDefinition) (*p2plab.NodeGroup, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	nodesByGroup := make(map[int][]metadata.Node)
+	for _, n := range ng.Nodes {
+		nodesByGroup[n.Group] = append(nodesByGroup[n.Group], n)
 	}
 
-	var (
-		ns        []metadata.Node
-		portIndex = 0
-	)
-	for _, group := range cdef.Groups {
-		for i := 0; i < group.Size; i++ {
-			agentPort, appPort := freePorts[portIndex], freePorts[portIndex+1]
-			portIndex += 2
+	var ns []metadata.Node
+	for i, group := range cdef.Groups {
+		gns := nodesByGroup[i]
+		delete(nodesByGroup, i)
+
+		// Node IDs are xids, so sorting by ID releases the nodes created last.
+		sort.SliceStable(gns, func(i, j int) bool {
+			return gns[i].ID < gns[j].ID
+		})
 
-			id := xid.New().String()
-			n, err := p.newNode(id, agentPort, appPort)
+		if len(gns) > group.Size {
+			err := p.closeNodes(ng.ID, gns[group.Size:])
 			if err != nil {
 				return nil, err
 			}
-			p.nodes[id] = append(p.nodes[id], n)
+			gns = gns[:group.Size]
+		} else if len(gns) < group.Size {
+			created, err := p.createNodes(ng.ID, i, group, group.Size-len(gns))
+			if err != nil {
+				return nil, err
+			}
+			gns = append(gns, created...)
+		}
+		ns = append(ns, gns...)
+	}
 
-			ns = append(ns, metadata.Node{
-				ID:        n.ID,
-				Address:   "127.0.0.1",
-				AgentPort: n.AgentPort,
-				AppPort:   n.AppPort,
-				Peer:      *group.Peer,
-				Labels: append([]string{
-					n.ID,
-					group.InstanceType,
-					group.Region,
-				}, group.Labels...),
-			})
+	// Release the nodes of groups removed from the definition.
+	for _, gns := range nodesByGroup {
+		err := p.closeNodes(ng.ID, gns)
+		if err != nil {
+			return nil, err
 		}
 	}
 
 	return &p2plab.NodeGroup{
-		ID:    id,
-		Nodes: ns,
+		ID:         ng.ID,
+		Definition: cdef,
+		Nodes:      ns,
 	}, nil
 }
 
 func (p *provider) DestroyNodeGroup(ctx context.Context, ng *p2plab.NodeGroup) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	for _, n := range p.nodes[ng.ID] {
 		err := n.Close()
 		if err != nil {
@@ -138,15 +163,103 @@ func (p *provider) DestroyNodeGroup(ctx context.Context, ng *p2plab.NodeGroup) e
 	return nil
 }
 
+func (p *provider) ListNodeGroups(ctx context.Context) ([]*p2plab.NodeGroup, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var ngs []*p2plab.NodeGroup
+	for id, nodes := range p.nodes {
+		ng := &p2plab.NodeGroup{
+			ID:    id,
+			Nodes: []metadata.Node{},
+		}
+		for _, n := range nodes {
+			ng.Nodes = append(ng.Nodes, metadata.Node{
+				ID:        n.ID,
+				Address:   "127.0.0.1",
+				AgentPort: n.AgentPort,
+				AppPort:   n.AppPort,
+				Group:     n.Group,
+			})
+		}
+		ngs = append(ngs, ng)
+	}
+
+	return ngs, nil
+}
+
+// createNodes starts count nodes for the i-th group of a cluster definition.
+func (p *provider) createNodes(cluster string, i int, group metadata.ClusterGroup, count int) ([]metadata.Node, error) {
+	// Nodes already share this host, so every node gets its own labagent
+	// regardless of the group's peers per instance.
+	freePorts, err := freeport.GetFreePorts(count * 2)
+	if err != nil {
+		return nil, err
+	}
+
+	var ns []metadata.Node
+	for j := 0; j < count; j++ {
+		agentPort, appPort := freePorts[2*j], freePorts[2*j+1]
+
+		id := xid.New().String()
+		n, err := p.newNode(id, i, agentPort, appPort)
+		if err != nil {
+			return nil, err
+		}
+		p.nodes[cluster] = append(p.nodes[cluster], n)
+
+		ns = append(ns, metadata.Node{
+			ID:        n.ID,
+			Address:   "127.0.0.1",
+			AgentPort: n.AgentPort,
+			AppPort:   n.AppPort,
+			Group:     i,
+			Peer:      *group.Peer,
+			Labels: append([]string{
+				n.ID,
+				group.InstanceType,
+				group.Region,
+			}, group.Labels...),
+		})
+	}
+
+	return ns, nil
+}
+
+func (p *provider) closeNodes(cluster string, ns []metadata.Node) error {
+	closeSet := make(map[string]struct{})
+	for _, n := range ns {
+		closeSet[n.ID] = struct{}{}
+	}
+
+	var nodes []*node
+	for _, n := range p.nodes[cluster] {
+		if _, ok := closeSet[n.ID]; !ok {
+			nodes = append(nodes, n)
+			continue
+		}
+
+		err := n.Close()
+		if err != nil {
+			p.logger.Error().Err(err).Str("node.id", n.ID).Msg("error encountered while releasing node")
+			return err
+		}
+	}
+	p.nodes[cluster] = nodes
+
+	return nil
+}
+
 type node struct {
 	ID        string
+	Group     int
 	AgentPort int
 	AppPort   int
 	LabAgent  *labagent.LabAgent
 	cancel    context.CancelFunc
 }
 
-func (p *provider) newNode(id string, agentPort, appPort int) (*node, error) {
+func (p *provider) newNode(id string, group, agentPort, appPort int) (*node, error) {
 	agentRoot := filepath.Join(p.root, id, "labagent")
 	agentAddr := fmt.Sprintf(":%d", agentPort)
 	err := os.MkdirAll(agentRoot, 0711)
@@ -176,6 +289,7 @@ func (p *provider) newNode(id string, agentPort, appPort int) (*node, error) {
 
 	return &node{
 		ID:        id,
+		Group:     group,
 		AgentPort: agentPort,
 		AppPort:   appPort,
 		LabAgent:  la,
@@ -0,0 +1,105 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package inventory provides nodes from a fixed inventory of hosts that are
+// already running labagent, leasing them to clusters until they are
+// destroyed.
+package inventory
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/providers/terraform"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Inventory is a set of hosts running labagent. It is read from YAML or JSON.
+type Inventory struct {
+	Hosts []Host `yaml:"hosts"`
+}
+
+// Host is a machine running labagent.
+type Host struct {
+	// Name identifies the host in node labels. Defaults to its address.
+	Name string `yaml:"name"`
+
+	Address string `yaml:"address"`
+
+	// AgentPort is the port labagent listens on. Defaults to 7002.
+	AgentPort int `yaml:"agentPort"`
+
+	// AppPort is the port of the host's first labapp instance. Defaults to
+	// 7003.
+	AppPort int `yaml:"appPort"`
+
+	// InstanceType and Region restrict the host to cluster groups of the same
+	// instance type and region. Empty values match any group.
+	InstanceType string `yaml:"instanceType"`
+	Region       string `yaml:"region"`
+
+	Labels []string `yaml:"labels"`
+}
+
+func (h Host) key() string {
+	return fmt.Sprintf("%s:%d", h.Address, h.AgentPort)
+}
+
+func (h Host) matches(instanceType, region string) bool {
+	return (h.InstanceType == "" || h.InstanceType == instanceType) &&
+		(h.Region == "" || h.Region == region)
+}
+
+// LoadInventory reads an inventory and fills in the defaults of its hosts.
+func LoadInventory(path string) (Inventory, error) {
+	var inventory Inventory
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return inventory, err
+	}
+
+	err = yaml.UnmarshalStrict(content, &inventory)
+	if err != nil {
+		return inventory, errors.Wrapf(errdefs.ErrInvalidArgument, "invalid inventory %q: %s", path, err)
+	}
+
+	keys := make(map[string]struct{})
+	for i, host := range inventory.Hosts {
+		if host.Address == "" {
+			return inventory, errors.Wrapf(errdefs.ErrInvalidArgument, "inventory host %d has no address", i)
+		}
+		if host.Name == "" {
+			host.Name = host.Address
+		}
+		if host.AgentPort == 0 {
+			host.AgentPort = terraform.DefaultAgentPort
+		}
+		if host.AppPort == 0 {
+			host.AppPort = terraform.DefaultAppPort
+		}
+
+		_, ok := keys[host.key()]
+		if ok {
+			return inventory, errors.Wrapf(errdefs.ErrInvalidArgument, "inventory host %q is listed more than once", host.key())
+		}
+		keys[host.key()] = struct{}{}
+
+		inventory.Hosts[i] = host
+	}
+
+	return inventory, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inventory
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+const testInventory = `
+hosts:
+- address: 10.0.0.1
+  labels: [rack1]
+- name: box2
+  address: 10.0.0.2
+  agentPort: 8002
+  appPort: 8003
+- address: 10.0.0.3
+  region: eu-west-1
+`
+
+// newTestProvider returns a provider leasing the hosts of testInventory, and a
+// function to clean it up.
+func newTestProvider(t *testing.T) (p2plab.NodeProvider, func()) {
+	dir, err := ioutil.TempDir("", "inventory")
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "inventory.yaml")
+	err = ioutil.WriteFile(path, []byte(testInventory), 0644)
+	require.NoError(t, err)
+
+	db, err := metadata.NewDB(filepath.Join(dir, "db"))
+	require.NoError(t, err)
+
+	logger := zerolog.Nop()
+	p, err := New(db, &logger, InventoryProviderSettings{Path: path})
+	require.NoError(t, err)
+
+	return p, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestLoadInventory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inventory")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "inventory.yaml")
+	err = ioutil.WriteFile(path, []byte(testInventory), 0644)
+	require.NoError(t, err)
+
+	inventory, err := LoadInventory(path)
+	require.NoError(t, err)
+	require.Len(t, inventory.Hosts, 3)
+	require.Equal(t, Host{
+		Name:      "10.0.0.1",
+		Address:   "10.0.0.1",
+		AgentPort: 7002,
+		AppPort:   7003,
+		Labels:    []string{"rack1"},
+	}, inventory.Hosts[0])
+	require.Equal(t, "10.0.0.2:8002", inventory.Hosts[1].key())
+
+	err = ioutil.WriteFile(path, []byte(`{"hosts": [{"address": "10.0.0.1"}, {"address": "10.0.0.1"}]}`), 0644)
+	require.NoError(t, err)
+
+	_, err = LoadInventory(path)
+	require.True(t, errdefs.IsInvalidArgument(err))
+}
+
+func TestLeaseHosts(t *testing.T) {
+	p, cleanup := newTestProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	ng, err := p.CreateNodeGroup(ctx, "a", metadata.ClusterDefinition{
+		Groups: []metadata.ClusterGroup{
+			{Size: 3, Region: "us-west-2", PeersPerInstance: 2},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, ng.Nodes, 3)
+	require.Equal(t, "10.0.0.1", ng.Nodes[1].Address)
+	require.Equal(t, 7004, ng.Nodes[1].AppPort)
+	require.Equal(t, 1, ng.Nodes[1].Instance)
+	require.Equal(t, "10.0.0.2", ng.Nodes[2].Address)
+
+	_, err = p.CreateNodeGroup(ctx, "b", metadata.ClusterDefinition{
+		Groups: []metadata.ClusterGroup{
+			{Size: 1, Region: "us-west-2"},
+		},
+	})
+	require.True(t, errdefs.IsUnavailable(err))
+
+	err = p.DestroyNodeGroup(ctx, ng)
+	require.NoError(t, err)
+
+	ng, err = p.CreateNodeGroup(ctx, "b", metadata.ClusterDefinition{
+		Groups: []metadata.ClusterGroup{
+			{Size: 1, Region: "us-west-2"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.1", ng.Nodes[0].Address)
+}
+
+func TestResizeNodeGroup(t *testing.T) {
+	p, cleanup := newTestProvider(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	cdef := metadata.ClusterDefinition{
+		Groups: []metadata.ClusterGroup{
+			{Size: 1, Region: "us-west-2", PeersPerInstance: 2},
+		},
+	}
+	ng, err := p.CreateNodeGroup(ctx, "a", cdef)
+	require.NoError(t, err)
+	require.Len(t, ng.Nodes, 1)
+	first := ng.Nodes[0]
+
+	cdef.Groups[0].Size = 3
+	ng, err = p.ResizeNodeGroup(ctx, ng, cdef)
+	require.NoError(t, err)
+	require.Len(t, ng.Nodes, 3)
+	require.Equal(t, first, ng.Nodes[0])
+	require.Equal(t, "10.0.0.1", ng.Nodes[1].Address)
+	require.Equal(t, "10.0.0.2", ng.Nodes[2].Address)
+
+	cdef.Groups = append(cdef.Groups, metadata.ClusterGroup{Size: 1, Region: "us-west-2"})
+	_, err = p.ResizeNodeGroup(ctx, ng, cdef)
+	require.True(t, errdefs.IsUnavailable(err))
+
+	cdef.Groups = cdef.Groups[:1]
+	cdef.Groups[0].Size = 2
+	ng, err = p.ResizeNodeGroup(ctx, ng, cdef)
+	require.NoError(t, err)
+	require.Len(t, ng.Nodes, 2)
+	require.Equal(t, first, ng.Nodes[0])
+
+	// The released host can be leased by another cluster.
+	_, err = p.CreateNodeGroup(ctx, "b", metadata.ClusterDefinition{
+		Groups: []metadata.ClusterGroup{
+			{Size: 1, Region: "us-west-2"},
+		},
+	})
+	require.NoError(t, err)
+}
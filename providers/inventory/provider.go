@@ -0,0 +1,270 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inventory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+	"github.com/rs/zerolog"
+)
+
+type InventoryProviderSettings struct {
+	// Path is the path to the inventory file.
+	Path string
+}
+
+type provider struct {
+	path   string
+	logger *zerolog.Logger
+
+	mu sync.Mutex
+	// leases maps the keys of leased hosts to the ID of their cluster.
+	leases map[string]string
+	// nodes maps cluster IDs to the nodes on their leased hosts.
+	nodes map[string][]metadata.Node
+}
+
+func New(db metadata.DB, logger *zerolog.Logger, settings InventoryProviderSettings) (p2plab.NodeProvider, error) {
+	if settings.Path == "" {
+		return nil, errors.Wrap(errdefs.ErrInvalidArgument, "inventory provider requires an inventory path")
+	}
+
+	inventory, err := LoadInventory(settings.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &provider{
+		path:   settings.Path,
+		logger: logger,
+		leases: make(map[string]string),
+		nodes:  make(map[string][]metadata.Node),
+	}
+
+	inventoryKeys := make(map[string]struct{})
+	for _, host := range inventory.Hosts {
+		inventoryKeys[host.key()] = struct{}{}
+	}
+
+	// Restore leases from the nodes of existing clusters.
+	ctx := context.Background()
+	clusters, err := db.ListClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cluster := range clusters {
+		nodes, err := db.ListNodes(ctx, cluster.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, node := range nodes {
+			key := Host{Address: node.Address, AgentPort: node.AgentPort}.key()
+			if _, ok := inventoryKeys[key]; ok {
+				p.leases[key] = cluster.ID
+				p.nodes[cluster.ID] = append(p.nodes[cluster.ID], node)
+			}
+		}
+	}
+
+	return p, nil
+}
+
+func (p *provider) CreateNodeGroup(ctx context.Context, id string, cdef metadata.ClusterDefinition) (*p2plab.NodeGroup, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ns, err := p.allocate(id, nil, cdef)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p2plab.NodeGroup{
+		ID:         id,
+		Definition: cdef,
+		Nodes:      ns,
+	}, nil
+}
+
+func (p *provider) ResizeNodeGroup(ctx context.Context, ng *p2plab.NodeGroup, cdef metadata.ClusterDefinition) (*p2plab.NodeGroup, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ns, err := p.allocate(ng.ID, ng.Nodes, cdef)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p2plab.NodeGroup{
+		ID:         ng.ID,
+		Definition: cdef,
+		Nodes:      ns,
+	}, nil
+}
+
+// slot is a labapp instance on a host.
+type slot struct {
+	key      string
+	instance int
+}
+
+// allocate leases hosts for the groups of a cluster definition and returns
+// the nodes on them. The hosts and nodes of a group in existing are kept
+// while the group still needs them, and hosts no longer needed are returned
+// to the free pool.
+func (p *provider) allocate(id string, existing []metadata.Node, cdef metadata.ClusterDefinition) ([]metadata.Node, error) {
+	// The inventory is read again so that hosts can be added without
+	// restarting labd.
+	inventory, err := LoadInventory(p.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		nodeBySlot  = make(map[slot]metadata.Node)
+		groupByHost = make(map[string]int)
+	)
+	for _, n := range existing {
+		key := Host{Address: n.Address, AgentPort: n.AgentPort}.key()
+		nodeBySlot[slot{key, n.Instance}] = n
+		groupByHost[key] = n.Group
+	}
+
+	var (
+		ns     []metadata.Node
+		leased = make(map[string]struct{})
+	)
+	for i, group := range cdef.Groups {
+		peersPerInstance := group.PeersPerInstance
+		if peersPerInstance < 1 {
+			peersPerInstance = 1
+		}
+
+		// Hosts already serving the group are preferred over free ones.
+		var hosts []Host
+		for _, host := range inventory.Hosts {
+			g, ok := groupByHost[host.key()]
+			if ok && g == i && len(hosts) < group.Instances() {
+				hosts = append(hosts, host)
+				leased[host.key()] = struct{}{}
+			}
+		}
+
+		for _, host := range inventory.Hosts {
+			if len(hosts) == group.Instances() {
+				break
+			}
+
+			_, isLeased := p.leases[host.key()]
+			_, isPicked := leased[host.key()]
+			if isLeased || isPicked || !host.matches(group.InstanceType, group.Region) {
+				continue
+			}
+
+			hosts = append(hosts, host)
+			leased[host.key()] = struct{}{}
+		}
+
+		if len(hosts) < group.Instances() {
+			return nil, errors.Wrapf(errdefs.ErrUnavailable, "only %d of %d hosts of instance type %q in %q are free", len(hosts), group.Instances(), group.InstanceType, group.Region)
+		}
+
+		remaining := group.Size
+		for _, host := range hosts {
+			for j := 0; j < peersPerInstance && remaining > 0; j++ {
+				n, ok := nodeBySlot[slot{host.key(), j}]
+				if !ok {
+					n = newNode(host, i, j, group)
+				}
+				ns = append(ns, n)
+				remaining--
+			}
+		}
+	}
+
+	for key, cluster := range p.leases {
+		if cluster == id {
+			delete(p.leases, key)
+		}
+	}
+
+	for key := range leased {
+		p.leases[key] = id
+	}
+	p.nodes[id] = ns
+
+	return ns, nil
+}
+
+// newNode returns the j-th node on a host for the i-th group of a cluster.
+func newNode(host Host, i, j int, group metadata.ClusterGroup) metadata.Node {
+	id := xid.New().String()
+	n := metadata.Node{
+		ID:        id,
+		Address:   host.Address,
+		AgentPort: host.AgentPort,
+		AppPort:   host.AppPort + j,
+		Instance:  j,
+		Group:     i,
+		Labels: append(append([]string{
+			id,
+			host.Name,
+			group.InstanceType,
+			group.Region,
+		}, host.Labels...), group.Labels...),
+	}
+	if group.Peer != nil {
+		n.Peer = *group.Peer
+	}
+	return n
+}
+
+// DestroyNodeGroup returns the group's hosts to the free pool. Their labapps
+// keep running until the hosts are leased and updated again.
+func (p *provider) DestroyNodeGroup(ctx context.Context, ng *p2plab.NodeGroup) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, id := range p.leases {
+		if id == ng.ID {
+			delete(p.leases, key)
+		}
+	}
+	delete(p.nodes, ng.ID)
+
+	return nil
+}
+
+func (p *provider) ListNodeGroups(ctx context.Context) ([]*p2plab.NodeGroup, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var ngs []*p2plab.NodeGroup
+	for id, ns := range p.nodes {
+		ngs = append(ngs, &p2plab.NodeGroup{
+			ID:    id,
+			Nodes: append([]metadata.Node{}, ns...),
+		})
+	}
+
+	return ngs, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package netns provides nodes on a single Linux host, each in its own
+// network namespace. Namespaces are connected to a bridge per cluster, and
+// traffic between regions is delayed with netem.
+package netns
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"time"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/pkg/errors"
+)
+
+var (
+	// FirstSubnet is the second octet of the first cluster's 10.x.0.0/16
+	// subnet. Every cluster gets the next free subnet.
+	FirstSubnet = 100
+
+	// InterRegionDelay is the delay added to packets sent between nodes in
+	// different regions.
+	InterRegionDelay = 40 * time.Millisecond
+)
+
+const (
+	// Each region has a /20 of the cluster's subnet, so that packets can be
+	// shaped by the region they are sent to. The last /20 is left for the
+	// bridge's address.
+	maxRegions         = 15
+	blocksPerRegion    = 16
+	instancesPerBlock  = 254
+	instancesPerRegion = blocksPerRegion * instancesPerBlock
+)
+
+// instanceIP returns the address of the i-th namespace of a region.
+func instanceIP(subnet, region, i int) (net.IP, error) {
+	if region >= maxRegions {
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "at most %d regions are supported", maxRegions)
+	}
+	if i >= instancesPerRegion {
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "at most %d instances per region are supported", instancesPerRegion)
+	}
+
+	return net.IPv4(10, byte(subnet), byte(region*blocksPerRegion+i/instancesPerBlock), byte(i%instancesPerBlock+1)).To4(), nil
+}
+
+// parseInstanceIP returns the subnet and region of an address returned by
+// instanceIP.
+func parseInstanceIP(addr string) (subnet, region int, ok bool) {
+	ip := net.ParseIP(addr).To4()
+	if ip == nil || ip[0] != 10 || int(ip[1]) < FirstSubnet || int(ip[2])/blocksPerRegion >= maxRegions {
+		return 0, 0, false
+	}
+
+	return int(ip[1]), int(ip[2]) / blocksPerRegion, true
+}
+
+func regionCIDR(subnet, region int) string {
+	return fmt.Sprintf("10.%d.%d.0/20", subnet, region*blocksPerRegion)
+}
+
+func gateway(subnet int) string {
+	return fmt.Sprintf("10.%d.255.254", subnet)
+}
+
+// Interface names are limited to 15 characters, so names are derived from the
+// address rather than node IDs.
+func bridgeName(subnet int) string {
+	return fmt.Sprintf("p2pbr%02x", subnet)
+}
+
+func instanceName(ip net.IP) string {
+	ip = ip.To4()
+	return fmt.Sprintf("%02x%02x%02x", ip[1], ip[2], ip[3])
+}
+
+func namespaceName(name string) string {
+	return fmt.Sprintf("p2plab-%s", name)
+}
+
+func namespacePath(name string) string {
+	return filepath.Join("/var/run/netns", namespaceName(name))
+}
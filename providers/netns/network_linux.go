@@ -0,0 +1,136 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package netns
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+func createBridge(ctx context.Context, subnet int) error {
+	br := bridgeName(subnet)
+
+	// Remove a bridge left behind by a previous labd.
+	deleteLink(ctx, br)
+
+	for _, args := range [][]string{
+		{"link", "add", "name", br, "type", "bridge"},
+		{"addr", "add", fmt.Sprintf("%s/16", gateway(subnet)), "dev", br},
+		{"link", "set", br, "up"},
+	} {
+		err := ip(ctx, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createNamespace creates a network namespace with an interface connected to
+// the subnet's bridge.
+func createNamespace(ctx context.Context, subnet int, addr net.IP, name string) error {
+	var (
+		ns       = namespaceName(name)
+		hostVeth = fmt.Sprintf("p2p%s", name)
+		nsVeth   = fmt.Sprintf("p2pn%s", name)
+	)
+
+	// Remove a namespace left behind by a previous labd. Deleting the
+	// namespace also deletes its end of the veth pair and therefore the pair.
+	deleteNamespace(ctx, name)
+	deleteLink(ctx, hostVeth)
+
+	for _, args := range [][]string{
+		{"netns", "add", ns},
+		{"link", "add", hostVeth, "type", "veth", "peer", "name", nsVeth},
+		{"link", "set", hostVeth, "master", bridgeName(subnet)},
+		{"link", "set", hostVeth, "up"},
+		{"link", "set", nsVeth, "netns", ns},
+		{"-n", ns, "link", "set", nsVeth, "name", "eth0"},
+		{"-n", ns, "addr", "add", fmt.Sprintf("%s/16", addr), "dev", "eth0"},
+		{"-n", ns, "link", "set", "eth0", "up"},
+		{"-n", ns, "link", "set", "lo", "up"},
+		{"-n", ns, "route", "add", "default", "via", gateway(subnet)},
+	} {
+		err := ip(ctx, args...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// shapeNamespace delays packets sent from a namespace to other regions.
+func shapeNamespace(ctx context.Context, name string, subnet, region int, regions []int) error {
+	ns := namespaceName(name)
+	delay := fmt.Sprintf("%dus", InterRegionDelay.Microseconds())
+
+	// Packets go through the first band unless they match a filter for another
+	// region, which sends them through the delayed second band.
+	cmds := [][]string{
+		append([]string{"qdisc", "add", "dev", "eth0", "root", "handle", "1:", "prio", "bands", "2", "priomap"}, strings.Fields(strings.Repeat("0 ", 16))...),
+		{"qdisc", "add", "dev", "eth0", "parent", "1:2", "handle", "20:", "netem", "delay", delay},
+	}
+	for _, r := range regions {
+		if r == region {
+			continue
+		}
+
+		cmds = append(cmds, []string{"filter", "add", "dev", "eth0", "parent", "1:", "protocol", "ip", "prio", "1", "u32", "match", "ip", "dst", regionCIDR(subnet, r), "flowid", "1:2"})
+	}
+
+	for _, args := range cmds {
+		err := run(ctx, "ip", append([]string{"netns", "exec", ns, "tc"}, args...)...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deleteNamespace(ctx context.Context, name string) error {
+	return ip(ctx, "netns", "delete", namespaceName(name))
+}
+
+func deleteLink(ctx context.Context, link string) error {
+	return ip(ctx, "link", "delete", link)
+}
+
+func ip(ctx context.Context, args ...string) error {
+	return run(ctx, "ip", args...)
+}
+
+func run(ctx context.Context, name string, args ...string) error {
+	stderr := new(bytes.Buffer)
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	if err != nil {
+		return errors.Wrapf(err, "%s %s: %s", name, strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package netns
+
+import (
+	"net"
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstanceIP(t *testing.T) {
+	for _, test := range []struct {
+		subnet, region, i int
+		expected          string
+	}{
+		{FirstSubnet, 0, 0, "10.100.0.1"},
+		{FirstSubnet, 0, instancesPerBlock - 1, "10.100.0.254"},
+		{FirstSubnet, 0, instancesPerBlock, "10.100.1.1"},
+		{FirstSubnet, 1, 0, "10.100.16.1"},
+		{FirstSubnet + 1, 2, 3, "10.101.32.4"},
+		{FirstSubnet, maxRegions - 1, instancesPerRegion - 1, "10.100.239.254"},
+	} {
+		ip, err := instanceIP(test.subnet, test.region, test.i)
+		require.NoError(t, err)
+		require.Equal(t, test.expected, ip.String())
+
+		// Every address is within its region's block, which packets are shaped
+		// by, and can be parsed back into its subnet and region.
+		_, block, err := net.ParseCIDR(regionCIDR(test.subnet, test.region))
+		require.NoError(t, err)
+		require.True(t, block.Contains(ip), "%s not in %s", ip, block)
+
+		subnet, region, ok := parseInstanceIP(ip.String())
+		require.True(t, ok)
+		require.Equal(t, test.subnet, subnet)
+		require.Equal(t, test.region, region)
+	}
+}
+
+func TestInstanceIPExhausted(t *testing.T) {
+	_, err := instanceIP(FirstSubnet, 0, instancesPerRegion)
+	require.True(t, errdefs.IsInvalidArgument(err))
+
+	_, err = instanceIP(FirstSubnet, maxRegions, 0)
+	require.True(t, errdefs.IsInvalidArgument(err))
+}
+
+func TestInstanceIPUnique(t *testing.T) {
+	names := make(map[string]struct{})
+	for region := 0; region < maxRegions; region++ {
+		for i := 0; i < instancesPerRegion; i++ {
+			ip, err := instanceIP(FirstSubnet, region, i)
+			require.NoError(t, err)
+			require.NotEqual(t, gateway(FirstSubnet), ip.String())
+
+			name := instanceName(ip)
+			_, ok := names[name]
+			require.False(t, ok, "duplicate instance name %q", name)
+			names[name] = struct{}{}
+		}
+	}
+}
+
+func TestParseInstanceIP(t *testing.T) {
+	for _, addr := range []string{
+		"",
+		"not-an-ip",
+		"192.168.0.1",
+		"10.99.0.1",
+		gateway(FirstSubnet),
+		"fd00::1",
+	} {
+		_, _, ok := parseInstanceIP(addr)
+		require.False(t, ok, addr)
+	}
+}
@@ -0,0 +1,558 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package netns
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/labagent"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/providers/terraform"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+	"github.com/rs/zerolog"
+)
+
+type provider struct {
+	root      string
+	clusters  map[string]*cluster
+	logger    *zerolog.Logger
+	agentOpts []labagent.LabagentOption
+	mu        sync.Mutex
+}
+
+type cluster struct {
+	subnet    int
+	instances []*instance
+	nodes     []metadata.Node
+}
+
+// instance is a network namespace with a labagent serving from it.
+type instance struct {
+	name     string
+	labAgent *labagent.LabAgent
+	cancel   context.CancelFunc
+}
+
+func New(root string, db metadata.DB, logger *zerolog.Logger, agentOpts ...labagent.LabagentOption) (p2plab.NodeProvider, error) {
+	_, err := exec.LookPath("ip")
+	if err != nil {
+		return nil, errors.Wrap(err, "netns provider requires iproute2")
+	}
+
+	err = os.MkdirAll(root, 0711)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &provider{
+		root:      root,
+		clusters:  make(map[string]*cluster),
+		logger:    logger,
+		agentOpts: agentOpts,
+	}
+
+	ctx := context.Background()
+	clusters, err := db.ListClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Recreate the namespaces of existing clusters from their nodes'
+	// addresses, which encode the cluster's subnet and the nodes' regions.
+	for _, cluster := range clusters {
+		nodes, err := db.ListNodes(ctx, cluster.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		addrSet := make(map[string]struct{})
+		for _, node := range nodes {
+			addrSet[node.Address] = struct{}{}
+		}
+
+		var addrs []string
+		for addr := range addrSet {
+			addrs = append(addrs, addr)
+		}
+		sort.Strings(addrs)
+
+		err = p.restoreCluster(ctx, cluster.ID, addrs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to restore cluster %q", cluster.ID)
+		}
+
+		if c, ok := p.clusters[cluster.ID]; ok {
+			c.nodes = nodes
+		}
+	}
+
+	return p, nil
+}
+
+func (p *provider) CreateNodeGroup(ctx context.Context, id string, cdef metadata.ClusterDefinition) (*p2plab.NodeGroup, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.clusters[id]; ok {
+		return nil, errors.Wrapf(errdefs.ErrAlreadyExists, "node group %q", id)
+	}
+
+	c, err := p.newCluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	regionIndex, regions := indexRegions(cdef)
+	ns, err := p.allocate(ctx, c, regionIndex, regions, nil, cdef)
+	if err != nil {
+		p.destroyCluster(ctx, c)
+		return nil, err
+	}
+	c.nodes = ns
+	p.clusters[id] = c
+
+	return &p2plab.NodeGroup{
+		ID:         id,
+		Definition: cdef,
+		Nodes:      ns,
+	}, nil
+}
+
+func (p *provider) ResizeNodeGroup(ctx context.Context, ng *p2plab.NodeGroup, cdef metadata.ClusterDefinition) (*p2plab.NodeGroup, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c, ok := p.clusters[ng.ID]
+	if !ok {
+		return nil, errors.Wrapf(errdefs.ErrNotFound, "node group %q", ng.ID)
+	}
+
+	// Existing namespaces are shaped for the regions the cluster was created
+	// with, so those regions keep their subnets and no region can be added.
+	regionIndex, regions := indexRegions(ng.Definition)
+	for _, group := range cdef.Groups {
+		if _, ok := regionIndex[group.Region]; !ok {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "cannot add region %q to node group %q", group.Region, ng.ID)
+		}
+	}
+
+	ns, err := p.allocate(ctx, c, regionIndex, regions, ng.Nodes, cdef)
+	if err != nil {
+		return nil, err
+	}
+	c.nodes = ns
+
+	return &p2plab.NodeGroup{
+		ID:         ng.ID,
+		Definition: cdef,
+		Nodes:      ns,
+	}, nil
+}
+
+func (p *provider) DestroyNodeGroup(ctx context.Context, ng *p2plab.NodeGroup) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	c, ok := p.clusters[ng.ID]
+	if !ok {
+		return nil
+	}
+
+	err := p.destroyCluster(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	delete(p.clusters, ng.ID)
+	return nil
+}
+
+func (p *provider) ListNodeGroups(ctx context.Context) ([]*p2plab.NodeGroup, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var ngs []*p2plab.NodeGroup
+	for id, c := range p.clusters {
+		ngs = append(ngs, &p2plab.NodeGroup{
+			ID:    id,
+			Nodes: append([]metadata.Node{}, c.nodes...),
+		})
+	}
+
+	return ngs, nil
+}
+
+// indexRegions assigns each region of a cluster definition an index in
+// alphabetical order.
+func indexRegions(cdef metadata.ClusterDefinition) (regionIndex map[string]int, regions []int) {
+	regionIndex = make(map[string]int)
+	for _, group := range cdef.Groups {
+		regionIndex[group.Region] = 0
+	}
+
+	var regionNames []string
+	for region := range regionIndex {
+		regionNames = append(regionNames, region)
+	}
+	sort.Strings(regionNames)
+
+	for i, region := range regionNames {
+		regionIndex[region] = i
+		regions = append(regions, i)
+	}
+
+	return regionIndex, regions
+}
+
+// slot is a labapp instance in a namespace.
+type slot struct {
+	addr     string
+	instance int
+}
+
+// allocate starts the namespaces needed for the groups of a cluster
+// definition and returns the nodes in them. The namespaces and nodes of a
+// group in existing are kept while the group still needs them, and
+// namespaces no longer needed are removed.
+func (p *provider) allocate(ctx context.Context, c *cluster, regionIndex map[string]int, regions []int, existing []metadata.Node, cdef metadata.ClusterDefinition) ([]metadata.Node, error) {
+	var (
+		nodeBySlot   = make(map[slot]metadata.Node)
+		addrSet      = make(map[string]struct{})
+		addrsByGroup = make(map[int][]net.IP)
+	)
+	for _, n := range existing {
+		nodeBySlot[slot{n.Address, n.Instance}] = n
+		if _, ok := addrSet[n.Address]; !ok {
+			addrSet[n.Address] = struct{}{}
+			addrsByGroup[n.Group] = append(addrsByGroup[n.Group], net.ParseIP(n.Address).To4())
+		}
+	}
+
+	var (
+		ns      []metadata.Node
+		started []string
+		keep    = make(map[string]struct{})
+	)
+	for i, group := range cdef.Groups {
+		peersPerInstance := group.PeersPerInstance
+		if peersPerInstance < 1 {
+			peersPerInstance = 1
+		}
+
+		// Namespaces with the highest addresses are removed first.
+		addrs := addrsByGroup[i]
+		sort.Slice(addrs, func(a, b int) bool {
+			return bytes.Compare(addrs[a], addrs[b]) < 0
+		})
+		if len(addrs) > group.Instances() {
+			addrs = addrs[:group.Instances()]
+		}
+
+		region := regionIndex[group.Region]
+		for len(addrs) < group.Instances() {
+			addr, err := c.nextInstanceIP(region)
+			if err == nil {
+				started = append(started, instanceName(addr))
+				err = p.startInstance(ctx, c, addr, region, regions)
+			}
+			if err != nil {
+				p.stopInstances(ctx, c, started)
+				return nil, err
+			}
+			addrs = append(addrs, addr)
+		}
+
+		remaining := group.Size
+		for _, addr := range addrs {
+			keep[instanceName(addr)] = struct{}{}
+			for j := 0; j < peersPerInstance && remaining > 0; j++ {
+				n, ok := nodeBySlot[slot{addr.String(), j}]
+				if !ok {
+					n = newNode(addr, i, j, group)
+				}
+				ns = append(ns, n)
+				remaining--
+			}
+		}
+	}
+
+	var released []string
+	for _, i := range c.instances {
+		if _, ok := keep[i.name]; !ok {
+			released = append(released, i.name)
+		}
+	}
+
+	err := p.stopInstances(ctx, c, released)
+	if err != nil {
+		return nil, err
+	}
+
+	return ns, nil
+}
+
+// newNode returns the j-th node in a namespace for the i-th group of a
+// cluster.
+func newNode(addr net.IP, i, j int, group metadata.ClusterGroup) metadata.Node {
+	id := xid.New().String()
+	labels := []string{id}
+	if group.PeersPerInstance > 1 {
+		// Nodes sharing a namespace can be selected by its name.
+		labels = append(labels, namespaceName(instanceName(addr)))
+	}
+
+	n := metadata.Node{
+		ID:        id,
+		Address:   addr.String(),
+		AgentPort: terraform.DefaultAgentPort,
+		AppPort:   terraform.DefaultAppPort + j,
+		Instance:  j,
+		Group:     i,
+		Labels: append(append(labels,
+			group.InstanceType,
+			group.Region,
+		), group.Labels...),
+	}
+	if group.Peer != nil {
+		n.Peer = *group.Peer
+	}
+	return n
+}
+
+// nextInstanceIP returns the first address of a region not used by the
+// cluster's namespaces.
+func (c *cluster) nextInstanceIP(region int) (net.IP, error) {
+	used := make(map[string]struct{})
+	for _, i := range c.instances {
+		used[i.name] = struct{}{}
+	}
+
+	for k := 0; ; k++ {
+		addr, err := instanceIP(c.subnet, region, k)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := used[instanceName(addr)]; !ok {
+			return addr, nil
+		}
+	}
+}
+
+// newCluster creates a bridge for a cluster on the first free subnet.
+func (p *provider) newCluster(ctx context.Context) (*cluster, error) {
+	used := make(map[int]struct{})
+	for _, c := range p.clusters {
+		used[c.subnet] = struct{}{}
+	}
+
+	subnet := FirstSubnet
+	for ; subnet < 256; subnet++ {
+		if _, ok := used[subnet]; !ok {
+			break
+		}
+	}
+	if subnet == 256 {
+		return nil, errors.Wrap(errdefs.ErrUnavailable, "no free subnet for cluster")
+	}
+
+	err := createBridge(ctx, subnet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cluster{subnet: subnet}, nil
+}
+
+func (p *provider) restoreCluster(ctx context.Context, id string, addrs []string) error {
+	var (
+		c          *cluster
+		regionSet  = make(map[int]struct{})
+		regionByIP = make(map[string]int)
+	)
+	for _, addr := range addrs {
+		subnet, region, ok := parseInstanceIP(addr)
+		if !ok {
+			p.logger.Debug().Str("cluster", id).Str("addr", addr).Msg("Skipping node not provided by netns")
+			continue
+		}
+
+		if c == nil {
+			c = &cluster{subnet: subnet}
+		}
+		regionSet[region] = struct{}{}
+		regionByIP[addr] = region
+	}
+	if c == nil {
+		return nil
+	}
+
+	var regions []int
+	for region := range regionSet {
+		regions = append(regions, region)
+	}
+	sort.Ints(regions)
+
+	err := createBridge(ctx, c.subnet)
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range addrs {
+		region, ok := regionByIP[addr]
+		if !ok {
+			continue
+		}
+
+		err = p.startInstance(ctx, c, net.ParseIP(addr).To4(), region, regions)
+		if err != nil {
+			p.destroyCluster(ctx, c)
+			return err
+		}
+	}
+
+	p.clusters[id] = c
+	return nil
+}
+
+// startInstance creates a network namespace and serves a labagent from it.
+func (p *provider) startInstance(ctx context.Context, c *cluster, addr net.IP, region int, regions []int) error {
+	name := instanceName(addr)
+	i := &instance{name: name}
+	c.instances = append(c.instances, i)
+
+	err := createNamespace(ctx, c.subnet, addr, name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create namespace for %s", addr)
+	}
+
+	if len(regions) > 1 {
+		err = shapeNamespace(ctx, name, c.subnet, region, regions)
+		if err != nil {
+			return errors.Wrapf(err, "failed to shape namespace for %s", addr)
+		}
+	}
+
+	agentRoot := filepath.Join(p.root, name, "labagent")
+	err = os.MkdirAll(agentRoot, 0711)
+	if err != nil {
+		return err
+	}
+
+	appRoot := filepath.Join(p.root, name, "labapp")
+	err = os.MkdirAll(appRoot, 0711)
+	if err != nil {
+		return err
+	}
+
+	var (
+		agentAddr = fmt.Sprintf(":%d", terraform.DefaultAgentPort)
+		appAddr   = fmt.Sprintf("http://localhost:%d", terraform.DefaultAppPort)
+		opts      = append(append([]labagent.LabagentOption{}, p.agentOpts...), labagent.WithNetworkNamespace(namespacePath(name)))
+	)
+	la, err := labagent.New(agentRoot, agentAddr, appRoot, appAddr, p.logger, opts...)
+	if err != nil {
+		return err
+	}
+	i.labAgent = la
+
+	actx, cancel := context.WithCancel(context.Background())
+	i.cancel = cancel
+	go func() {
+		err := la.Serve(actx)
+		if err != nil {
+			p.logger.Error().Err(err).Str("addr", addr.String()).Msg("serve exited with error")
+		}
+	}()
+
+	return nil
+}
+
+// destroyCluster stops the cluster's labagents and removes its namespaces and
+// bridge, continuing past errors so that as much as possible is cleaned up.
+func (p *provider) destroyCluster(ctx context.Context, c *cluster) error {
+	var names []string
+	for _, i := range c.instances {
+		names = append(names, i.name)
+	}
+
+	errs := []error{
+		p.stopInstances(ctx, c, names),
+		deleteLink(ctx, bridgeName(c.subnet)),
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			p.logger.Error().Err(err).Int("subnet", c.subnet).Msg("error encountered while destroying node group")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stopInstances stops the labagents of the named namespaces and removes them
+// from the cluster, continuing past errors so that as much as possible is
+// cleaned up.
+func (p *provider) stopInstances(ctx context.Context, c *cluster, names []string) error {
+	nameSet := make(map[string]struct{})
+	for _, name := range names {
+		nameSet[name] = struct{}{}
+	}
+
+	var (
+		errs      []error
+		instances []*instance
+	)
+	for _, i := range c.instances {
+		if _, ok := nameSet[i.name]; !ok {
+			instances = append(instances, i)
+			continue
+		}
+
+		if i.labAgent != nil {
+			i.cancel()
+			errs = append(errs, i.labAgent.Close())
+		}
+
+		errs = append(errs,
+			deleteNamespace(ctx, i.name),
+			os.RemoveAll(filepath.Join(p.root, i.name)),
+		)
+	}
+	c.instances = instances
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
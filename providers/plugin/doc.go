@@ -0,0 +1,85 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package plugin provides nodes from an out-of-process provider plugin, so
+// that node groups can be created by schedulers labd does not know about.
+//
+// # Invocation
+//
+// A plugin is an executable invoked once per operation with the operation's
+// method as its last argument, one of create, resize, destroy or list. Any
+// arguments configured for the plugin come before the method. The plugin
+// reads a JSON Request from stdin and writes a JSON Response to stdout.
+// Anything it writes to stderr is logged by labd. Plugins run in a directory
+// of their own under the provider root, where they may keep state between
+// invocations.
+//
+// Plugins must implement create and destroy. A plugin that cannot resize or
+// list node groups responds with an error whose code is unimplemented, in
+// which case resizing fails and every cluster is assumed to have a node group
+// whose nodes cannot be listed.
+//
+// # Messages
+//
+// All keys are camelCase. The create method is given the ID of the node group
+// and the cluster definition to provision it for:
+//
+//	{
+//	  "id": "my-cluster",
+//	  "definition": {
+//	    "groups": [
+//	      {"size": 2, "instanceType": "t2.micro", "region": "us-west-2", "labels": ["slow"]}
+//	    ]
+//	  }
+//	}
+//
+// It responds with the node group created. The nodes of a group must have
+// unique IDs, and each must be reachable at its address by labd on its
+// agentPort and by its peers on its appPort. A node's group is the index of the
+// cluster definition group it was provisioned for, and its instance is the
+// index of its labapp among those supervised by the same labagent:
+//
+//	{
+//	  "nodeGroup": {
+//	    "id": "my-cluster",
+//	    "definition": {"groups": [{"size": 2, "instanceType": "t2.micro", "region": "us-west-2", "labels": ["slow"]}]},
+//	    "nodes": [
+//	      {"id": "node-0", "address": "10.0.0.1", "agentPort": 7002, "appPort": 7003, "group": 0, "peer": {}, "labels": ["node-0"]},
+//	      {"id": "node-1", "address": "10.0.0.2", "agentPort": 7002, "appPort": 7003, "group": 0, "peer": {}, "labels": ["node-1"]}
+//	    ]
+//	  }
+//	}
+//
+// The resize method is given the new cluster definition along with the node
+// group to resize, and responds with the node group resized like create. The
+// destroy method is given the node group to destroy, and responds with an
+// empty object:
+//
+//	{"nodeGroup": {"id": "my-cluster", "definition": {...}, "nodes": [...]}}
+//
+// The list method is given an empty object, and responds with every node group
+// the plugin has. A node group whose nodes are null has nodes the plugin
+// cannot list, whereas an empty list of nodes means it has none:
+//
+//	{"nodeGroups": [{"id": "my-cluster", "definition": {...}, "nodes": null}]}
+//
+// # Errors
+//
+// A failed method responds with an error, and may exit with a non-zero status
+// after writing it. The code is one of already_exists, not_found,
+// invalid_argument, unavailable or unimplemented, and is returned to labd's
+// callers as the error of the same name. Other codes are returned as is:
+//
+//	{"error": {"code": "not_found", "message": "no such node group \"my-cluster\""}}
+package plugin
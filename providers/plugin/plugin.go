@@ -0,0 +1,253 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/Netflix/p2plab/pkg/logutil"
+	"github.com/Netflix/p2plab/pkg/traceutil"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+)
+
+const (
+	MethodCreate  = "create"
+	MethodResize  = "resize"
+	MethodDestroy = "destroy"
+	MethodList    = "list"
+)
+
+const (
+	CodeAlreadyExists   = "already_exists"
+	CodeNotFound        = "not_found"
+	CodeInvalidArgument = "invalid_argument"
+	CodeUnavailable     = "unavailable"
+	CodeUnimplemented   = "unimplemented"
+)
+
+// Request is written to a plugin's stdin.
+type Request struct {
+	// ID is the ID of the node group to create.
+	ID string `json:"id,omitempty"`
+
+	// Definition is the cluster definition to create or resize a node group
+	// for.
+	Definition *ClusterDefinition `json:"definition,omitempty"`
+
+	// NodeGroup is the node group to resize or destroy.
+	NodeGroup *NodeGroup `json:"nodeGroup,omitempty"`
+}
+
+// Response is read from a plugin's stdout.
+type Response struct {
+	// NodeGroup is the node group created or resized.
+	NodeGroup *NodeGroup `json:"nodeGroup,omitempty"`
+
+	// NodeGroups are the node groups listed.
+	NodeGroups []*NodeGroup `json:"nodeGroups,omitempty"`
+
+	Error *Error `json:"error,omitempty"`
+}
+
+// Error is an error returned by a plugin. Its code is mapped to the errdefs
+// error of the same name, and unrecognized codes are returned as is.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Code
+	}
+	return e.Message
+}
+
+func (e *Error) cause() error {
+	var err error
+	switch e.Code {
+	case CodeAlreadyExists:
+		err = errdefs.ErrAlreadyExists
+	case CodeNotFound:
+		err = errdefs.ErrNotFound
+	case CodeInvalidArgument:
+		err = errdefs.ErrInvalidArgument
+	case CodeUnavailable:
+		err = errdefs.ErrUnavailable
+	default:
+		return e
+	}
+
+	if e.Message == "" {
+		return err
+	}
+	return errors.Wrap(err, e.Message)
+}
+
+type PluginSettings struct {
+	// Path is the plugin's executable.
+	Path string
+
+	// Args are passed to the plugin before the method.
+	Args []string
+}
+
+type provider struct {
+	root     string
+	db       metadata.DB
+	settings PluginSettings
+}
+
+func New(root string, db metadata.DB, settings PluginSettings) (p2plab.NodeProvider, error) {
+	if settings.Path == "" {
+		return nil, errors.Wrap(errdefs.ErrInvalidArgument, "plugin path must not be empty")
+	}
+
+	err := os.MkdirAll(root, 0711)
+	if err != nil {
+		return nil, err
+	}
+
+	return &provider{
+		root:     root,
+		db:       db,
+		settings: settings,
+	}, nil
+}
+
+func (p *provider) CreateNodeGroup(ctx context.Context, id string, cdef metadata.ClusterDefinition) (*p2plab.NodeGroup, error) {
+	var resp Response
+	definition := FromClusterDefinition(cdef)
+	err := p.call(ctx, MethodCreate, &Request{ID: id, Definition: &definition}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.NodeGroup == nil {
+		return nil, errors.Errorf("plugin did not return node group %q", id)
+	}
+
+	return resp.NodeGroup.ToNodeGroup(), nil
+}
+
+func (p *provider) ResizeNodeGroup(ctx context.Context, ng *p2plab.NodeGroup, cdef metadata.ClusterDefinition) (*p2plab.NodeGroup, error) {
+	var resp Response
+	definition := FromClusterDefinition(cdef)
+	err := p.call(ctx, MethodResize, &Request{Definition: &definition, NodeGroup: FromNodeGroup(ng)}, &resp)
+	if err != nil {
+		if isUnimplemented(err) {
+			return nil, errors.Wrap(errdefs.ErrInvalidArgument, "plugin does not support resizing node groups")
+		}
+		return nil, err
+	}
+
+	if resp.NodeGroup == nil {
+		return nil, errors.Errorf("plugin did not return node group %q", ng.ID)
+	}
+
+	return resp.NodeGroup.ToNodeGroup(), nil
+}
+
+func (p *provider) DestroyNodeGroup(ctx context.Context, ng *p2plab.NodeGroup) error {
+	return p.call(ctx, MethodDestroy, &Request{NodeGroup: FromNodeGroup(ng)}, &Response{})
+}
+
+func (p *provider) ListNodeGroups(ctx context.Context) ([]*p2plab.NodeGroup, error) {
+	var resp Response
+	err := p.call(ctx, MethodList, &Request{}, &resp)
+	if err == nil {
+		var ngs []*p2plab.NodeGroup
+		for _, ng := range resp.NodeGroups {
+			if ng != nil {
+				ngs = append(ngs, ng.ToNodeGroup())
+			}
+		}
+		return ngs, nil
+	} else if !isUnimplemented(err) {
+		return nil, err
+	}
+
+	clusters, err := p.db.ListClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ngs []*p2plab.NodeGroup
+	for _, cluster := range clusters {
+		ngs = append(ngs, &p2plab.NodeGroup{
+			ID:         cluster.ID,
+			Definition: cluster.Definition,
+		})
+	}
+
+	return ngs, nil
+}
+
+func (p *provider) call(ctx context.Context, method string, req *Request, resp *Response) error {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "plugin.call")
+	defer span.Finish()
+	span.SetTag("method", method)
+
+	content, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	logger := zerolog.Ctx(ctx).With().Str("plugin", p.settings.Path).Str("method", method).Logger()
+	logWriter := logutil.NewWriter(&logger, zerolog.DebugLevel)
+	defer logWriter.Close()
+
+	var stdout bytes.Buffer
+	args := append(append([]string{}, p.settings.Args...), method)
+	cmd := exec.CommandContext(ctx, p.settings.Path, args...)
+	cmd.Dir = p.root
+	cmd.Stdin = bytes.NewReader(content)
+	cmd.Stdout = &stdout
+	cmd.Stderr = logWriter
+
+	runErr := cmd.Run()
+
+	// Plugins may exit with a non-zero status after writing an error response,
+	// which is more descriptive than the exit status.
+	if stdout.Len() > 0 {
+		err = json.Unmarshal(stdout.Bytes(), resp)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode response of plugin method %q", method)
+		}
+
+		if resp.Error != nil {
+			return errors.Wrapf(resp.Error.cause(), "plugin method %q failed", method)
+		}
+	}
+
+	if runErr != nil {
+		return errors.Wrapf(runErr, "plugin method %q failed", method)
+	}
+
+	return nil
+}
+
+func isUnimplemented(err error) bool {
+	e, ok := errors.Cause(err).(*Error)
+	return ok && e.Code == CodeUnimplemented
+}
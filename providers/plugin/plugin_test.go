@@ -0,0 +1,164 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHelperPlugin is a plugin run by the other tests as a subprocess.
+func TestHelperPlugin(t *testing.T) {
+	if os.Getenv("P2PLAB_TEST_PLUGIN") != "1" {
+		return
+	}
+
+	var req Request
+	err := json.NewDecoder(os.Stdin).Decode(&req)
+	if err != nil {
+		os.Exit(1)
+	}
+
+	var resp Response
+	switch os.Args[len(os.Args)-1] {
+	case MethodCreate:
+		ng := &NodeGroup{ID: req.ID, Definition: *req.Definition}
+		for i, group := range req.Definition.Groups {
+			for j := 0; j < group.Size; j++ {
+				ng.Nodes = append(ng.Nodes, Node{
+					ID:    fmt.Sprintf("%s-%d-%d", req.ID, i, j),
+					Group: i,
+				})
+			}
+		}
+		resp.NodeGroup = ng
+	case MethodDestroy:
+		if req.NodeGroup.ID == "missing" {
+			resp.Error = &Error{Code: CodeNotFound, Message: "no such node group"}
+		}
+	default:
+		resp.Error = &Error{Code: CodeUnimplemented}
+	}
+
+	json.NewEncoder(os.Stdout).Encode(&resp)
+	os.Exit(0)
+}
+
+func TestPlugin(t *testing.T) {
+	os.Setenv("P2PLAB_TEST_PLUGIN", "1")
+	defer os.Unsetenv("P2PLAB_TEST_PLUGIN")
+
+	root, err := ioutil.TempDir("", "plugin-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	db, err := metadata.NewDB(filepath.Join(root, "db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	p, err := New(filepath.Join(root, "plugin"), db, PluginSettings{
+		Path: os.Args[0],
+		Args: []string{"-test.run=TestHelperPlugin", "--"},
+	})
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	cdef := metadata.ClusterDefinition{
+		Groups: []metadata.ClusterGroup{{Size: 2}, {Size: 1}},
+	}
+	ng, err := p.CreateNodeGroup(ctx, "test", cdef)
+	require.NoError(t, err)
+	require.Equal(t, "test", ng.ID)
+	require.Len(t, ng.Nodes, 3)
+	require.Equal(t, 1, ng.Nodes[2].Group)
+
+	_, err = p.ResizeNodeGroup(ctx, ng, cdef)
+	require.True(t, errdefs.IsInvalidArgument(err))
+
+	// Plugins that cannot list node groups have one for every cluster.
+	_, err = db.CreateCluster(ctx, metadata.Cluster{ID: "test", Definition: cdef})
+	require.NoError(t, err)
+
+	ngs, err := p.ListNodeGroups(ctx)
+	require.NoError(t, err)
+	require.Len(t, ngs, 1)
+	require.Equal(t, "test", ngs[0].ID)
+	require.Nil(t, ngs[0].Nodes)
+
+	require.NoError(t, p.DestroyNodeGroup(ctx, ng))
+
+	err = p.DestroyNodeGroup(ctx, &p2plab.NodeGroup{ID: "missing"})
+	require.True(t, errdefs.IsNotFound(err))
+}
+
+func TestProtocol(t *testing.T) {
+	ng := &p2plab.NodeGroup{
+		ID: "test",
+		Definition: metadata.ClusterDefinition{
+			Groups: []metadata.ClusterGroup{{
+				Size:         1,
+				InstanceType: "t2.micro",
+				Region:       "us-west-2",
+				Peer:         &metadata.PeerDefinition{GitReference: "HEAD"},
+			}},
+		},
+		Nodes: []metadata.Node{{
+			ID:        "node-0",
+			Address:   "10.0.0.1",
+			AgentPort: 7002,
+			AppPort:   7003,
+			Peer:      metadata.PeerDefinition{GitReference: "HEAD"},
+			Labels:    []string{"node-0"},
+		}},
+	}
+
+	content, err := json.Marshal(&Request{NodeGroup: FromNodeGroup(ng)})
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"nodeGroup": {
+			"id": "test",
+			"definition": {
+				"groups": [
+					{"size": 1, "instanceType": "t2.micro", "region": "us-west-2", "peer": {"gitReference": "HEAD"}}
+				]
+			},
+			"nodes": [
+				{"id": "node-0", "address": "10.0.0.1", "agentPort": 7002, "appPort": 7003, "group": 0, "peer": {"gitReference": "HEAD"}, "labels": ["node-0"]}
+			]
+		}
+	}`, string(content))
+
+	var req Request
+	require.NoError(t, json.Unmarshal(content, &req))
+	require.Equal(t, ng, req.NodeGroup.ToNodeGroup())
+
+	// Nodes that cannot be listed are distinguished from no nodes.
+	var resp Response
+	require.NoError(t, json.Unmarshal([]byte(`{"nodeGroups": [{"id": "a", "nodes": null}, {"id": "b", "nodes": []}]}`), &resp))
+	require.Len(t, resp.NodeGroups, 2)
+	require.Nil(t, resp.NodeGroups[0].ToNodeGroup().Nodes)
+	require.NotNil(t, resp.NodeGroups[1].ToNodeGroup().Nodes)
+	require.Empty(t, resp.NodeGroups[1].ToNodeGroup().Nodes)
+}
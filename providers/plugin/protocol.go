@@ -0,0 +1,230 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/metadata"
+)
+
+// NodeGroup is the JSON representation of a node group exchanged with plugins.
+type NodeGroup struct {
+	ID string `json:"id"`
+
+	// Definition is the cluster definition the nodes were provisioned for.
+	Definition ClusterDefinition `json:"definition"`
+
+	// Nodes is null when the plugin cannot list the node group's nodes.
+	Nodes []Node `json:"nodes"`
+}
+
+// ClusterDefinition is the JSON representation of a cluster definition.
+type ClusterDefinition struct {
+	Groups []ClusterGroup `json:"groups"`
+}
+
+// ClusterGroup is the JSON representation of a group of a cluster definition.
+type ClusterGroup struct {
+	Size             int             `json:"size"`
+	InstanceType     string          `json:"instanceType,omitempty"`
+	Region           string          `json:"region,omitempty"`
+	PeersPerInstance int             `json:"peersPerInstance,omitempty"`
+	Peer             *PeerDefinition `json:"peer,omitempty"`
+	Labels           []string        `json:"labels,omitempty"`
+}
+
+// PeerDefinition is the JSON representation of the configuration of a node's
+// peer.
+type PeerDefinition struct {
+	GitReference           string   `json:"gitReference,omitempty"`
+	Transports             []string `json:"transports,omitempty"`
+	Muxers                 []string `json:"muxers,omitempty"`
+	SecurityTransports     []string `json:"securityTransports,omitempty"`
+	Routing                string   `json:"routing,omitempty"`
+	ConnManagerLow         int      `json:"connManagerLow,omitempty"`
+	ConnManagerHigh        int      `json:"connManagerHigh,omitempty"`
+	ConnManagerGracePeriod string   `json:"connManagerGracePeriod,omitempty"`
+	RestartPolicy          string   `json:"restartPolicy,omitempty"`
+	CPUQuota               float64  `json:"cpuQuota,omitempty"`
+	MemoryLimit            string   `json:"memoryLimit,omitempty"`
+	IOWeight               int      `json:"ioWeight,omitempty"`
+}
+
+// Node is the JSON representation of a node provided by a plugin. The status
+// of its labagent and its timestamps are kept by labd, so they are not part of
+// the protocol.
+type Node struct {
+	ID        string `json:"id"`
+	Address   string `json:"address"`
+	AgentPort int    `json:"agentPort"`
+	AppPort   int    `json:"appPort"`
+
+	// Instance is the index of the node's labapp among those supervised by its
+	// labagent.
+	Instance int `json:"instance,omitempty"`
+
+	// Group is the index of the cluster group the node was provisioned for.
+	Group int `json:"group"`
+
+	Peer   PeerDefinition `json:"peer"`
+	Labels []string       `json:"labels,omitempty"`
+}
+
+// FromNodeGroup returns the JSON representation of a node group.
+func FromNodeGroup(ng *p2plab.NodeGroup) *NodeGroup {
+	if ng == nil {
+		return nil
+	}
+
+	dto := &NodeGroup{
+		ID:         ng.ID,
+		Definition: FromClusterDefinition(ng.Definition),
+	}
+	if ng.Nodes != nil {
+		dto.Nodes = make([]Node, 0, len(ng.Nodes))
+		for _, n := range ng.Nodes {
+			dto.Nodes = append(dto.Nodes, fromNode(n))
+		}
+	}
+	return dto
+}
+
+// ToNodeGroup returns the node group represented by ng.
+func (ng *NodeGroup) ToNodeGroup() *p2plab.NodeGroup {
+	if ng == nil {
+		return nil
+	}
+
+	out := &p2plab.NodeGroup{
+		ID:         ng.ID,
+		Definition: ng.Definition.ToClusterDefinition(),
+	}
+	if ng.Nodes != nil {
+		out.Nodes = make([]metadata.Node, 0, len(ng.Nodes))
+		for _, n := range ng.Nodes {
+			out.Nodes = append(out.Nodes, n.toNode())
+		}
+	}
+	return out
+}
+
+// FromClusterDefinition returns the JSON representation of a cluster
+// definition.
+func FromClusterDefinition(cdef metadata.ClusterDefinition) ClusterDefinition {
+	var dto ClusterDefinition
+	for _, g := range cdef.Groups {
+		dto.Groups = append(dto.Groups, ClusterGroup{
+			Size:             g.Size,
+			InstanceType:     g.InstanceType,
+			Region:           g.Region,
+			PeersPerInstance: g.PeersPerInstance,
+			Peer:             fromPeerDefinitionPtr(g.Peer),
+			Labels:           g.Labels,
+		})
+	}
+	return dto
+}
+
+// ToClusterDefinition returns the cluster definition represented by cdef.
+func (cdef ClusterDefinition) ToClusterDefinition() metadata.ClusterDefinition {
+	var out metadata.ClusterDefinition
+	for _, g := range cdef.Groups {
+		out.Groups = append(out.Groups, metadata.ClusterGroup{
+			Size:             g.Size,
+			InstanceType:     g.InstanceType,
+			Region:           g.Region,
+			PeersPerInstance: g.PeersPerInstance,
+			Peer:             g.Peer.toPeerDefinitionPtr(),
+			Labels:           g.Labels,
+		})
+	}
+	return out
+}
+
+func fromNode(n metadata.Node) Node {
+	return Node{
+		ID:        n.ID,
+		Address:   n.Address,
+		AgentPort: n.AgentPort,
+		AppPort:   n.AppPort,
+		Instance:  n.Instance,
+		Group:     n.Group,
+		Peer:      fromPeerDefinition(n.Peer),
+		Labels:    n.Labels,
+	}
+}
+
+func (n Node) toNode() metadata.Node {
+	return metadata.Node{
+		ID:        n.ID,
+		Address:   n.Address,
+		AgentPort: n.AgentPort,
+		AppPort:   n.AppPort,
+		Instance:  n.Instance,
+		Group:     n.Group,
+		Peer:      n.Peer.toPeerDefinition(),
+		Labels:    n.Labels,
+	}
+}
+
+func fromPeerDefinition(pdef metadata.PeerDefinition) PeerDefinition {
+	return PeerDefinition{
+		GitReference:           pdef.GitReference,
+		Transports:             pdef.Transports,
+		Muxers:                 pdef.Muxers,
+		SecurityTransports:     pdef.SecurityTransports,
+		Routing:                pdef.Routing,
+		ConnManagerLow:         pdef.ConnManagerLow,
+		ConnManagerHigh:        pdef.ConnManagerHigh,
+		ConnManagerGracePeriod: pdef.ConnManagerGracePeriod,
+		RestartPolicy:          pdef.RestartPolicy,
+		CPUQuota:               pdef.CPUQuota,
+		MemoryLimit:            pdef.MemoryLimit,
+		IOWeight:               pdef.IOWeight,
+	}
+}
+
+func fromPeerDefinitionPtr(pdef *metadata.PeerDefinition) *PeerDefinition {
+	if pdef == nil {
+		return nil
+	}
+	dto := fromPeerDefinition(*pdef)
+	return &dto
+}
+
+func (pdef PeerDefinition) toPeerDefinition() metadata.PeerDefinition {
+	return metadata.PeerDefinition{
+		GitReference:           pdef.GitReference,
+		Transports:             pdef.Transports,
+		Muxers:                 pdef.Muxers,
+		SecurityTransports:     pdef.SecurityTransports,
+		Routing:                pdef.Routing,
+		ConnManagerLow:         pdef.ConnManagerLow,
+		ConnManagerHigh:        pdef.ConnManagerHigh,
+		ConnManagerGracePeriod: pdef.ConnManagerGracePeriod,
+		RestartPolicy:          pdef.RestartPolicy,
+		CPUQuota:               pdef.CPUQuota,
+		MemoryLimit:            pdef.MemoryLimit,
+		IOWeight:               pdef.IOWeight,
+	}
+}
+
+func (pdef *PeerDefinition) toPeerDefinitionPtr() *metadata.PeerDefinition {
+	if pdef == nil {
+		return nil
+	}
+	out := pdef.toPeerDefinition()
+	return &out
+}
@@ -24,14 +24,23 @@ import (
 	"github.com/Netflix/p2plab/labagent"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/providers/inmemory"
+	"github.com/Netflix/p2plab/providers/inventory"
+	"github.com/Netflix/p2plab/providers/netns"
+	"github.com/Netflix/p2plab/providers/plugin"
 	"github.com/Netflix/p2plab/providers/terraform"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 )
 
 type ProviderSettings struct {
-	DB     metadata.DB
-	Logger *zerolog.Logger
+	DB        metadata.DB
+	Logger    *zerolog.Logger
+	Inventory inventory.InventoryProviderSettings
+	Terraform terraform.TerraformProviderSettings
+
+	// Plugins are out-of-process providers registered by name. Built-in
+	// providers take precedence over plugins of the same name.
+	Plugins map[string]plugin.PluginSettings
 }
 
 func GetNodeProvider(root, providerType string, settings ProviderSettings) (p2plab.NodeProvider, error) {
@@ -44,9 +53,21 @@ func GetNodeProvider(root, providerType string, settings ProviderSettings) (p2pl
 			},
 		}),
 		)
+	case "netns":
+		return netns.New(root, settings.DB, settings.Logger, labagent.WithDownloaderSettings(downloaders.DownloaderSettings{
+			S3: s3downloader.S3DownloaderSettings{
+				Region: "us-west-2",
+			},
+		}),
+		)
+	case "inventory":
+		return inventory.New(settings.DB, settings.Logger, settings.Inventory)
 	case "terraform":
-		return terraform.New(root)
+		return terraform.New(root, settings.Terraform)
 	default:
+		if ps, ok := settings.Plugins[providerType]; ok {
+			return plugin.New(root, settings.DB, ps)
+		}
 		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "unrecognized node provider type %q", providerType)
 	}
 }
@@ -27,6 +27,15 @@ type EC2Instance struct {
 	InstanceId   string `json:"InstanceId"`
 	InstanceType string `json:"InstanceType"`
 	PrivateIp    string `json:"PrivateIpAddress"`
+	State        struct {
+		Name string `json:"Name"`
+	} `json:"State"`
+}
+
+// Running returns whether the instance is running, or pending when its state
+// is unknown.
+func (i EC2Instance) Running() bool {
+	return i.State.Name == "" || i.State.Name == "running"
 }
 
 func DiscoverInstances(ctx context.Context, asg, region string) ([]EC2Instance, error) {
@@ -47,6 +56,11 @@ func DiscoverInstances(ctx context.Context, asg, region string) ([]EC2Instance,
 		return nil, err
 	}
 
+	// Describing no instance IDs would describe every instance in the region.
+	if len(instanceIds) == 0 {
+		return nil, nil
+	}
+
 	instancesStdout := new(bytes.Buffer)
 	err = awscliWithStdio(ctx, instancesStdout, nil, append([]string{"ec2", "describe-instances",
 		"--query", "Reservations[].Instances[]",
@@ -16,6 +16,7 @@ package terraform
 
 import (
 	"context"
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -34,58 +35,129 @@ var (
 	DefaultAppPort   = 7003
 )
 
+const (
+	// BackendS3 stores terraform state in an S3 bucket.
+	BackendS3 = "s3"
+
+	// BackendLocal stores terraform state in the cluster's directory, so that
+	// its configuration can be planned without AWS credentials for the state
+	// bucket.
+	BackendLocal = "local"
+)
+
+type TerraformProviderSettings struct {
+	// Templates is the directory of terraform templates. Defaults to the
+	// templates directory in the provider's root. Files in the clusters/<id>
+	// subdirectory of the templates override those of the cluster <id>.
+	Templates string
+
+	// Backend is where terraform state is stored. Defaults to s3.
+	Backend string
+
+	// Bucket and BucketRegion locate the S3 bucket storing terraform state.
+	Bucket       string
+	BucketRegion string
+
+	// Regions are the AWS regions clusters can be deployed to. Defaults to
+	// every region with subnets.
+	Regions []string
+
+	// Subnets are the IDs of the subnets instances are launched in, by region.
+	// Every region must have at least one subnet.
+	Subnets map[string][]string
+}
+
 type provider struct {
 	root          string
-	tfvars        *template.Template
-	maintf        *template.Template
+	templates     string
+	backend       BackendVars
+	regions       []string
+	subnets       map[string][]string
 	terraformById map[string]*Terraform
 }
 
 type BackendVars struct {
+	// Type is the terraform backend, either s3 or local.
+	Type string
+
 	Bucket string
 	Key    string
 	Region string
 }
 
+// MainVars are the variables of the main.tf template.
+type MainVars struct {
+	BackendVars
+
+	Regions []string
+}
+
 type ClusterVars struct {
 	ID                    string
 	RegionalClusterGroups []RegionalClusterGroups
 }
 
 type RegionalClusterGroups struct {
-	Region string
-	Groups []metadata.ClusterGroup
+	Region  string
+	Subnets []string
+	Groups  []metadata.ClusterGroup
 }
 
-func New(root string) (p2plab.NodeProvider, error) {
-	tfvarsPath := filepath.Join(root, "templates/terraform.tfvars")
-	tfvarsContent, err := ioutil.ReadFile(tfvarsPath)
-	if err != nil {
-		return nil, err
+func New(root string, settings TerraformProviderSettings) (p2plab.NodeProvider, error) {
+	p := &provider{
+		root:      root,
+		templates: settings.Templates,
+		backend: BackendVars{
+			Type:   settings.Backend,
+			Bucket: settings.Bucket,
+			Region: settings.BucketRegion,
+		},
+		regions:       settings.Regions,
+		subnets:       settings.Subnets,
+		terraformById: make(map[string]*Terraform),
+	}
+	if p.templates == "" {
+		p.templates = filepath.Join(root, "templates")
+	}
+	if p.backend.Type == "" {
+		p.backend.Type = BackendS3
+	}
+	if len(p.regions) == 0 {
+		for region := range p.subnets {
+			p.regions = append(p.regions, region)
+		}
+		sort.Strings(p.regions)
 	}
 
-	tfvars, err := template.New("terraform.tfvars").Parse(string(tfvarsContent))
-	if err != nil {
-		return nil, err
+	if len(p.regions) == 0 {
+		return nil, errors.Wrap(errdefs.ErrInvalidArgument, "at least one region with subnets is required")
+	}
+	for _, region := range p.regions {
+		if len(p.subnets[region]) == 0 {
+			return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "region %q has no subnets", region)
+		}
 	}
 
-	maintfPath := filepath.Join(root, "templates/main.tf")
-	maintfContent, err := ioutil.ReadFile(maintfPath)
-	if err != nil {
-		return nil, err
+	switch p.backend.Type {
+	case BackendS3:
+		if p.backend.Bucket == "" || p.backend.Region == "" {
+			return nil, errors.Wrap(errdefs.ErrInvalidArgument, "s3 backend requires a bucket and its region")
+		}
+	case BackendLocal:
+	default:
+		return nil, errors.Wrapf(errdefs.ErrInvalidArgument, "unrecognized terraform backend %q", p.backend.Type)
 	}
 
-	maintf, err := template.New("main.tf").Parse(string(maintfContent))
-	if err != nil {
-		return nil, err
+	// Parse the templates early so that mistakes are found before any cluster
+	// is created.
+	for _, name := range []string{"main.tf", "terraform.tfvars"} {
+		_, err := p.parseTemplate("", name)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return &provider{
-		root:          root,
-		tfvars:        tfvars,
-		maintf:        maintf,
-		terraformById: make(map[string]*Terraform),
-	}, nil
+	return p, nil
 }
 
 func (p *provider) CreateNodeGroup(ctx context.Context, id string, cdef metadata.ClusterDefinition) (*p2plab.NodeGroup, error) {
@@ -96,10 +168,10 @@ func (p *provider) CreateNodeGroup(ctx context.Context, id string, cdef metadata
 	}
 	logger := zerolog.Ctx(ctx).With().Str("dir", clusterDir).Logger()
 
-	logger.Debug().Msg("Executing tfvars template")
-	err = p.executeTfvarsTemplate(id, cdef)
+	logger.Debug().Msg("Executing templates")
+	err = p.executeTemplates(id, cdef)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to execute tfvars template")
+		return nil, err
 	}
 
 	logger.Debug().Msg("Creating terraform handler")
@@ -116,26 +188,49 @@ func (p *provider) CreateNodeGroup(ctx context.Context, id string, cdef metadata
 	}
 
 	return &p2plab.NodeGroup{
-		ID:    id,
-		Nodes: ns,
+		ID:         id,
+		Definition: cdef,
+		Nodes:      ns,
+	}, nil
+}
+
+// ResizeNodeGroup applies the cluster's terraform configuration with the new
+// definition. Groups are matched to autoscaling groups by their index, so
+// existing groups keep their instances.
+func (p *provider) ResizeNodeGroup(ctx context.Context, ng *p2plab.NodeGroup, cdef metadata.ClusterDefinition) (*p2plab.NodeGroup, error) {
+	t, err := p.getTerraform(ctx, ng.ID)
+	if err != nil {
+		return nil, err
+	}
+	logger := zerolog.Ctx(ctx).With().Str("dir", t.root).Logger()
+
+	logger.Debug().Msg("Executing templates")
+	err = p.executeTemplates(ng.ID, cdef)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug().Msg("Terraform applying")
+	ns, err := t.Apply(ctx, ng.ID, cdef)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to terraform apply")
+	}
+
+	return &p2plab.NodeGroup{
+		ID:         ng.ID,
+		Definition: cdef,
+		Nodes:      ns,
 	}, nil
 }
 
 func (p *provider) DestroyNodeGroup(ctx context.Context, ng *p2plab.NodeGroup) error {
-	t, ok := p.terraformById[ng.ID]
-	if !ok {
-		zerolog.Ctx(ctx).Debug().Msg("Creating terraform handler")
-		var err error
-		clusterDir := filepath.Join(p.root, ng.ID)
-		t, err = NewTerraform(ctx, clusterDir)
-		if err != nil {
-			return errors.Wrap(err, "failed to create terraform handler")
-		}
-		p.terraformById[ng.ID] = t
+	t, err := p.getTerraform(ctx, ng.ID)
+	if err != nil {
+		return err
 	}
 
 	zerolog.Ctx(ctx).Debug().Msg("Terraform destroying")
-	err := t.Destroy(ctx, ng.ID)
+	err = t.Destroy(ctx, ng.ID)
 	if err != nil {
 		return errors.Wrap(err, "failed to terraform destroy")
 	}
@@ -151,6 +246,67 @@ func (p *provider) DestroyNodeGroup(ctx context.Context, ng *p2plab.NodeGroup) e
 	return nil
 }
 
+// ListNodeGroups returns a node group for each cluster directory, with the
+// nodes of the instances running in its autoscaling groups. The nodes of
+// clusters whose definition was not recorded are left nil.
+func (p *provider) ListNodeGroups(ctx context.Context) ([]*p2plab.NodeGroup, error) {
+	fis, err := ioutil.ReadDir(p.root)
+	if err != nil {
+		return nil, err
+	}
+
+	var ngs []*p2plab.NodeGroup
+	for _, fi := range fis {
+		if !fi.IsDir() || filepath.Join(p.root, fi.Name()) == filepath.Clean(p.templates) {
+			continue
+		}
+
+		ng := &p2plab.NodeGroup{
+			ID: fi.Name(),
+		}
+		ngs = append(ngs, ng)
+
+		cdef, err := p.readDefinition(ng.ID)
+		if err != nil {
+			if os.IsNotExist(errors.Cause(err)) {
+				zerolog.Ctx(ctx).Debug().Str("name", ng.ID).Msg("Cluster definition not recorded, nodes cannot be listed")
+				continue
+			}
+			return nil, err
+		}
+		ng.Definition = cdef
+
+		ng.Nodes, err = DiscoverNodes(ctx, ng.ID, cdef)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to discover nodes of %q", ng.ID)
+		}
+		if ng.Nodes == nil {
+			ng.Nodes = []metadata.Node{}
+		}
+	}
+
+	return ngs, nil
+}
+
+// getTerraform returns the terraform handler of a cluster, initializing one
+// from the cluster directory if labd was restarted since it was created.
+func (p *provider) getTerraform(ctx context.Context, id string) (*Terraform, error) {
+	t, ok := p.terraformById[id]
+	if ok {
+		return t, nil
+	}
+
+	zerolog.Ctx(ctx).Debug().Msg("Creating terraform handler")
+	clusterDir := filepath.Join(p.root, id)
+	t, err := NewTerraform(ctx, clusterDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create terraform handler")
+	}
+	p.terraformById[id] = t
+
+	return t, nil
+}
+
 func (p *provider) prepareClusterDir(id string) (clusterDir string, err error) {
 	clusterDir = filepath.Join(p.root, id)
 	_, err = os.Stat(clusterDir)
@@ -169,20 +325,19 @@ func (p *provider) prepareClusterDir(id string) (clusterDir string, err error) {
 		}
 	}()
 
-	terraformDir := filepath.Join(p.root, "templates")
-	for _, p := range []string{
+	for _, name := range []string{
 		"outputs.tf",
 		"variables.tf",
 		"modules/labagent/main.tf",
 		"modules/labagent/outputs.tf",
 		"modules/labagent/variables.tf",
 	} {
-		dst, err := filepath.Abs(filepath.Join(terraformDir, p))
+		dst, err := filepath.Abs(p.templatePath(id, name))
 		if err != nil {
 			return clusterDir, err
 		}
 
-		src, err := filepath.Abs(filepath.Join(clusterDir, p))
+		src, err := filepath.Abs(filepath.Join(clusterDir, name))
 		if err != nil {
 			return clusterDir, err
 		}
@@ -193,39 +348,60 @@ func (p *provider) prepareClusterDir(id string) (clusterDir string, err error) {
 		}
 	}
 
-	maintfPath := filepath.Join(p.root, id, "main.tf")
-	f, err := os.Create(maintfPath)
+	return clusterDir, nil
+}
+
+func (p *provider) destroyClusterDir(id string) error {
+	clusterDir := filepath.Join(p.root, id)
+	return os.RemoveAll(clusterDir)
+}
+
+// executeTemplates renders the main.tf and terraform.tfvars of a cluster from
+// the current templates and settings, and the cluster definition.
+func (p *provider) executeTemplates(id string, cdef metadata.ClusterDefinition) error {
+	err := p.executeMainTemplate(id)
 	if err != nil {
-		return clusterDir, err
+		return errors.Wrap(err, "failed to execute main.tf template")
 	}
-	defer f.Close()
 
-	vars := BackendVars{
-		Bucket: "nflx-labdterraform-protocollabstest-us-west-2",
-		Key:    id,
-		Region: "us-west-2",
+	err = p.executeTfvarsTemplate(id, cdef)
+	if err != nil {
+		return errors.Wrap(err, "failed to execute tfvars template")
 	}
 
-	err = p.maintf.Execute(f, &vars)
+	return nil
+}
+
+func (p *provider) executeMainTemplate(id string) error {
+	maintf, err := p.parseTemplate(id, "main.tf")
 	if err != nil {
-		return clusterDir, err
+		return err
 	}
 
-	return clusterDir, nil
-}
+	f, err := os.Create(filepath.Join(p.root, id, "main.tf"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
-func (p *provider) destroyClusterDir(id string) error {
-	clusterDir := filepath.Join(p.root, id)
-	return os.RemoveAll(clusterDir)
+	vars := MainVars{
+		BackendVars: p.backend,
+		Regions:     p.regions,
+	}
+	vars.Key = id
+
+	return maintf.Execute(f, &vars)
 }
 
 func (p *provider) executeTfvarsTemplate(id string, cdef metadata.ClusterDefinition) error {
 	vars := ClusterVars{ID: id}
 
-	clusterGroupsByRegion := map[string]RegionalClusterGroups{
-		"us-west-2": RegionalClusterGroups{Region: "us-west-2"},
-		"us-east-1": RegionalClusterGroups{Region: "us-east-1"},
-		"eu-west-1": RegionalClusterGroups{Region: "eu-west-1"},
+	clusterGroupsByRegion := make(map[string]RegionalClusterGroups)
+	for _, region := range p.regions {
+		clusterGroupsByRegion[region] = RegionalClusterGroups{
+			Region:  region,
+			Subnets: p.subnets[region],
+		}
 	}
 
 	for _, group := range cdef.Groups {
@@ -245,6 +421,16 @@ func (p *provider) executeTfvarsTemplate(id string, cdef metadata.ClusterDefinit
 		return vars.RegionalClusterGroups[i].Region < vars.RegionalClusterGroups[j].Region
 	})
 
+	tfvars, err := p.parseTemplate(id, "terraform.tfvars")
+	if err != nil {
+		return err
+	}
+
+	err = p.writeDefinition(id, cdef)
+	if err != nil {
+		return err
+	}
+
 	tfvarsPath := filepath.Join(p.root, id, "terraform.tfvars")
 	f, err := os.Create(tfvarsPath)
 	if err != nil {
@@ -252,10 +438,63 @@ func (p *provider) executeTfvarsTemplate(id string, cdef metadata.ClusterDefinit
 	}
 	defer f.Close()
 
-	err = p.tfvars.Execute(f, &vars)
+	err = tfvars.Execute(f, &vars)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// writeDefinition records the definition a cluster's configuration was
+// rendered from, so that its nodes can be discovered after labd restarts.
+func (p *provider) writeDefinition(id string, cdef metadata.ClusterDefinition) error {
+	content, err := json.Marshal(&cdef)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(p.root, id, "definition.json"), content, 0644)
+}
+
+func (p *provider) readDefinition(id string) (metadata.ClusterDefinition, error) {
+	var cdef metadata.ClusterDefinition
+	content, err := ioutil.ReadFile(filepath.Join(p.root, id, "definition.json"))
+	if err != nil {
+		return cdef, err
+	}
+
+	err = json.Unmarshal(content, &cdef)
+	if err != nil {
+		return cdef, errors.Wrapf(err, "invalid definition of cluster %q", id)
+	}
+	return cdef, nil
+}
+
+// templatePath returns the path to a template of a cluster, which is the
+// cluster's override if it has one.
+func (p *provider) templatePath(id, name string) string {
+	if id != "" {
+		override := filepath.Join(p.templates, "clusters", id, name)
+		_, err := os.Stat(override)
+		if err == nil {
+			return override
+		}
+	}
+	return filepath.Join(p.templates, name)
+}
+
+func (p *provider) parseTemplate(id, name string) (*template.Template, error) {
+	path := p.templatePath(id, name)
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := template.New(name).Parse(string(content))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse template %q", path)
+	}
+
+	return t, nil
+}
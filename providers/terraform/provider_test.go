@@ -0,0 +1,266 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Netflix/p2plab/errdefs"
+	"github.com/Netflix/p2plab/metadata"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestPrepareClusterDir(t *testing.T) {
+	root, err := ioutil.TempDir("", "terraform-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	templates, err := filepath.Abs("templates")
+	require.NoError(t, err)
+
+	np, err := New(root, TerraformProviderSettings{
+		Templates: templates,
+		Backend:   BackendLocal,
+		Regions:   []string{"ap-south-1", "us-west-2"},
+		Subnets: map[string][]string{
+			"ap-south-1": {"subnet-0a"},
+			"us-west-2":  {"subnet-1a", "subnet-1b"},
+			"eu-west-1":  {"subnet-2a"},
+		},
+	})
+	require.NoError(t, err)
+	p := np.(*provider)
+
+	clusterDir, err := p.prepareClusterDir("test")
+	require.NoError(t, err)
+
+	err = p.executeTemplates("test", metadata.ClusterDefinition{
+		Groups: []metadata.ClusterGroup{
+			{Size: 4, PeersPerInstance: 2, InstanceType: "t2.micro", Region: "ap-south-1"},
+		},
+	})
+	require.NoError(t, err)
+
+	maintf, err := ioutil.ReadFile(filepath.Join(clusterDir, "main.tf"))
+	require.NoError(t, err)
+	require.Contains(t, string(maintf), `backend "local" {}`)
+	require.NotContains(t, string(maintf), `backend "s3"`)
+	require.Contains(t, string(maintf), `module "labagent_ap-south-1"`)
+	require.Contains(t, string(maintf), `module "labagent_us-west-2"`)
+	require.NotContains(t, string(maintf), "eu-west-1")
+
+	tfvars, err := ioutil.ReadFile(filepath.Join(clusterDir, "terraform.tfvars"))
+	require.NoError(t, err)
+	require.Contains(t, string(tfvars), "ap-south-1 = {")
+	require.Contains(t, string(tfvars), "test-0 = {")
+	require.Contains(t, string(tfvars), "size          = 2")
+	require.Contains(t, string(tfvars), "us-west-2 = {")
+	require.Contains(t, string(tfvars), "internal_subnets = {")
+	require.Contains(t, string(tfvars), `"subnet-0a",`)
+	require.Contains(t, string(tfvars), `"subnet-1b",`)
+	require.NotContains(t, string(tfvars), "subnet-2a")
+
+	// Both files are rendered again when the cluster is resized.
+	for _, name := range []string{"main.tf", "terraform.tfvars"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(clusterDir, name), []byte("stale"), 0644))
+	}
+	err = p.executeTemplates("test", metadata.ClusterDefinition{
+		Groups: []metadata.ClusterGroup{
+			{Size: 2, InstanceType: "t2.micro", Region: "us-west-2"},
+		},
+	})
+	require.NoError(t, err)
+
+	maintf, err = ioutil.ReadFile(filepath.Join(clusterDir, "main.tf"))
+	require.NoError(t, err)
+	require.Contains(t, string(maintf), `module "labagent_us-west-2"`)
+
+	tfvars, err = ioutil.ReadFile(filepath.Join(clusterDir, "terraform.tfvars"))
+	require.NoError(t, err)
+	require.Contains(t, string(tfvars), "size          = 2")
+
+	err = p.executeTemplates("test", metadata.ClusterDefinition{
+		Groups: []metadata.ClusterGroup{
+			{Size: 1, InstanceType: "t2.micro", Region: "eu-west-1"},
+		},
+	})
+	require.True(t, errdefs.IsInvalidArgument(err))
+}
+
+func TestTemplateOverride(t *testing.T) {
+	templates, err := ioutil.TempDir("", "terraform-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(templates)
+
+	override := filepath.Join(templates, "clusters", "custom")
+	require.NoError(t, os.MkdirAll(override, 0711))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templates, "main.tf"), []byte("default {{.Key}}"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(templates, "terraform.tfvars"), []byte("{{.ID}}"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(override, "main.tf"), []byte("custom {{.Key}}"), 0644))
+
+	np, err := New(templates, TerraformProviderSettings{
+		Templates: templates,
+		Backend:   BackendLocal,
+		Subnets:   map[string][]string{"us-west-2": {"subnet-1a"}},
+	})
+	require.NoError(t, err)
+	p := np.(*provider)
+
+	for id, expected := range map[string]string{
+		"custom": "custom custom",
+		"other":  "default other",
+	} {
+		tmpl, err := p.parseTemplate(id, "main.tf")
+		require.NoError(t, err)
+
+		var sb strings.Builder
+		require.NoError(t, tmpl.Execute(&sb, &MainVars{BackendVars: BackendVars{Key: id}}))
+		require.Equal(t, expected, sb.String())
+	}
+}
+
+func TestNewInvalidBackend(t *testing.T) {
+	subnets := map[string][]string{"us-west-2": {"subnet-1a"}}
+
+	_, err := New("", TerraformProviderSettings{Backend: BackendS3, Subnets: subnets})
+	require.True(t, errdefs.IsInvalidArgument(err))
+
+	_, err = New("", TerraformProviderSettings{Backend: BackendS3, Bucket: "bucket", Subnets: subnets})
+	require.True(t, errdefs.IsInvalidArgument(err))
+
+	_, err = New("", TerraformProviderSettings{Backend: "consul", Subnets: subnets})
+	require.True(t, errdefs.IsInvalidArgument(err))
+
+	_, err = New("", TerraformProviderSettings{
+		Backend:      BackendS3,
+		Bucket:       "bucket",
+		BucketRegion: "us-west-2",
+		Subnets:      subnets,
+	})
+	require.NoError(t, err)
+}
+
+func TestNewInvalidSubnets(t *testing.T) {
+	_, err := New("", TerraformProviderSettings{Backend: BackendLocal})
+	require.True(t, errdefs.IsInvalidArgument(err))
+
+	_, err = New("", TerraformProviderSettings{
+		Backend: BackendLocal,
+		Regions: []string{"us-west-2", "eu-west-1"},
+		Subnets: map[string][]string{"us-west-2": {"subnet-1a"}},
+	})
+	require.True(t, errdefs.IsInvalidArgument(err))
+
+	np, err := New("", TerraformProviderSettings{
+		Backend: BackendLocal,
+		Subnets: map[string][]string{
+			"us-west-2": {"subnet-1a"},
+			"eu-west-1": {"subnet-2a"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"eu-west-1", "us-west-2"}, np.(*provider).regions)
+}
+
+// renderTestCluster renders the default templates for a cluster in root.
+func renderTestCluster(t *testing.T, root string) string {
+	templates, err := filepath.Abs("templates")
+	require.NoError(t, err)
+
+	np, err := New(root, TerraformProviderSettings{
+		Templates: templates,
+		Backend:   BackendLocal,
+		Subnets: map[string][]string{
+			"ap-south-1": {"subnet-0a"},
+			"us-west-2":  {"subnet-1a", "subnet-1b"},
+		},
+	})
+	require.NoError(t, err)
+	p := np.(*provider)
+
+	clusterDir, err := p.prepareClusterDir("test")
+	require.NoError(t, err)
+
+	err = p.executeTemplates("test", metadata.ClusterDefinition{
+		Groups: []metadata.ClusterGroup{
+			{Size: 4, PeersPerInstance: 2, InstanceType: "t2.micro", Region: "ap-south-1"},
+			{Size: 1, InstanceType: "c5.large", Region: "us-west-2"},
+			{Size: 3, InstanceType: "t2.micro", Region: "us-west-2"},
+		},
+	})
+	require.NoError(t, err)
+
+	return clusterDir
+}
+
+// TestRenderGolden compares the configuration rendered from the default
+// templates to golden files, which are rewritten with -update.
+func TestRenderGolden(t *testing.T) {
+	root, err := ioutil.TempDir("", "terraform-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	clusterDir := renderTestCluster(t, root)
+	for _, name := range []string{"main.tf", "terraform.tfvars"} {
+		actual, err := ioutil.ReadFile(filepath.Join(clusterDir, name))
+		require.NoError(t, err)
+
+		golden := filepath.Join("testdata", name+".golden")
+		if *update {
+			require.NoError(t, ioutil.WriteFile(golden, actual, 0644))
+		}
+
+		expected, err := ioutil.ReadFile(golden)
+		require.NoError(t, err)
+		require.Equal(t, string(expected), string(actual), name)
+	}
+}
+
+// TestValidate validates the configuration rendered from the default
+// templates. Validation does not read AWS, but initializing terraform
+// downloads the AWS provider, so it is skipped without terraform or network
+// access.
+func TestValidate(t *testing.T) {
+	if _, err := exec.LookPath("terraform"); err != nil {
+		t.Skip("terraform is not installed")
+	}
+
+	root, err := ioutil.TempDir("", "terraform-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	clusterDir := renderTestCluster(t, root)
+	ctx := context.Background()
+
+	tf := &Terraform{root: clusterDir}
+	err = tf.terraform(ctx, "init", "-backend=false", "-input=false", "-no-color")
+	if err != nil {
+		t.Skipf("failed to initialize terraform: %s", err)
+	}
+
+	var buf bytes.Buffer
+	err = tf.terraformWithStdio(ctx, &buf, &buf, "validate", "-no-color")
+	require.NoError(t, err, buf.String())
+}
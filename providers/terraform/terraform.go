@@ -73,6 +73,12 @@ func (t *Terraform) Apply(ctx context.Context, id string, cdef metadata.ClusterD
 		return nil, errors.Wrap(err, "failed to auto-approve apply templates")
 	}
 
+	return DiscoverNodes(ctx, id, cdef)
+}
+
+// DiscoverNodes returns the nodes of the running instances in the autoscaling
+// groups of a cluster.
+func DiscoverNodes(ctx context.Context, id string, cdef metadata.ClusterDefinition) ([]metadata.Node, error) {
 	var ns []metadata.Node
 	for i, cg := range cdef.Groups {
 		asg := fmt.Sprintf("%s-%d", id, i)
@@ -83,22 +89,46 @@ func (t *Terraform) Apply(ctx context.Context, id string, cdef metadata.ClusterD
 			return nil, errors.Wrapf(err, "failed to discover instances for ASG %q in %q", asg, cg.Region)
 		}
 
+		peersPerInstance := cg.PeersPerInstance
+		if peersPerInstance < 1 {
+			peersPerInstance = 1
+		}
+
+		// Each instance's labagent supervises a labapp per node, listening on
+		// consecutive ports from the default app port.
+		remaining := cg.Size
 		for _, instance := range instances {
-			n := metadata.Node{
-				ID:        instance.InstanceId,
-				Address:   instance.PrivateIp,
-				AgentPort: DefaultAgentPort,
-				AppPort:   DefaultAppPort,
-				Labels: append([]string{
-					instance.InstanceId,
-					instance.InstanceType,
-					cg.Region,
-				}, cg.Labels...),
+			if !instance.Running() {
+				continue
 			}
-			if cg.Peer != nil {
-				n.Peer = *cg.Peer
+
+			for j := 0; j < peersPerInstance && remaining > 0; j++ {
+				id := instance.InstanceId
+				labels := []string{id}
+				if peersPerInstance > 1 {
+					// Nodes sharing an instance can be selected by its ID.
+					id = fmt.Sprintf("%s-%d", instance.InstanceId, j)
+					labels = []string{id, instance.InstanceId}
+				}
+
+				n := metadata.Node{
+					ID:        id,
+					Address:   instance.PrivateIp,
+					AgentPort: DefaultAgentPort,
+					AppPort:   DefaultAppPort + j,
+					Instance:  j,
+					Group:     i,
+					Labels: append(append(labels,
+						instance.InstanceType,
+						cg.Region,
+					), cg.Labels...),
+				}
+				if cg.Peer != nil {
+					n.Peer = *cg.Peer
+				}
+				ns = append(ns, n)
+				remaining--
 			}
-			ns = append(ns, n)
 		}
 	}
 
@@ -48,6 +48,57 @@ func ComputeAggregates(reportByNodeId map[string]metadata.ReportNode) metadata.R
 			*pair.aggregate += pair.single
 		}
 
+		conns := reportNode.Connections
+		for _, pair := range []uint64Pair{
+			{conns.Peak, &aggregates.Totals.Connections.Peak},
+		} {
+			*pair.aggregate += pair.single
+		}
+
+		for _, pair := range []float64Pair{
+			{conns.Average, &aggregates.Totals.Connections.Average},
+		} {
+			*pair.aggregate += pair.single
+		}
+
+		reads := reportNode.Reads
+		for _, pair := range []uint64Pair{
+			{reads.Count, &aggregates.Totals.Reads.Count},
+			{reads.Bytes, &aggregates.Totals.Reads.Bytes},
+		} {
+			*pair.aggregate += pair.single
+		}
+		aggregates.Totals.Reads.Elapsed += reads.Elapsed
+
+		finds := reportNode.FindProviders
+		for _, pair := range []uint64Pair{
+			{finds.Count, &aggregates.Totals.FindProviders.Count},
+			{finds.Providers, &aggregates.Totals.FindProviders.Providers},
+		} {
+			*pair.aggregate += pair.single
+		}
+		aggregates.Totals.FindProviders.Elapsed += finds.Elapsed
+		aggregates.Totals.FindProviders.FirstProvider += finds.FirstProvider
+
+		resources := reportNode.Resources
+		for _, pair := range []uint64Pair{
+			{resources.PeakRSS, &aggregates.Totals.Resources.PeakRSS},
+			{resources.AverageRSS, &aggregates.Totals.Resources.AverageRSS},
+			{resources.DiskRead, &aggregates.Totals.Resources.DiskRead},
+			{resources.DiskWritten, &aggregates.Totals.Resources.DiskWritten},
+			{resources.PeakFDs, &aggregates.Totals.Resources.PeakFDs},
+		} {
+			*pair.aggregate += pair.single
+		}
+
+		for _, pair := range []float64Pair{
+			{resources.PeakCPU, &aggregates.Totals.Resources.PeakCPU},
+			{resources.AverageCPU, &aggregates.Totals.Resources.AverageCPU},
+			{resources.AverageFDs, &aggregates.Totals.Resources.AverageFDs},
+		} {
+			*pair.aggregate += pair.single
+		}
+
 		bandwidth := reportNode.Bandwidth.Totals
 		for _, pair := range []int64Pair{
 			{bandwidth.TotalIn, &aggregates.Totals.Bandwidth.Totals.TotalIn},
@@ -63,5 +114,10 @@ func ComputeAggregates(reportByNodeId map[string]metadata.ReportNode) metadata.R
 			*pair.aggregate += pair.single
 		}
 	}
+
+	totalReads := aggregates.Totals.Reads
+	if totalReads.Elapsed > 0 {
+		aggregates.Totals.Reads.Throughput = float64(totalReads.Bytes) / totalReads.Elapsed.Seconds()
+	}
 	return aggregates
 }
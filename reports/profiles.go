@@ -0,0 +1,89 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reports
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/Netflix/p2plab"
+	"github.com/google/pprof/profile"
+	"github.com/pkg/errors"
+)
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// MergeProfiles merges the profiles in dir, laid out as <node>/<kind>.pb.gz,
+// of the nodes matched by each query into merged/<query>/<kind>.pb.gz.
+func MergeProfiles(dir string, queries map[string][]string) error {
+	for q, nodeIds := range queries {
+		qdir := filepath.Join(dir, "merged", unsafePathChars.ReplaceAllString(q, "_"))
+		err := os.MkdirAll(qdir, 0711)
+		if err != nil {
+			return err
+		}
+
+		for _, kind := range p2plab.ProfileKinds {
+			var ps []*profile.Profile
+			for _, nodeId := range nodeIds {
+				p, err := readProfile(filepath.Join(dir, nodeId, fmt.Sprintf("%s.pb.gz", kind)))
+				if err != nil {
+					if os.IsNotExist(errors.Cause(err)) {
+						continue
+					}
+					return errors.Wrapf(err, "failed to read %s profile of %q", kind, nodeId)
+				}
+				ps = append(ps, p)
+			}
+			if len(ps) == 0 {
+				continue
+			}
+
+			merged, err := profile.Merge(ps)
+			if err != nil {
+				return errors.Wrapf(err, "failed to merge %s profiles for %q", kind, q)
+			}
+
+			err = writeProfile(filepath.Join(qdir, fmt.Sprintf("%s.pb.gz", kind)), merged)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func readProfile(path string) (*profile.Profile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return profile.Parse(f)
+}
+
+func writeProfile(path string, p *profile.Profile) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return p.Write(f)
+}
@@ -16,14 +16,18 @@ package scenarios
 
 import (
 	"context"
+	"strings"
 	"sync"
 
 	"github.com/Netflix/p2plab"
 	"github.com/Netflix/p2plab/actions"
+	"github.com/Netflix/p2plab/errdefs"
 	"github.com/Netflix/p2plab/metadata"
 	"github.com/Netflix/p2plab/query"
 	"github.com/Netflix/p2plab/transformers"
 	cid "github.com/ipfs/go-cid"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"golang.org/x/sync/errgroup"
 )
@@ -31,6 +35,7 @@ import (
 func Plan(ctx context.Context, sdef metadata.ScenarioDefinition, ts *transformers.Transformers, peer p2plab.Peer, lset p2plab.LabeledSet) (plan metadata.ScenarioPlan, queries map[string][]string, err error) {
 	plan = metadata.ScenarioPlan{
 		Objects:   make(map[string]cid.Cid),
+		Digests:   make(map[string]digest.Digest),
 		Seed:      make(map[string]metadata.Task),
 		Benchmark: make(map[string]metadata.Task),
 	}
@@ -47,7 +52,7 @@ func Plan(ctx context.Context, sdef metadata.ScenarioDefinition, ts *transformer
 				return err
 			}
 
-			opts := AddOptionsFromDefinition(odef)
+			opts := p2plab.AddOptionsFromDefinition(odef)
 			c, err := t.Transform(gctx, peer, odef.Source, opts...)
 			if err != nil {
 				return err
@@ -66,6 +71,26 @@ func Plan(ctx context.Context, sdef metadata.ScenarioDefinition, ts *transformer
 		return plan, nil, err
 	}
 
+	// Record the digest of the content read by read actions, either a whole
+	// object or a path within one, so that nodes can verify what they read
+	// back. Objects that are never read are not digested.
+	for _, a := range append(mapValues(sdef.Seed), mapValues(sdef.Benchmark)...) {
+		target, ok := actions.ReadTarget(a)
+		if !ok {
+			continue
+		}
+
+		if _, ok := plan.Digests[target]; ok {
+			continue
+		}
+
+		dgst, err := digestTarget(ctx, peer, plan.Objects, target)
+		if err != nil {
+			return plan, nil, err
+		}
+		plan.Digests[target] = dgst
+	}
+
 	zerolog.Ctx(ctx).Info().Msg("Planning scenario seed")
 	for q, a := range sdef.Seed {
 		qry, err := query.Parse(ctx, q)
@@ -84,7 +109,7 @@ func Plan(ctx context.Context, sdef metadata.ScenarioDefinition, ts *transformer
 		}
 		zerolog.Ctx(ctx).Debug().Str("query", qry.String()).Strs("ids", ids).Msg("Matched query")
 
-		action, err := actions.Parse(plan.Objects, a)
+		action, err := actions.Parse(plan, sdef.Objects, a)
 		if err != nil {
 			return plan, nil, err
 		}
@@ -99,7 +124,9 @@ func Plan(ctx context.Context, sdef metadata.ScenarioDefinition, ts *transformer
 			return plan, nil, err
 		}
 
-		plan.Seed = taskMap
+		for id, task := range taskMap {
+			plan.Seed[id] = task
+		}
 	}
 
 	zerolog.Ctx(ctx).Info().Msg("Planning scenario benchmark")
@@ -123,7 +150,7 @@ func Plan(ctx context.Context, sdef metadata.ScenarioDefinition, ts *transformer
 		zerolog.Ctx(ctx).Debug().Str("query", qry.String()).Strs("ids", ids).Msg("Matched query")
 		queries[qry.String()] = ids
 
-		action, err := actions.Parse(plan.Objects, a)
+		action, err := actions.Parse(plan, sdef.Objects, a)
 		if err != nil {
 			return plan, nil, err
 		}
@@ -138,25 +165,45 @@ func Plan(ctx context.Context, sdef metadata.ScenarioDefinition, ts *transformer
 			return plan, nil, err
 		}
 
-		plan.Benchmark = taskMap
+		for id, task := range taskMap {
+			plan.Benchmark[id] = task
+		}
 	}
 
 	return plan, queries, nil
 }
 
-func AddOptionsFromDefinition(odef metadata.ObjectDefinition) []p2plab.AddOption {
-	var opts []p2plab.AddOption
-	if odef.Layout != "" {
-		opts = append(opts, p2plab.WithLayout(odef.Layout))
+// digestTarget digests the content of an object, or of a path within one.
+func digestTarget(ctx context.Context, peer p2plab.Peer, objects map[string]cid.Cid, target string) (digest.Digest, error) {
+	parts := strings.SplitN(target, "/", 2)
+	c, ok := objects[parts[0]]
+	if !ok {
+		return "", errors.Wrapf(errdefs.ErrNotFound, "object %q", parts[0])
 	}
-	if odef.Chunker != "" {
-		opts = append(opts, p2plab.WithChunker(odef.Chunker))
+
+	if len(parts) == 2 {
+		var err error
+		c, err = peer.Resolve(ctx, c, parts[1])
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to resolve %q", target)
+		}
 	}
-	if odef.RawLeaves {
-		opts = append(opts, p2plab.WithRawLeaves(true))
+
+	digester := digest.Canonical.Digester()
+	_, err := peer.Read(ctx, c, digester.Hash())
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to digest %q", target)
 	}
-	if odef.HashFunc != "" {
-		opts = append(opts, p2plab.WithHashFunc(odef.HashFunc))
+
+	dgst := digester.Digest()
+	zerolog.Ctx(ctx).Debug().Str("target", target).Str("digest", dgst.String()).Msg("Digested read target")
+	return dgst, nil
+}
+
+func mapValues(m map[string]string) []string {
+	var values []string
+	for _, v := range m {
+		values = append(values, v)
 	}
-	return opts
+	return values
 }
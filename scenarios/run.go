@@ -25,6 +25,7 @@ import (
 	"github.com/Netflix/p2plab/nodes"
 	"github.com/Netflix/p2plab/pkg/logutil"
 	"github.com/Netflix/p2plab/pkg/traceutil"
+	"github.com/Netflix/p2plab/progress"
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
@@ -38,7 +39,27 @@ type Execution struct {
 	Span   opentracing.Span
 }
 
-func Run(ctx context.Context, lset p2plab.LabeledSet, plan metadata.ScenarioPlan, seederAddrs []string) (*Execution, error) {
+// RunOption is an option to modify how a scenario plan is executed.
+type RunOption func(*RunSettings) error
+
+// RunSettings specify how a scenario plan is executed.
+type RunSettings struct {
+	// ProfileDir is where the profiles captured during the benchmark stage are
+	// written. Profiles are not captured when empty.
+	ProfileDir string
+}
+
+// WithProfiles captures profiles on every node during the benchmark stage and
+// writes them into dir.
+func WithProfiles(dir string) RunOption {
+	return func(s *RunSettings) error {
+		s.ProfileDir = dir
+		return nil
+	}
+}
+
+// Run seeds the cluster and benchmarks the scenario plan.
+func Run(ctx context.Context, lset p2plab.LabeledSet, plan metadata.ScenarioPlan, seederAddrs []string, opts ...RunOption) (*Execution, error) {
 	span, ctx := traceutil.StartSpanFromContext(ctx, "scenarios.Run")
 	defer span.Finish()
 
@@ -47,7 +68,7 @@ func Run(ctx context.Context, lset p2plab.LabeledSet, plan metadata.ScenarioPlan
 		return nil, err
 	}
 
-	return Session(ctx, lset, plan.Benchmark)
+	return Session(ctx, lset, plan.Benchmark, opts...)
 }
 
 func LabeledSetToNodes(lset p2plab.LabeledSet) ([]p2plab.Node, error) {
@@ -67,6 +88,9 @@ func Seed(ctx context.Context, lset p2plab.LabeledSet, seed metadata.ScenarioSta
 
 	zerolog.Ctx(ctx).Info().Msg("Seeding cluster")
 	go logutil.Elapsed(gctx, 20*time.Second, "Seeding cluster")
+
+	tracker := progress.NewTracker("seed")
+	go tracker.Run(gctx, progress.MergeInterval)
 	for id, task := range seed {
 		id, task := id, task
 		seeding.Go(func() error {
@@ -91,7 +115,7 @@ func Seed(ctx context.Context, lset p2plab.LabeledSet, seed metadata.ScenarioSta
 			}
 
 			logger.Debug().Str("task", string(task.Type)).Msg("Executing seeding task")
-			err = n.Run(gctx, task)
+			err = n.Run(logutil.WithLogWriter(gctx, tracker.Writer(id, logutil.LogWriter(gctx))), task)
 			if err != nil {
 				return errors.Wrap(err, "failed to run seeding task")
 			}
@@ -118,7 +142,15 @@ func Seed(ctx context.Context, lset p2plab.LabeledSet, seed metadata.ScenarioSta
 	return nil
 }
 
-func Session(ctx context.Context, lset p2plab.LabeledSet, benchmark metadata.ScenarioStage) (*Execution, error) {
+func Session(ctx context.Context, lset p2plab.LabeledSet, benchmark metadata.ScenarioStage, opts ...RunOption) (*Execution, error) {
+	var settings RunSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	ns, err := LabeledSetToNodes(lset)
 	if err != nil {
 		return nil, err
@@ -131,12 +163,48 @@ func Session(ctx context.Context, lset p2plab.LabeledSet, benchmark metadata.Sce
 			return err
 		}
 
+		if settings.ProfileDir != "" {
+			err = nodes.StartProfiling(ctx, ns)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = nodes.StartSampling(ctx, ns)
+		if err != nil {
+			return err
+		}
+
 		execution.Start = time.Now()
 		err = Benchmark(sctx, lset, benchmark)
+		execution.End = time.Now()
+
+		// Sampling is stopped even if the benchmark failed, so that the nodes
+		// don't keep sampling until the next benchmark.
+		serr := nodes.StopSampling(ctx, ns)
+		if serr != nil {
+			if err != nil {
+				zerolog.Ctx(ctx).Warn().Err(serr).Msg("Failed to stop sampling")
+			} else {
+				err = serr
+			}
+		}
+
+		// Profiles are collected even if the benchmark failed, so that the
+		// nodes stop profiling and the failure can be investigated.
+		if settings.ProfileDir != "" {
+			perr := nodes.CollectProfiles(ctx, ns, settings.ProfileDir)
+			if perr != nil {
+				if err != nil {
+					zerolog.Ctx(ctx).Warn().Err(perr).Msg("Failed to collect profiles")
+				} else {
+					err = errors.Wrap(perr, "failed to collect profiles")
+				}
+			}
+		}
 		if err != nil {
 			return err
 		}
-		execution.End = time.Now()
 
 		execution.Report, err = nodes.CollectReports(ctx, ns)
 		if err != nil {
@@ -160,6 +228,9 @@ func Benchmark(ctx context.Context, lset p2plab.LabeledSet, benchmark metadata.S
 
 	zerolog.Ctx(ctx).Info().Msg("Benchmarking cluster")
 	go logutil.Elapsed(gctx, 20*time.Second, "Benchmarking cluster")
+
+	tracker := progress.NewTracker("benchmark")
+	go tracker.Run(gctx, progress.MergeInterval)
 	for id, task := range benchmark {
 		id, task := id, task
 		benchmarking.Go(func() error {
@@ -175,7 +246,22 @@ func Benchmark(ctx context.Context, lset p2plab.LabeledSet, benchmark metadata.S
 			}
 
 			logger.Debug().Str("task", string(task.Type)).Msg("Executing benchmarking task")
-			return n.Run(gctx, task)
+			exec, err := nodes.RunTask(gctx, n, task, func(exec metadata.TaskExecution) {
+				tracker.Update(id, exec.Progress)
+			})
+			if err != nil {
+				return err
+			}
+
+			switch exec.Status {
+			case metadata.TaskCanceled:
+				// Tasks stuck on a single node can be canceled without failing
+				// the whole benchmark.
+				logger.Warn().Str("id", exec.ID).Msg("Benchmarking task was canceled")
+			case metadata.TaskError:
+				return errors.Errorf("benchmarking task %q failed on %q: %s", exec.ID, id, exec.Error)
+			}
+			return nil
 		})
 	}
 
@@ -34,10 +34,10 @@ import (
 	"github.com/containerd/containerd/remotes"
 	"github.com/containerd/containerd/remotes/docker"
 	cid "github.com/ipfs/go-cid"
+	files "github.com/ipfs/go-ipfs-files"
 	ipld "github.com/ipfs/go-ipld-format"
 	"github.com/ipfs/go-merkledag/dagutils"
 	unixfs "github.com/ipfs/go-unixfs"
-	multihash "github.com/multiformats/go-multihash"
 	digest "github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
@@ -94,7 +94,12 @@ func (t *transformer) Transform(ctx context.Context, p p2plab.Peer, source strin
 	}
 	zerolog.Ctx(ctx).Info().Str("source", source).Str("digest", desc.Digest.String()).Msg("Resolved reference to digest")
 
-	target, err := t.get(desc.Digest)
+	key, err := transformKey(desc.Digest, opts...)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	target, err := t.get(key)
 	if err != nil && !errdefs.IsNotFound(err) {
 		return cid.Undef, errors.Wrapf(err, "failed to look for cached transform")
 	}
@@ -111,7 +116,7 @@ func (t *transformer) Transform(ctx context.Context, p p2plab.Peer, source strin
 			return cid.Undef, errors.Wrapf(err, "failed to convert %q", name)
 		}
 
-		err = t.put(desc.Digest, target)
+		err = t.put(key, target)
 		if err != nil {
 			return cid.Undef, errors.Wrapf(err, "failed to put cached transform")
 		}
@@ -126,6 +131,25 @@ func (t *transformer) Transform(ctx context.Context, p p2plab.Peer, source strin
 	return nd.Cid(), nil
 }
 
+// transformKey returns the key of the cached transform of a manifest, which
+// depends on the add options since they change the converted DAG.
+func transformKey(dgst digest.Digest, opts ...p2plab.AddOption) (string, error) {
+	var settings p2plab.AddSettings
+	for _, opt := range opts {
+		err := opt(&settings)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	content, err := json.Marshal(&settings)
+	if err != nil {
+		return "", err
+	}
+
+	return digest.FromBytes(append([]byte(dgst), content...)).String(), nil
+}
+
 func Convert(ctx context.Context, peer p2plab.Peer, fetcher remotes.Fetcher, store content.Store, desc ocispec.Descriptor, opts ...p2plab.AddOption) (target ocispec.Descriptor, err error) {
 	// Get all the children for a descriptor from a provider.
 	childrenHandler := images.ChildrenHandler(store)
@@ -185,7 +209,7 @@ func ConvertHandler(conversions map[digest.Digest]ocispec.Descriptor, peer p2pla
 		case images.MediaTypeDockerSchema2Manifest, ocispec.MediaTypeImageManifest,
 			images.MediaTypeDockerSchema2ManifestList, ocispec.MediaTypeImageIndex:
 
-			target, err = Convert(ctx, peer, fetcher, store, desc)
+			target, err = Convert(ctx, peer, fetcher, store, desc, opts...)
 
 		case images.MediaTypeDockerSchema2Layer, images.MediaTypeDockerSchema2LayerGzip,
 			images.MediaTypeDockerSchema2LayerForeign, images.MediaTypeDockerSchema2LayerForeignGzip,
@@ -287,7 +311,7 @@ func BuildManifestHandler(f images.HandlerFunc, peer p2plab.Peer, provider conte
 }
 
 func AddBlob(ctx context.Context, peer p2plab.Peer, r io.Reader, opts ...p2plab.AddOption) (digest.Digest, error) {
-	n, err := peer.Add(ctx, r, opts...)
+	n, err := peer.Add(ctx, files.NewReaderFile(r), opts...)
 	if err != nil {
 		return "", err
 	}
@@ -303,7 +327,9 @@ func AddBlob(ctx context.Context, peer p2plab.Peer, r io.Reader, opts ...p2plab.
 
 func ConstructDAGFromManifest(ctx context.Context, p p2plab.Peer, image ocispec.Descriptor, opts ...p2plab.AddOption) (ipld.Node, error) {
 	settings := p2plab.AddSettings{
-		HashFunc: "sha2-256",
+		HashFunc:    "sha2-256",
+		CidVersion:  1,
+		InlineLimit: p2plab.DefaultInlineLimit,
 	}
 	for _, opt := range opts {
 		err := opt(&settings)
@@ -321,8 +347,13 @@ func ConstructDAGFromManifest(ctx context.Context, p p2plab.Peer, image ocispec.
 		return nil, err
 	}
 
+	builder, err := settings.CidBuilder()
+	if err != nil {
+		return nil, err
+	}
+
 	root := unixfs.EmptyDirNode()
-	root.SetCidBuilder(cid.V1Builder{MhType: multihash.Names[settings.HashFunc]})
+	root.SetCidBuilder(builder)
 
 	dserv := p.DAGService()
 	e := dagutils.NewDagEditor(root, dserv)
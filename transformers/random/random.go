@@ -0,0 +1,57 @@
+// Copyright 2019 Netflix, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package random
+
+import (
+	"context"
+
+	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/pkg/randutil"
+	"github.com/Netflix/p2plab/pkg/traceutil"
+	cid "github.com/ipfs/go-cid"
+	files "github.com/ipfs/go-ipfs-files"
+	"github.com/pkg/errors"
+)
+
+type transformer struct{}
+
+// New returns a transformer that adds pseudo-random content generated from a
+// source of the form "<size>[?seed=<n>]".
+func New() p2plab.Transformer {
+	return &transformer{}
+}
+
+func (t *transformer) Close() error {
+	return nil
+}
+
+func (t *transformer) Transform(ctx context.Context, p p2plab.Peer, source string, opts ...p2plab.AddOption) (cid.Cid, error) {
+	span, ctx := traceutil.StartSpanFromContext(ctx, "transformer.Transform")
+	defer span.Finish()
+	span.SetTag("peer", p.Host().ID().String())
+	span.SetTag("source", source)
+
+	size, seed, err := randutil.ParseSource(source)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	nd, err := p.Add(ctx, files.NewReaderFile(randutil.NewReader(size, seed)), opts...)
+	if err != nil {
+		return cid.Undef, errors.Wrapf(err, "failed to add %q", source)
+	}
+
+	return nd.Cid(), nil
+}
@@ -20,7 +20,9 @@ import (
 	"sync"
 
 	"github.com/Netflix/p2plab"
+	"github.com/Netflix/p2plab/transformers/dir"
 	"github.com/Netflix/p2plab/transformers/oci"
+	"github.com/Netflix/p2plab/transformers/random"
 	"github.com/pkg/errors"
 )
 
@@ -70,6 +72,10 @@ func (t *Transformers) newTransformer(objectType string) (p2plab.Transformer, er
 	switch objectType {
 	case "oci":
 		return oci.New(root, t.client)
+	case "dir":
+		return dir.New(), nil
+	case "random":
+		return random.New(), nil
 	default:
 		return nil, errors.Errorf("unrecognized object type: %q", objectType)
 	}